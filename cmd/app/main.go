@@ -11,6 +11,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/go-co-op/gocron/v2"
 	"github.com/gofiber/fiber/v2/middleware/monitor"
 	"github.com/gofiber/swagger"
 	"github.com/gofiber/template/html/v2"
@@ -69,6 +70,16 @@ func main() {
 	// Middleware setup
 	setupMiddleware(app, cfg)
 
+	// Users are only known from a trusted authenticating proxy
+	identity, err := handler.NewUserIdentity(cfg.TrustedProxies, cfg.TrustedProxySecret)
+	if err != nil {
+		log.Fatal("Invalid trusted proxy:", err)
+	}
+	if !identity.Configured() {
+		log.Println("No trusted proxy is configured, X-Forwarded-User is ignored and change requests can't be created or reviewed")
+	}
+	app.Use(identity.Middleware)
+
 	// logger, _ := config.NewZapLog(cfg.AppEnv)
 	// logger = logger.WithOptions(zap.AddCallerSkip(1))
 
@@ -95,16 +106,42 @@ func main() {
 		log.Fatal("Failed to connect to SQLite:", err)
 	}
 	// Migrate
-	sqliteDB.AutoMigrate(&entity.CHConnection{}, &entity.SlowQueryReport{}, &entity.QueryHistory{}, &entity.FavoriteComparison{})
+	sqliteDB.AutoMigrate(&entity.CHConnection{}, &entity.SlowQueryReport{}, &entity.QueryHistory{}, &entity.FavoriteComparison{},
+		&entity.ComparisonSuite{}, &entity.ComparisonSuiteRun{}, &entity.ComparisonSuiteRunResult{}, &entity.ReplayRun{}, &entity.SavedQuery{},
+		&entity.ChangeRequest{})
 
 	// CH Manager Dependencies
 	chClient := clickhouse.NewClickHouseClient()
 	connectionRepo := sqlite.NewConnectionRepository(sqliteDB)
 	historyRepo := sqlite.NewQueryHistoryRepository(sqliteDB)
+	if err := historyRepo.SetupSearch(context.Background()); err != nil {
+		log.Printf("History search falls back to LIKE, FTS5 is unavailable: %v", err)
+	}
 	favRepo := sqlite.NewFavoriteRepository(sqliteDB)
 	reportRepo := sqlite.NewReportRepository(sqliteDB)
-	connectionUsecase := usecase.NewConnectionUsecase(connectionRepo, historyRepo, favRepo, chClient)
+	suiteRepo := sqlite.NewSuiteRepository(sqliteDB)
+	replayRepo := sqlite.NewReplayRepository(sqliteDB)
+	savedQueryRepo := sqlite.NewSavedQueryRepository(sqliteDB)
+	changeRequestRepo := sqlite.NewChangeRequestRepository(sqliteDB)
+	guardPolicies, err := guardPolicies(cfg)
+	if err != nil {
+		log.Fatal("Invalid statement guard policy:", err)
+	}
+	guard := usecase.NewStatementGuard(guardPolicies)
+	connectionUsecase := usecase.NewConnectionUsecase(connectionRepo, historyRepo, favRepo, chClient, guard)
 	reportUsecase := usecase.NewReportUsecase(reportRepo, connectionRepo, chClient)
+	suiteUsecase := usecase.NewSuiteUsecase(suiteRepo, favRepo, connectionRepo, chClient)
+	loadTestUsecase := usecase.NewLoadTestUsecase(connectionRepo, chClient)
+	replayUsecase := usecase.NewReplayUsecase(replayRepo, connectionRepo, chClient)
+	savedQueryUsecase := usecase.NewSavedQueryUsecase(savedQueryRepo, connectionRepo)
+	changeRequestUsecase := usecase.NewChangeRequestUsecase(changeRequestRepo, connectionRepo, connectionUsecase, guard)
+
+	// Scheduled comparison suites
+	scheduler, err := newSuiteScheduler(suiteUsecase)
+	if err != nil {
+		log.Fatal("Failed to start suite scheduler:", err)
+	}
+	defer scheduler.Shutdown()
 
 	api := app.Group("/api/v1")
 
@@ -113,6 +150,21 @@ func main() {
 	// Register Report Handler
 	handler.NewReportHandler(reportUsecase, connectionUsecase).Register(app)
 
+	// Register Comparison Suite Handler
+	handler.NewSuiteHandler(presenterJson, suiteUsecase, connectionUsecase).Register(app)
+
+	// Register Load Test Handler
+	handler.NewLoadTestHandler(presenterJson, loadTestUsecase, connectionUsecase).Register(app)
+
+	// Register Query Replay Handler
+	handler.NewReplayHandler(presenterJson, replayUsecase, connectionUsecase).Register(app)
+
+	// Register Saved Query Library Handler
+	handler.NewSavedQueryHandler(presenterJson, savedQueryUsecase, connectionUsecase).Register(app)
+
+	// Register Change Request Handler
+	handler.NewChangeRequestHandler(presenterJson, changeRequestUsecase, connectionUsecase).Register(app)
+
 	// Register View Handler (MPA)
 	// Note: View routes are correctly registered at root level by this handler
 	handler.NewViewHandler(connectionUsecase).Register(app)
@@ -129,6 +181,50 @@ func main() {
 	runServerWithGracefulShutdown(app, cfg.ApiPort, 30)
 }
 
+// guardPolicies reads the configured statement guard policies, labels left empty keep their default
+func guardPolicies(cfg *config.Config) (map[string]entity.GuardPolicy, error) {
+	policies := make(map[string]entity.GuardPolicy)
+	for label, value := range map[string]string{
+		entity.LabelDevelopment: cfg.GuardPolicyDevelopment,
+		entity.LabelStaging:     cfg.GuardPolicyStaging,
+		entity.LabelProduction:  cfg.GuardPolicyProduction,
+	} {
+		if value == "" {
+			continue
+		}
+		policy, err := usecase.ParseGuardPolicy(value)
+		if err != nil {
+			return nil, err
+		}
+		policies[label] = policy
+	}
+	return policies, nil
+}
+
+// newSuiteScheduler checks every minute for comparison suites whose schedule is due.
+func newSuiteScheduler(suiteUsecase usecase.SuiteUsecase) (gocron.Scheduler, error) {
+	s, err := gocron.NewScheduler()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.NewJob(
+		gocron.DurationJob(time.Minute),
+		gocron.NewTask(func() {
+			if err := suiteUsecase.RunDueSuites(context.Background()); err != nil {
+				log.Printf("Failed to run scheduled suites: %v", err)
+			}
+		}),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Start()
+	return s, nil
+}
+
 func setupMiddleware(app *fiber.App, cfg *config.Config) {
 	// Enable CORS if API shared in public
 	// if cfg.AppEnv == "production" {
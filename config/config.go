@@ -14,6 +14,17 @@ type Config struct {
 	AllowedCredentialOrigins []string `env:"ALLOWED_CREDENTIAL_ORIGINS"`
 	MiddlewareAddress        string   `env:"MIDDLEWARE_ADDR"`
 	JwtExpireDaysCount       int      `env:"JWT_EXPIRE_DAYS_COUNT"`
+
+	// Statement guard policies per connection label as kind=action pairs, e.g. "*=confirm,drop=block".
+	// Empty keeps the default policy of the label.
+	GuardPolicyDevelopment string `env:"GUARD_POLICY_DEVELOPMENT"`
+	GuardPolicyStaging     string `env:"GUARD_POLICY_STAGING"`
+	GuardPolicyProduction  string `env:"GUARD_POLICY_PRODUCTION"`
+
+	// The authenticating proxy whose X-Forwarded-User header is trusted: allow-listed IPs or CIDR ranges,
+	// and/or a secret it sends in X-Proxy-Secret. Without either every request is anonymous.
+	TrustedProxies     []string `env:"TRUSTED_PROXIES"`
+	TrustedProxySecret string   `env:"TRUSTED_PROXY_SECRET"`
 }
 
 func NewConfig() *Config {
@@ -2,6 +2,30 @@ package entity
 
 import "time"
 
+// Connection labels
+const (
+	LabelDevelopment = "DEVELOPMENT"
+	LabelStaging     = "STAGING"
+	LabelProduction  = "PRODUCTION"
+)
+
+// Read-only modes of a connection, the value of the ClickHouse readonly setting it is opened with
+const (
+	ReadOnlyOff = 0
+	// ReadOnlyStrict rejects writes, DDL and changing settings
+	ReadOnlyStrict = 1
+	// ReadOnlyAllowSettings rejects writes and DDL but lets queries change settings
+	ReadOnlyAllowSettings = 2
+)
+
+// Result limits of console queries
+const (
+	DefaultMaxResultRows  = 100000
+	DefaultMaxResultBytes = 100 << 20
+	DefaultResultPageSize = 500
+	MaxResultPageSize     = 10000
+)
+
 type CHConnection struct {
 	ID         int64  `json:"id" gorm:"primaryKey;autoIncrement"`
 	Name       string `json:"name" gorm:"type:varchar(255);not null"`
@@ -14,11 +38,47 @@ type CHConnection struct {
 	UseSSL     bool   `json:"use_ssl" gorm:"default:false"`
 	ServerInfo string `json:"server_info" gorm:"type:text"`
 	Label      string `json:"label" gorm:"type:varchar(20);default:'DEVELOPMENT'"`
+	// ReadOnly is one of the ReadOnly modes, ClickHouse itself rejects writes on a read-only connection
+	ReadOnly int `json:"read_only" form:"read_only" gorm:"default:0"`
+
+	// Caps of console results, 0 means the defaults
+	MaxResultRows  int   `json:"max_result_rows" form:"max_result_rows" gorm:"default:0"`
+	MaxResultBytes int64 `json:"max_result_bytes" form:"max_result_bytes" gorm:"default:0"`
+	// HistoryRetention is the number of unpinned console history entries kept, 0 means the default
+	HistoryRetention int `json:"history_retention" form:"history_retention" gorm:"default:0"`
 
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// ResultLimits returns the row and byte caps of console results on this connection
+func (c *CHConnection) ResultLimits() (int, int64) {
+	rows, bytes := c.MaxResultRows, c.MaxResultBytes
+	if rows <= 0 {
+		rows = DefaultMaxResultRows
+	}
+	if bytes <= 0 {
+		bytes = DefaultMaxResultBytes
+	}
+	return rows, bytes
+}
+
+// IsReadOnly reports whether the connection is opened in a read-only mode
+func (c *CHConnection) IsReadOnly() bool {
+	return c.ReadOnly != ReadOnlyOff
+}
+
+// HistoryLimit returns the number of unpinned console history entries kept for this connection
+func (c *CHConnection) HistoryLimit() int {
+	switch {
+	case c.HistoryRetention <= 0:
+		return DefaultHistoryRetention
+	case c.HistoryRetention > MaxHistoryRetention:
+		return MaxHistoryRetention
+	}
+	return c.HistoryRetention
+}
+
 type TableSchema struct {
 	Name     string              `json:"name"`
 	Database string              `json:"database"`
@@ -35,22 +95,81 @@ type TableMeta struct {
 	Engine string `json:"engine"`
 }
 
+// QueryStats holds the metrics of one execution as recorded in system.query_log.
+// ProfileEvents is the full ProfileEvents map (CPU time, IO wait, cache hits, network bytes, ...).
 type QueryStats struct {
-	ExecutionTimeMs int64  `json:"execution_time_ms"`
-	RowsRead        uint64 `json:"rows_read"`
-	BytesRead       uint64 `json:"bytes_read"`
-	MemoryPeak      uint64 `json:"memory_peak"`
-	PartsRead       uint64 `json:"parts_read"`
-	MarksRead       uint64 `json:"marks_read"`
+	ExecutionTimeMs int64             `json:"execution_time_ms"`
+	RowsRead        uint64            `json:"rows_read"`
+	BytesRead       uint64            `json:"bytes_read"`
+	WrittenRows     uint64            `json:"written_rows"`
+	WrittenBytes    uint64            `json:"written_bytes"`
+	MemoryPeak      uint64            `json:"memory_peak"`
+	PartsRead       uint64            `json:"parts_read"`
+	MarksRead       uint64            `json:"marks_read"`
+	ProfileEvents   map[string]uint64 `json:"profile_events,omitempty"`
+	// Unlogged is set when the query never showed up in system.query_log, only ExecutionTimeMs is known,
+	// timed by the client
+	Unlogged bool `json:"unlogged,omitempty"`
+}
+
+// MaxQueryIDLength bounds client supplied query IDs
+const MaxQueryIDLength = 128
+
+// QueryOptions are the per-request options of a console query
+type QueryOptions struct {
+	// QueryID lets the client pick the ID up front so it can cancel the query while waiting for the response
+	QueryID string `json:"query_id"`
+	// PageSize > 0 returns only the first page and keeps a cursor open on the server for the rest
+	PageSize int `json:"page_size"`
+	// Timezone is the IANA name date times are rendered in, empty keeps each column's own timezone
+	Timezone string `json:"timezone"`
+	// Parameters bind the {name:Type} placeholders of the query
+	Parameters QueryParameters `json:"parameters"`
+	// SessionID runs the query in a console session, see ConsoleSession
+	SessionID string `json:"session_id"`
+	// Settings are ClickHouse settings of this execution only, e.g. {"max_execution_time": 10, "readonly": 1}
+	Settings QuerySettings `json:"settings"`
+	// Confirm is the typed confirmation of a statement the guard asks to confirm, see StatementGuardError
+	Confirm string `json:"confirm"`
 }
 
 type QueryResult struct {
-	Columns []string                 `json:"columns"`
-	Rows    []map[string]interface{} `json:"rows"`
-	Stats   *QueryStats              `json:"stats"`
+	QueryID string   `json:"query_id"`
+	Columns []string `json:"columns"`
+	// ColumnTypes are the ClickHouse types of Columns, e.g. "Nullable(Decimal(18, 2))"
+	ColumnTypes []string                 `json:"column_types"`
+	Rows        []map[string]interface{} `json:"rows"`
+	Stats       *QueryStats              `json:"stats"`
+	// Truncated is set when the connection's row or byte cap stopped the query early
+	Truncated       bool   `json:"truncated"`
+	TruncatedReason string `json:"truncated_reason,omitempty"`
+	// CursorID identifies the open cursor of a paged result while HasMore is set
+	CursorID string `json:"cursor_id,omitempty"`
+	HasMore  bool   `json:"has_more"`
+	// Settings are the per-query settings the query ran with
+	Settings QuerySettings `json:"settings,omitempty"`
 }
 
-type CompareResult struct {
-	Query1Stats *QueryStats `json:"query1_stats"`
-	Query2Stats *QueryStats `json:"query2_stats"`
+// QueryStreamEvent is one line of a streamed (NDJSON) query result
+type QueryStreamEvent struct {
+	Type            string                 `json:"type"` // meta, row, end or error
+	QueryID         string                 `json:"query_id,omitempty"`
+	Columns         []string               `json:"columns,omitempty"`
+	ColumnTypes     []string               `json:"column_types,omitempty"`
+	Row             map[string]interface{} `json:"row,omitempty"`
+	Settings        QuerySettings          `json:"settings,omitempty"`
+	Stats           *QueryStats            `json:"stats,omitempty"`
+	RowCount        int                    `json:"row_count,omitempty"`
+	Truncated       bool                   `json:"truncated,omitempty"`
+	TruncatedReason string                 `json:"truncated_reason,omitempty"`
+	Error           string                 `json:"error,omitempty"`
+	// ErrorCode tells errors the console handles apart, e.g. CONFIRMATION_REQUIRED_CODE
+	ErrorCode string `json:"error_code,omitempty"`
 }
+
+const (
+	QueryStreamEventMeta  = "meta"
+	QueryStreamEventRow   = "row"
+	QueryStreamEventEnd   = "end"
+	QueryStreamEventError = "error"
+)
@@ -0,0 +1,58 @@
+package entity
+
+import "time"
+
+// Statuses of a change request. Pending requests are approved or rejected by a reviewer, or cancelled by
+// their author; approved requests run once and end as executed or failed.
+const (
+	ChangeRequestPending   = "pending"
+	ChangeRequestApproved  = "approved"
+	ChangeRequestRejected  = "rejected"
+	ChangeRequestCancelled = "cancelled"
+	ChangeRequestRunning   = "running"
+	ChangeRequestExecuted  = "executed"
+	ChangeRequestFailed    = "failed"
+)
+
+// ChangeRequest is a statement that needs the approval of a second user before the manager runs it,
+// see GuardActionApprove
+type ChangeRequest struct {
+	ID           int64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	ConnectionID int64  `gorm:"index;not null" json:"connection_id"`
+	Query        string `gorm:"type:text;not null" json:"query"`
+	// Kinds are the guarded statement kinds of Query, e.g. ["ddl", "mutation"]
+	Kinds         []string `gorm:"serializer:json" json:"kinds"`
+	Justification string   `gorm:"type:text;not null" json:"justification"`
+	Status        string   `gorm:"type:varchar(20);index;not null" json:"status"`
+	CreatedBy     string   `gorm:"type:varchar(255);not null" json:"created_by"`
+	ReviewedBy    string   `gorm:"type:varchar(255)" json:"reviewed_by"`
+	ReviewComment string   `gorm:"type:text" json:"review_comment"`
+	ExecutedBy    string   `gorm:"type:varchar(255)" json:"executed_by"`
+	// Result holds the outcome of every statement once the request ran
+	Result *ScriptResult `gorm:"serializer:json" json:"result,omitempty"`
+	Error  string        `gorm:"type:text" json:"error,omitempty"`
+	// Log records every step of the request in order
+	Log        []ChangeRequestLogEntry `gorm:"serializer:json" json:"log"`
+	ReviewedAt *time.Time              `json:"reviewed_at"`
+	ExecutedAt *time.Time              `json:"executed_at"`
+	CreatedAt  time.Time               `json:"created_at"`
+	UpdatedAt  time.Time               `json:"updated_at"`
+}
+
+// ChangeRequestLogEntry is one step of a change request, e.g. its approval or the start of its execution
+type ChangeRequestLogEntry struct {
+	At      time.Time `json:"at"`
+	User    string    `json:"user"`
+	Action  string    `json:"action"`
+	Message string    `json:"message,omitempty"`
+}
+
+// ChangeRequestFilter selects the change requests of a connection, an empty status selects all
+type ChangeRequestFilter struct {
+	Status string `query:"status"`
+}
+
+// ChangeRequestReview is the comment of an approval, rejection or cancellation
+type ChangeRequestReview struct {
+	Comment string `json:"comment"`
+}
@@ -0,0 +1,190 @@
+package entity
+
+const (
+	DefaultCompareIterations = 1
+	MaxCompareIterations     = 100
+	MaxCompareWarmup         = 20
+
+	// ResultCheckFull fetches both result sets and diffs them row by row
+	ResultCheckFull = "full"
+	// ResultCheckHash only compares row count and an order-insensitive hash computed by ClickHouse
+	ResultCheckHash = "hash"
+
+	MaxResultDiffSamples = 20
+
+	MinCompareVariants = 2
+	MaxCompareVariants = 10
+
+	// CacheModeCold drops the mark, uncompressed and query caches before every measured iteration
+	CacheModeCold = "cold"
+	// CacheModeColdWarm runs every iteration twice, right after dropping the caches and again with warm caches
+	CacheModeColdWarm = "cold_warm"
+)
+
+// Metrics used to rank comparison variants, lower is better for all of them
+const (
+	RankMetricDuration  = "duration"
+	RankMetricRowsRead  = "rows_read"
+	RankMetricBytesRead = "bytes_read"
+	RankMetricMemory    = "memory"
+)
+
+// QueryVariant is one labelled query taking part in a comparison.
+// Settings are applied to every execution of the variant, so the same query can be compared under different settings.
+type QueryVariant struct {
+	Label    string        `json:"label"`
+	Query    string        `json:"query"`
+	Settings QuerySettings `json:"settings,omitempty"`
+	// Parameters bind the {name:Type} placeholders of Query
+	Parameters QueryParameters `json:"parameters,omitempty"`
+}
+
+// CompareOptions controls how many times each query is executed during a comparison.
+// Warmup runs are executed first and discarded from the statistics.
+// ResultCheck optionally verifies both sides return the same data (empty, "full" or "hash").
+// CacheMode optionally drops server caches between runs (empty, "cold" or "cold_warm"),
+// which requires ConfirmCacheDrop on PRODUCTION connections.
+type CompareOptions struct {
+	Iterations       int    `json:"iterations"`
+	Warmup           int    `json:"warmup"`
+	ResultCheck      string `json:"result_check"`
+	CacheMode        string `json:"cache_mode"`
+	ConfirmCacheDrop bool   `json:"confirm_cache_drop"`
+}
+
+// MetricSummary holds aggregate statistics of a single metric across iterations
+type MetricSummary struct {
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	P95    float64 `json:"p95"`
+	P99    float64 `json:"p99"`
+	StdDev float64 `json:"stddev"`
+}
+
+// QueryBenchmark is the result of running one query for several iterations.
+// With cache mode "cold_warm" the top level holds the warm runs and Cold the runs right after dropping caches.
+type QueryBenchmark struct {
+	Iterations []*QueryStats   `json:"iterations"`
+	Duration   MetricSummary   `json:"duration"`
+	RowsRead   MetricSummary   `json:"rows_read"`
+	BytesRead  MetricSummary   `json:"bytes_read"`
+	Memory     MetricSummary   `json:"memory"`
+	Cold       *QueryBenchmark `json:"cold,omitempty"`
+	// UnloggedRuns counts the iterations without query_log stats, they are left out of the summaries
+	UnloggedRuns int `json:"unlogged_runs,omitempty"`
+}
+
+// ProfileEventDiff is the change of one ProfileEvents counter between two runs
+type ProfileEventDiff struct {
+	Event     string  `json:"event"`
+	Value1    float64 `json:"value1"`
+	Value2    float64 `json:"value2"`
+	ChangePct float64 `json:"change_pct"`
+}
+
+// VariantResult holds the benchmark of one variant.
+// Ranks maps each rank metric to the 1-based position of this variant (1 = best).
+// ResultDiff compares the variant against the first (reference) variant.
+type VariantResult struct {
+	Label      string          `json:"label"`
+	Query      string          `json:"query"`
+	Settings   QuerySettings   `json:"settings,omitempty"`
+	Stats      *QueryStats     `json:"stats"`
+	ColdStats  *QueryStats     `json:"cold_stats,omitempty"`
+	Benchmark  *QueryBenchmark `json:"benchmark"`
+	Ranks      map[string]int  `json:"ranks"`
+	ResultDiff *ResultDiff     `json:"result_diff,omitempty"`
+	// ProfileEventDiffs compares the mean ProfileEvents against the first (reference) variant
+	ProfileEventDiffs []ProfileEventDiff `json:"profile_event_diffs,omitempty"`
+}
+
+type CompareResult struct {
+	// Query1Stats and Query2Stats hold the mean of all measured iterations of the first two variants
+	Query1Stats     *QueryStats      `json:"query1_stats"`
+	Query2Stats     *QueryStats      `json:"query2_stats"`
+	Query1Benchmark *QueryBenchmark  `json:"query1_benchmark"`
+	Query2Benchmark *QueryBenchmark  `json:"query2_benchmark"`
+	ResultDiff      *ResultDiff      `json:"result_diff,omitempty"`
+	Variants        []*VariantResult `json:"variants"`
+	// Rankings lists variant labels per rank metric, best first
+	Rankings   map[string][]string `json:"rankings"`
+	Iterations int                 `json:"iterations"`
+	Warmup     int                 `json:"warmup"`
+	CacheMode  string              `json:"cache_mode,omitempty"`
+	Warnings   []string            `json:"warnings,omitempty"`
+}
+
+// CrossCompareThresholdPct is the relative change above which a metric difference is flagged
+const CrossCompareThresholdPct = 10.0
+
+type ConnectionSummary struct {
+	ID            int64  `json:"id"`
+	Name          string `json:"name"`
+	Label         string `json:"label"`
+	ServerVersion string `json:"server_version"`
+}
+
+// CompareDifferences describes how the second run differs from the first one.
+// Change percentages are relative to the first run, positive means the second run is higher.
+type CompareDifferences struct {
+	ServerVersionDiffers bool               `json:"server_version_differs"`
+	DurationChangePct    float64            `json:"duration_change_pct"`
+	RowsReadChangePct    float64            `json:"rows_read_change_pct"`
+	MemoryChangePct      float64            `json:"memory_change_pct"`
+	ProfileEvents        []ProfileEventDiff `json:"profile_events,omitempty"`
+	Flags                []string           `json:"flags"`
+}
+
+// CrossConnectionCompareResult is the result of running one query against two connections
+type CrossConnectionCompareResult struct {
+	Query                string             `json:"query"`
+	Connection1          ConnectionSummary  `json:"connection1"`
+	Connection2          ConnectionSummary  `json:"connection2"`
+	Connection1Stats     *QueryStats        `json:"connection1_stats"`
+	Connection2Stats     *QueryStats        `json:"connection2_stats"`
+	Connection1Benchmark *QueryBenchmark    `json:"connection1_benchmark"`
+	Connection2Benchmark *QueryBenchmark    `json:"connection2_benchmark"`
+	Differences          CompareDifferences `json:"differences"`
+	ResultDiff           *ResultDiff        `json:"result_diff,omitempty"`
+	Iterations           int                `json:"iterations"`
+	Warmup               int                `json:"warmup"`
+	CacheMode            string             `json:"cache_mode,omitempty"`
+	Warnings             []string           `json:"warnings,omitempty"`
+}
+
+// ResultHash is an order-insensitive fingerprint of a result set
+type ResultHash struct {
+	Rows uint64 `json:"rows"`
+	Hash string `json:"hash"`
+	// Columns are the names and types of the result columns, e.g. "id UInt64"
+	Columns []string `json:"columns"`
+}
+
+type ColumnValueDiff struct {
+	Row    int         `json:"row"`
+	Column string      `json:"column"`
+	Value1 interface{} `json:"value1"`
+	Value2 interface{} `json:"value2"`
+}
+
+// ResultDiff describes whether two result sets are equal.
+// MissingRows are present in the first result only, ExtraRows in the second result only.
+// Both lists and ValueDiffs are capped at MaxResultDiffSamples entries.
+type ResultDiff struct {
+	Mode            string                   `json:"mode"`
+	Equal           bool                     `json:"equal"`
+	RowCount1       uint64                   `json:"row_count1"`
+	RowCount2       uint64                   `json:"row_count2"`
+	Hash1           string                   `json:"hash1,omitempty"`
+	Hash2           string                   `json:"hash2,omitempty"`
+	ColumnsMatch    bool                     `json:"columns_match"`
+	Columns1        []string                 `json:"columns1,omitempty"`
+	Columns2        []string                 `json:"columns2,omitempty"`
+	MissingRowCount int                      `json:"missing_row_count"`
+	ExtraRowCount   int                      `json:"extra_row_count"`
+	MissingRows     []map[string]interface{} `json:"missing_rows,omitempty"`
+	ExtraRows       []map[string]interface{} `json:"extra_rows,omitempty"`
+	ValueDiffs      []ColumnValueDiff        `json:"value_diffs,omitempty"`
+}
@@ -0,0 +1,78 @@
+package entity
+
+import "time"
+
+const (
+	DefaultSuiteRegressionThresholdPct = 20.0
+	DefaultSuiteIterations             = 3
+
+	SuiteRunStatusRunning   = "running"
+	SuiteRunStatusCompleted = "completed"
+	SuiteRunStatusFailed    = "failed"
+
+	SuiteRunTriggerManual   = "manual"
+	SuiteRunTriggerSchedule = "schedule"
+)
+
+// ComparisonSuite groups saved FavoriteComparisons so they can be run together as a regression suite.
+// The first completed run becomes the baseline unless another run is promoted later.
+type ComparisonSuite struct {
+	ID                      int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	ConnectionID            int64      `gorm:"index;not null" json:"connection_id"`
+	Name                    string     `gorm:"type:varchar(255);not null" json:"name"`
+	Description             string     `gorm:"type:text" json:"description"`
+	FavoriteIDs             []int64    `gorm:"serializer:json" json:"favorite_ids"`
+	Iterations              int        `gorm:"default:3" json:"iterations"`
+	Warmup                  int        `gorm:"default:1" json:"warmup"`
+	RegressionThresholdPct  float64    `gorm:"default:20" json:"regression_threshold_pct"`
+	ScheduleIntervalMinutes int        `gorm:"default:0" json:"schedule_interval_minutes"` // 0 = manual only
+	BaselineRunID           int64      `json:"baseline_run_id"`
+	LastRunAt               *time.Time `json:"last_run_at"`
+	CreatedAt               time.Time  `json:"created_at"`
+	UpdatedAt               time.Time  `json:"updated_at"`
+}
+
+// IsDue reports whether a scheduled suite should run at the given time
+func (s *ComparisonSuite) IsDue(now time.Time) bool {
+	if s.ScheduleIntervalMinutes <= 0 {
+		return false
+	}
+	if s.LastRunAt == nil {
+		return true
+	}
+	return !s.LastRunAt.Add(time.Duration(s.ScheduleIntervalMinutes) * time.Minute).After(now)
+}
+
+type ComparisonSuiteRun struct {
+	ID              int64                       `gorm:"primaryKey;autoIncrement" json:"id"`
+	SuiteID         int64                       `gorm:"index;not null" json:"suite_id"`
+	Status          string                      `gorm:"type:varchar(20)" json:"status"`
+	Trigger         string                      `gorm:"type:varchar(20)" json:"trigger"`
+	IsBaseline      bool                        `json:"is_baseline"`
+	BaselineRunID   int64                       `json:"baseline_run_id"`
+	RegressionCount int                         `json:"regression_count"`
+	Error           string                      `gorm:"type:text" json:"error"`
+	StartedAt       time.Time                   `json:"started_at"`
+	FinishedAt      *time.Time                  `json:"finished_at"`
+	Results         []*ComparisonSuiteRunResult `gorm:"foreignKey:RunID" json:"results,omitempty"`
+}
+
+// ComparisonSuiteRunResult holds the stats of one query of one favorite within a suite run
+type ComparisonSuiteRunResult struct {
+	ID                      int64   `gorm:"primaryKey;autoIncrement" json:"id"`
+	RunID                   int64   `gorm:"index;not null" json:"run_id"`
+	FavoriteID              int64   `gorm:"index" json:"favorite_id"`
+	FavoriteTitle           string  `json:"favorite_title"`
+	Variant                 string  `gorm:"type:varchar(255)" json:"variant"`
+	Query                   string  `gorm:"type:text" json:"query"`
+	ExecutionTimeMs         float64 `json:"execution_time_ms"`
+	RowsRead                uint64  `json:"rows_read"`
+	BytesRead               uint64  `json:"bytes_read"`
+	MemoryPeak              uint64  `json:"memory_peak"`
+	BaselineExecutionTimeMs float64 `json:"baseline_execution_time_ms"`
+	BaselineBytesRead       uint64  `json:"baseline_bytes_read"`
+	DurationChangePct       float64 `json:"duration_change_pct"`
+	BytesReadChangePct      float64 `json:"bytes_read_change_pct"`
+	Regressed               bool    `json:"regressed"`
+	Error                   string  `gorm:"type:text" json:"error"`
+}
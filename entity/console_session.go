@@ -0,0 +1,25 @@
+package entity
+
+import "time"
+
+const (
+	// ConsoleSessionIdleTimeout closes console sessions that ran nothing for a while
+	ConsoleSessionIdleTimeout = 30 * time.Minute
+	// MaxConsoleSessions bounds the dedicated ClickHouse connections held by console sessions
+	MaxConsoleSessions = 32
+)
+
+// ConsoleSession is a console session whose SET, USE and temporary tables persist across runs
+type ConsoleSession struct {
+	ID           string `json:"id"`
+	ConnectionID int64  `json:"connection_id"`
+	// Database is the current database of the session, changed by USE
+	Database string `json:"database"`
+	// Settings are the settings changed in the session, e.g. by SET
+	Settings []SessionSetting `json:"settings"`
+}
+
+type SessionSetting struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
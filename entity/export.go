@@ -0,0 +1,53 @@
+package entity
+
+// ExportFormat describes a download format of query results
+type ExportFormat struct {
+	Name        string `json:"name"`
+	Label       string `json:"label"`
+	Extension   string `json:"extension"`
+	ContentType string `json:"content_type"`
+	// ClickHouseFormat is the output format the server renders itself over HTTP, empty when the manager encodes the rows
+	ClickHouseFormat string `json:"clickhouse_format,omitempty"`
+	// NeedsHTTP is set for formats only the server can produce, they are unavailable on native connections
+	NeedsHTTP bool `json:"needs_http"`
+}
+
+const (
+	ExportFormatCSV         = "csv"
+	ExportFormatTSV         = "tsv"
+	ExportFormatJSONEachRow = "jsoneachrow"
+	ExportFormatParquet     = "parquet"
+	ExportFormatXLSX        = "xlsx"
+)
+
+// ExportFormats lists the supported formats in the order the console offers them
+var ExportFormats = []ExportFormat{
+	{Name: ExportFormatCSV, Label: "CSV", Extension: "csv", ContentType: "text/csv; charset=utf-8", ClickHouseFormat: "CSVWithNames"},
+	{Name: ExportFormatTSV, Label: "TSV", Extension: "tsv", ContentType: "text/tab-separated-values; charset=utf-8", ClickHouseFormat: "TSVWithNames"},
+	{Name: ExportFormatJSONEachRow, Label: "JSONEachRow", Extension: "jsonl", ContentType: "application/x-ndjson", ClickHouseFormat: "JSONEachRow"},
+	{Name: ExportFormatParquet, Label: "Parquet", Extension: "parquet", ContentType: "application/vnd.apache.parquet", ClickHouseFormat: "Parquet", NeedsHTTP: true},
+	{Name: ExportFormatXLSX, Label: "Excel (XLSX)", Extension: "xlsx", ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+}
+
+// FindExportFormat returns the export format with the given name
+func FindExportFormat(name string) (ExportFormat, bool) {
+	for _, f := range ExportFormats {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return ExportFormat{}, false
+}
+
+type ExportRequest struct {
+	Query    string `json:"query" form:"query"`
+	Format   string `json:"format" form:"format"`
+	FileName string `json:"file_name" form:"file_name"` // Optional, without or with the format's extension
+	QueryID  string `json:"query_id" form:"query_id"`
+	Timezone string `json:"timezone" form:"timezone"` // Optional, as in QueryOptions
+	// Parameters bind the {name:Type} placeholders of Query
+	Parameters QueryParameters `json:"parameters" form:"-"`
+	SessionID  string          `json:"session_id" form:"session_id"` // Optional, as in QueryOptions
+	Settings   QuerySettings   `json:"settings" form:"-"`            // Optional, as in QueryOptions
+	Confirm    string          `json:"confirm" form:"confirm"`       // Optional, as in QueryOptions
+}
@@ -2,11 +2,26 @@ package entity
 
 import "time"
 
+// FavoriteComparison is a saved comparison. Query1 and Query2 mirror the first two variants
+// so favorites saved before variants existed keep working.
 type FavoriteComparison struct {
-	ID           int64     `gorm:"primaryKey;autoIncrement" json:"id"`
-	ConnectionID int64     `gorm:"index;not null" json:"connection_id"`
-	Title        string    `gorm:"type:text;not null" json:"title"`
-	Query1       string    `gorm:"type:text;not null" json:"query1"`
-	Query2       string    `gorm:"type:text;not null" json:"query2"`
-	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
+	ID           int64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	ConnectionID int64          `gorm:"index;not null" json:"connection_id"`
+	Title        string         `gorm:"type:text;not null" json:"title"`
+	Query1       string         `gorm:"type:text;not null" json:"query1"`
+	Query2       string         `gorm:"type:text;not null" json:"query2"`
+	Variants     []QueryVariant `gorm:"serializer:json" json:"variants"`
+	CreatedAt    time.Time      `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// GetVariants returns the saved variants, falling back to Query1/Query2 for older favorites
+func (f *FavoriteComparison) GetVariants() []QueryVariant {
+	if len(f.Variants) > 0 {
+		return f.Variants
+	}
+
+	return []QueryVariant{
+		{Label: "Query 1", Query: f.Query1},
+		{Label: "Query 2", Query: f.Query2},
+	}
 }
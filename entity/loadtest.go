@@ -0,0 +1,78 @@
+package entity
+
+import "time"
+
+const (
+	DefaultLoadTestConcurrency     = 4
+	DefaultLoadTestDurationSeconds = 10
+	MaxLoadTestConcurrency         = 64
+	MaxLoadTestDurationSeconds     = 600
+	MaxLoadTestIterations          = 100000
+	MaxLoadTestQueries             = 20
+	MaxLoadTestErrorSamples        = 10
+	// MaxLoadTestHistory is the number of finished load tests kept in memory
+	MaxLoadTestHistory = 20
+
+	LoadTestStatusRunning   = "running"
+	LoadTestStatusCompleted = "completed"
+	LoadTestStatusCancelled = "cancelled"
+	LoadTestStatusFailed    = "failed"
+)
+
+// LoadTestQuery is one query of a load test. Queries are picked at random proportionally to their Weight.
+type LoadTestQuery struct {
+	Label  string `json:"label"`
+	Query  string `json:"query"`
+	Weight int    `json:"weight"`
+}
+
+// LoadTestConfig describes a load test. The test stops after DurationSeconds or after
+// Iterations executions in total, whichever comes first.
+type LoadTestConfig struct {
+	Queries         []LoadTestQuery `json:"queries"`
+	Concurrency     int             `json:"concurrency"`
+	DurationSeconds int             `json:"duration_seconds"`
+	Iterations      int             `json:"iterations"`
+}
+
+// LoadTestServerStats aggregates the load test queries found in system.query_log
+type LoadTestServerStats struct {
+	QueryCount     uint64  `json:"query_count"`
+	CPUTimeMs      float64 `json:"cpu_time_ms"`
+	AvgCPUTimeMs   float64 `json:"avg_cpu_time_ms"`
+	PeakMemory     uint64  `json:"peak_memory"`
+	AvgMemory      float64 `json:"avg_memory"`
+	TotalReadBytes uint64  `json:"total_read_bytes"`
+	TotalReadRows  uint64  `json:"total_read_rows"`
+}
+
+type LoadTestQueryReport struct {
+	Label     string               `json:"label"`
+	Query     string               `json:"query"`
+	Weight    int                  `json:"weight"`
+	Completed int64                `json:"completed"`
+	Errors    int64                `json:"errors"`
+	QPS       float64              `json:"qps"`
+	LatencyMs MetricSummary        `json:"latency_ms"`
+	Server    *LoadTestServerStats `json:"server,omitempty"`
+}
+
+// LoadTestReport is both the live progress and the final result of a load test.
+// Server stats are only filled once the test has finished.
+type LoadTestReport struct {
+	ID           string                 `json:"id"`
+	ConnectionID int64                  `json:"connection_id"`
+	Status       string                 `json:"status"`
+	Config       LoadTestConfig         `json:"config"`
+	StartedAt    time.Time              `json:"started_at"`
+	FinishedAt   *time.Time             `json:"finished_at"`
+	ElapsedMs    int64                  `json:"elapsed_ms"`
+	Completed    int64                  `json:"completed"`
+	Errors       int64                  `json:"errors"`
+	QPS          float64                `json:"qps"`
+	LatencyMs    MetricSummary          `json:"latency_ms"`
+	Queries      []*LoadTestQueryReport `json:"queries"`
+	ErrorSamples []string               `json:"error_samples"`
+	Server       *LoadTestServerStats   `json:"server,omitempty"`
+	Error        string                 `json:"error,omitempty"`
+}
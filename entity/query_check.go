@@ -0,0 +1,53 @@
+package entity
+
+const (
+	// ValidateModeAST only parses the statements, like EXPLAIN AST
+	ValidateModeAST = "ast"
+	// ValidateModeSyntax also resolves them against the schema, like EXPLAIN SYNTAX, and returns the rewritten query
+	ValidateModeSyntax = "syntax"
+)
+
+type FormatRequest struct {
+	Query string `json:"query"`
+	// SingleLine formats with formatQuerySingleLine instead of formatQuery
+	SingleLine bool `json:"single_line"`
+}
+
+type FormatResult struct {
+	// Query is the formatted script, empty when a statement does not parse
+	Query  string             `json:"query"`
+	Errors []QuerySyntaxError `json:"errors"`
+}
+
+type ValidateRequest struct {
+	Query string `json:"query"`
+	// Mode is ast (default) or syntax
+	Mode string `json:"mode"`
+}
+
+// ValidatedStatement is a statement the server accepted with its EXPLAIN output: the syntax tree in ast mode,
+// the query as the server rewrites it in syntax mode. Mode is the one the statement was checked in, EXPLAIN
+// SYNTAX only takes SELECT queries so other statements are checked in ast mode whatever the requested mode.
+type ValidatedStatement struct {
+	Index     int    `json:"index"`
+	Statement string `json:"statement"`
+	Mode      string `json:"mode"`
+	Explain   string `json:"explain"`
+}
+
+type ValidateResult struct {
+	Valid      bool                 `json:"valid"`
+	Mode       string               `json:"mode"`
+	Statements []ValidatedStatement `json:"statements"`
+	Errors     []QuerySyntaxError   `json:"errors"`
+}
+
+// QuerySyntaxError is a statement the server rejected. Line and Column are 1-based and point into the
+// submitted text, they are 0 when the exception has no position, e.g. for an unknown table.
+type QuerySyntaxError struct {
+	Statement int    `json:"statement"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	Code      int    `json:"code"`
+	Message   string `json:"message"`
+}
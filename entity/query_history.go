@@ -1,10 +1,68 @@
 package entity
 
-import "time"
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
 
+// Retention of the console history
+const (
+	DefaultHistoryRetention = 50
+	MaxHistoryRetention     = 10000
+	DefaultHistoryPageSize  = 50
+	MaxHistoryPageSize      = 500
+)
+
+// QueryHistory is one console run. Running the same query again right after only bumps RunCount and
+// refreshes the outcome instead of adding an entry. Pinned entries are never pruned.
 type QueryHistory struct {
-	ID           int64     `gorm:"primaryKey;autoIncrement" json:"id"`
-	ConnectionID int64     `gorm:"index;not null" json:"connection_id"`
-	Query        string    `gorm:"type:text;not null" json:"query"`
-	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
+	ID           int64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	ConnectionID int64  `gorm:"index;not null" json:"connection_id"`
+	Query        string `gorm:"type:text;not null" json:"query"`
+	// Parameters are the placeholder values the query ran with
+	Parameters QueryParameters `gorm:"serializer:json" json:"parameters,omitempty"`
+	// Settings are the per-query settings the query ran with
+	Settings QuerySettings `gorm:"serializer:json" json:"settings,omitempty"`
+	Database string        `gorm:"type:varchar(255)" json:"database"`
+	// User is who ran the query, empty when the manager runs without an authenticating proxy
+	User       string `gorm:"type:varchar(255);index" json:"user"`
+	DurationMs int64  `json:"duration_ms"`
+	RowsRead   uint64 `json:"rows_read"`
+	BytesRead  uint64 `json:"bytes_read"`
+	// Error is the message of a failed run, empty on success
+	Error     string    `gorm:"type:text" json:"error,omitempty"`
+	Pinned    bool      `gorm:"default:false;index" json:"pinned"`
+	RunCount  int       `gorm:"default:1" json:"run_count"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// SameRun reports whether h ran the same query as other, with the same values, on the same database and by the same user
+func (h *QueryHistory) SameRun(other *QueryHistory) bool {
+	return h.Query == other.Query &&
+		h.Database == other.Database &&
+		h.User == other.User &&
+		sameJSON(h.Parameters, other.Parameters) &&
+		sameJSON(h.Settings, other.Settings)
+}
+
+// sameJSON compares two values as they are stored, a map read back from the database holds float64 numbers
+// where the freshly run one holds ints
+func sameJSON[M ~map[string]V, V any](a, b M) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+// HistoryFilter selects console history entries
+type HistoryFilter struct {
+	// Search is full text matched against the query and the error message
+	Search     string `query:"q"`
+	PinnedOnly bool   `query:"pinned"`
+	FailedOnly bool   `query:"failed"`
+	User       string `query:"user"`
+	Limit      int    `query:"limit"`
 }
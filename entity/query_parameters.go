@@ -0,0 +1,11 @@
+package entity
+
+// QueryParameters are the values of {name:Type} placeholders, in text form as ClickHouse parses them,
+// e.g. {"uid": "42", "day": "2024-01-31"}
+type QueryParameters map[string]string
+
+// QueryParameter is a {name:Type} placeholder found in a query
+type QueryParameter struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
@@ -0,0 +1,5 @@
+package entity
+
+// QuerySettings are ClickHouse settings applied to a single statement, e.g. {"max_threads": 4}.
+// Values are numbers, strings or booleans as decoded from JSON.
+type QuerySettings map[string]interface{}
@@ -0,0 +1,86 @@
+package entity
+
+import "time"
+
+const (
+	DefaultReplayWindowHours            = 24
+	MaxReplayWindowHours                = 24 * 30
+	DefaultReplayFingerprints           = 20
+	MaxReplayFingerprints               = 100
+	DefaultReplaySamples                = 3
+	MaxReplaySamples                    = 10
+	DefaultReplayRegressionThresholdPct = 20.0
+	MaxReplayErrorSamples               = 3
+
+	ReplayStatusRunning   = "running"
+	ReplayStatusCompleted = "completed"
+	ReplayStatusFailed    = "failed"
+	ReplayStatusCancelled = "cancelled"
+)
+
+// QueryLogSample is a group of logged SELECTs sharing the same normalizeQuery fingerprint,
+// with a random sample of the concrete statements and their logged metrics
+type QueryLogSample struct {
+	Fingerprint   string
+	Executions    uint64
+	AvgDurationMs float64
+	Queries       []string
+	DurationsMs   []uint64
+	BytesRead     []uint64
+}
+
+// ReplaySample is the outcome of replaying one logged statement on the target connection.
+// The source side comes from system.query_log, so the source is never queried for the timings.
+type ReplaySample struct {
+	Query            string  `json:"query"`
+	SourceDurationMs float64 `json:"source_duration_ms"`
+	SourceBytesRead  uint64  `json:"source_bytes_read"`
+	TargetDurationMs float64 `json:"target_duration_ms"`
+	TargetBytesRead  uint64  `json:"target_bytes_read"`
+	Error            string  `json:"error,omitempty"`
+	HashChecked      bool    `json:"hash_checked"`
+	HashMismatch     bool    `json:"hash_mismatch"`
+}
+
+// ReplayFingerprintResult aggregates the replayed samples of one query fingerprint
+type ReplayFingerprintResult struct {
+	Fingerprint        string          `json:"fingerprint"`
+	Executions         uint64          `json:"executions"`
+	LoggedAvgDuration  float64         `json:"logged_avg_duration_ms"`
+	Replayed           int             `json:"replayed"`
+	Errors             int             `json:"errors"`
+	HashMismatches     int             `json:"hash_mismatches"`
+	SourceDurationMs   float64         `json:"source_duration_ms"`
+	TargetDurationMs   float64         `json:"target_duration_ms"`
+	DurationChangePct  float64         `json:"duration_change_pct"`
+	SourceBytesRead    float64         `json:"source_bytes_read"`
+	TargetBytesRead    float64         `json:"target_bytes_read"`
+	BytesReadChangePct float64         `json:"bytes_read_change_pct"`
+	Regressed          bool            `json:"regressed"`
+	ErrorSamples       []string        `json:"error_samples,omitempty"`
+	Samples            []*ReplaySample `json:"samples"`
+}
+
+// ReplayRun replays sampled SELECTs of a source connection's query log on a target connection,
+// e.g. before a ClickHouse upgrade or a schema change
+type ReplayRun struct {
+	ID                     int64                      `gorm:"primaryKey;autoIncrement" json:"id"`
+	SourceConnectionID     int64                      `gorm:"index;not null" json:"source_connection_id"`
+	TargetConnectionID     int64                      `gorm:"not null" json:"target_connection_id"`
+	WindowHours            int                        `json:"window_hours"`
+	MaxFingerprints        int                        `json:"max_fingerprints"`
+	SamplesPerFingerprint  int                        `json:"samples_per_fingerprint"`
+	CompareResults         bool                       `json:"compare_results"`
+	RegressionThresholdPct float64                    `json:"regression_threshold_pct"`
+	Status                 string                     `gorm:"type:varchar(20)" json:"status"`
+	FingerprintCount       int                        `json:"fingerprint_count"`
+	ErrorCount             int                        `json:"error_count"`
+	MismatchCount          int                        `json:"mismatch_count"`
+	RegressionCount        int                        `json:"regression_count"`
+	TotalSamples           int                        `json:"total_samples"` // Known once the query log is sampled
+	ReplayedSamples        int                        `json:"replayed_samples"`
+	Fingerprints           []*ReplayFingerprintResult `gorm:"serializer:json" json:"fingerprints,omitempty"`
+	Error                  string                     `gorm:"type:text" json:"error"`
+	StartedAt              time.Time                  `json:"started_at"`
+	FinishedAt             *time.Time                 `json:"finished_at"`
+}
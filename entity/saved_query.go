@@ -0,0 +1,44 @@
+package entity
+
+import "time"
+
+// SavedQuery is a query kept in the library. It belongs to the connection it was saved on and, when
+// AnyConnection is set, can be opened on every connection.
+type SavedQuery struct {
+	ID            int64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	ConnectionID  int64  `gorm:"index;not null" json:"connection_id"`
+	AnyConnection bool   `gorm:"default:false;index" json:"any_connection"`
+	Title         string `gorm:"type:varchar(255);not null" json:"title"`
+	Description   string `gorm:"type:text" json:"description"`
+	Query         string `gorm:"type:text;not null" json:"query"`
+	// Parameters are the default values of the query's {name:Type} placeholders
+	Parameters QueryParameters `gorm:"serializer:json" json:"parameters,omitempty"`
+	Tags       []string        `gorm:"serializer:json" json:"tags"`
+	// Folder is a slash separated path, e.g. "reports/daily", empty for the top level
+	Folder    string    `gorm:"type:varchar(255);index" json:"folder"`
+	CreatedBy string    `gorm:"type:varchar(255)" json:"created_by"`
+	UpdatedBy string    `gorm:"type:varchar(255)" json:"updated_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// VisibleOn reports whether the query can be opened on the connection
+func (q *SavedQuery) VisibleOn(connectionID int64) bool {
+	return q.ConnectionID == connectionID || q.AnyConnection
+}
+
+// SavedQueryFilter selects saved queries of the library
+type SavedQueryFilter struct {
+	// Search is matched against the title, description and SQL
+	Search string `query:"q"`
+	// Folder selects the folder and its subfolders
+	Folder string `query:"folder"`
+	Tag    string `query:"tag"`
+}
+
+// SavedQueryImportResult counts the queries of an imported bundle, queries with the title of one
+// already in the same folder replace it
+type SavedQueryImportResult struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+}
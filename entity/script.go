@@ -0,0 +1,42 @@
+package entity
+
+// MaxScriptStatements bounds the statements of a console script
+const MaxScriptStatements = 100
+
+const (
+	ScriptStatementOK      = "ok"
+	ScriptStatementError   = "error"
+	ScriptStatementSkipped = "skipped"
+)
+
+// ScriptOptions are the per-request options of a console script
+type ScriptOptions struct {
+	// ContinueOnError runs the remaining statements after a failure instead of skipping them
+	ContinueOnError bool            `json:"continue_on_error"`
+	Timezone        string          `json:"timezone"`
+	Parameters      QueryParameters `json:"parameters"`
+	SessionID       string          `json:"session_id"`
+	Settings        QuerySettings   `json:"settings"`
+	// Confirm is the typed confirmation of guarded statements, as in QueryOptions
+	Confirm string `json:"confirm"`
+}
+
+// ScriptStatementResult is the outcome of one statement: the rows of a query, or only the stats of
+// statements that return none (DDL, INSERT, SET ...)
+type ScriptStatementResult struct {
+	Index     int          `json:"index"`
+	Statement string       `json:"statement"`
+	Status    string       `json:"status"`
+	Result    *QueryResult `json:"result,omitempty"`
+	Stats     *QueryStats  `json:"stats,omitempty"`
+	Error     string       `json:"error,omitempty"`
+}
+
+type ScriptResult struct {
+	Statements      []ScriptStatementResult `json:"statements"`
+	Succeeded       int                     `json:"succeeded"`
+	Failed          int                     `json:"failed"`
+	Skipped         int                     `json:"skipped"`
+	ContinueOnError bool                    `json:"continue_on_error"`
+	DurationMs      int64                   `json:"duration_ms"`
+}
@@ -0,0 +1,94 @@
+package entity
+
+// Kinds of statements the guard checks, other statements (SELECT, INSERT, SET ...) always run
+const (
+	StatementKindDDL      = "ddl"      // CREATE, ALTER, RENAME, ATTACH, DETACH, EXCHANGE, GRANT, REVOKE ...
+	StatementKindMutation = "mutation" // ALTER ... UPDATE/DELETE and lightweight DELETE/UPDATE
+	StatementKindDrop     = "drop"
+	StatementKindTruncate = "truncate"
+	StatementKindKill     = "kill"
+	StatementKindSystem   = "system"
+)
+
+// GuardedStatementKinds lists the kinds a guard policy can name
+var GuardedStatementKinds = []string{
+	StatementKindDDL,
+	StatementKindMutation,
+	StatementKindDrop,
+	StatementKindTruncate,
+	StatementKindKill,
+	StatementKindSystem,
+}
+
+// What the guard does with a statement kind, ordered from the least to the most strict
+const (
+	GuardActionAllow   = "allow"
+	GuardActionConfirm = "confirm"
+	// GuardActionApprove only runs the statement as a change request approved by a second user
+	GuardActionApprove = "approve"
+	GuardActionBlock   = "block"
+)
+
+// GuardActions lists the actions from the least to the most strict
+var GuardActions = []string{GuardActionAllow, GuardActionConfirm, GuardActionApprove, GuardActionBlock}
+
+// GuardPolicy maps statement kinds to the guard action of a connection label, missing kinds are allowed
+type GuardPolicy map[string]string
+
+// DefaultGuardPolicies apply to the labels whose policy is not configured
+func DefaultGuardPolicies() map[string]GuardPolicy {
+	return map[string]GuardPolicy{
+		LabelDevelopment: {},
+		LabelStaging: {
+			StatementKindDrop:     GuardActionConfirm,
+			StatementKindTruncate: GuardActionConfirm,
+		},
+		LabelProduction: {
+			StatementKindDDL:      GuardActionApprove,
+			StatementKindMutation: GuardActionApprove,
+			StatementKindDrop:     GuardActionConfirm,
+			StatementKindTruncate: GuardActionConfirm,
+			StatementKindKill:     GuardActionConfirm,
+			StatementKindSystem:   GuardActionConfirm,
+		},
+	}
+}
+
+// Response codes of statements stopped by the guard
+const (
+	CONFIRMATION_REQUIRED_CODE   = "40"
+	STATEMENT_BLOCKED_CODE       = "41"
+	CHANGE_REQUEST_REQUIRED_CODE = "42"
+)
+
+// StatementGuardError is returned for a statement the guard stopped. With GuardActionConfirm the request
+// runs once it is sent again with ConfirmToken typed in as its confirmation, with GuardActionApprove it has
+// to be submitted as a ChangeRequest.
+type StatementGuardError struct {
+	Label        string
+	Kind         string
+	Action       string
+	Statement    string
+	ConfirmToken string
+}
+
+// Code is the response code of the error
+func (e *StatementGuardError) Code() string {
+	switch e.Action {
+	case GuardActionBlock:
+		return STATEMENT_BLOCKED_CODE
+	case GuardActionApprove:
+		return CHANGE_REQUEST_REQUIRED_CODE
+	}
+	return CONFIRMATION_REQUIRED_CODE
+}
+
+func (e *StatementGuardError) Error() string {
+	switch e.Action {
+	case GuardActionBlock:
+		return "the " + e.Kind + " statement is blocked on " + e.Label + " connections: " + e.Statement
+	case GuardActionApprove:
+		return "the " + e.Kind + " statement needs a change request approved by a second user on " + e.Label + " connections: " + e.Statement
+	}
+	return "the " + e.Kind + " statement needs confirmation on " + e.Label + " connections, type \"" + e.ConfirmToken + "\" to run it: " + e.Statement
+}
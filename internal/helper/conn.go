@@ -0,0 +1,48 @@
+package helper
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// disconnectPollInterval is how often WatchDisconnect checks the client connection
+const disconnectPollInterval = 250 * time.Millisecond
+
+// WatchDisconnect returns a context that is cancelled when parent is done or when the peer closes conn.
+// The HTTP server does not notice a client going away while a handler runs, so long running handlers
+// use this to stop their work. The returned function must be called before the handler returns.
+func WatchDisconnect(parent context.Context, conn net.Conn) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	if conn == nil {
+		return ctx, cancel
+	}
+
+	stopped := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(disconnectPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopped:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if peerClosed(conn) {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	return ctx, func() {
+		close(stopped)
+		<-finished
+		cancel()
+	}
+}
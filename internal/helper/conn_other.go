@@ -0,0 +1,10 @@
+//go:build !unix
+
+package helper
+
+import "net"
+
+// peerClosed cannot peek at sockets on this platform, disconnects are not detected
+func peerClosed(conn net.Conn) bool {
+	return false
+}
@@ -0,0 +1,63 @@
+//go:build unix
+
+package helper_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rahmatrdn/go-ch-manager/internal/helper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchDisconnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("cannot listen on loopback:", err)
+	}
+	defer listener.Close()
+
+	dial := func(t *testing.T) (client, server net.Conn) {
+		client, err := net.Dial("tcp", listener.Addr().String())
+		assert.NoError(t, err)
+		server, err = listener.Accept()
+		assert.NoError(t, err)
+		return client, server
+	}
+
+	t.Run("Cancelled When Peer Closes", func(t *testing.T) {
+		client, server := dial(t)
+		defer server.Close()
+
+		ctx, stop := helper.WatchDisconnect(context.Background(), server)
+		defer stop()
+
+		client.Close()
+		select {
+		case <-ctx.Done():
+		case <-time.After(2 * time.Second):
+			t.Fatal("context was not cancelled after the peer closed the connection")
+		}
+	})
+
+	t.Run("Pending Data Is Not Consumed", func(t *testing.T) {
+		client, server := dial(t)
+		defer client.Close()
+		defer server.Close()
+
+		_, err := client.Write([]byte("next request"))
+		assert.NoError(t, err)
+
+		ctx, stop := helper.WatchDisconnect(context.Background(), server)
+		time.Sleep(600 * time.Millisecond)
+		assert.NoError(t, ctx.Err())
+		stop()
+
+		buf := make([]byte, 12)
+		_, err = server.Read(buf)
+		assert.NoError(t, err)
+		assert.Equal(t, "next request", string(buf))
+	})
+}
@@ -0,0 +1,29 @@
+//go:build unix
+
+package helper
+
+import (
+	"net"
+	"syscall"
+)
+
+// peerClosed peeks at the socket without consuming data, an orderly shutdown reads zero bytes
+func peerClosed(conn net.Conn) bool {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return false
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return false
+	}
+
+	closed := false
+	_ = raw.Read(func(fd uintptr) bool {
+		var buf [1]byte
+		n, _, err := syscall.Recvfrom(int(fd), buf[:], syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+		closed = (n == 0 && err == nil) || err == syscall.ECONNRESET
+		return true
+	})
+	return closed
+}
@@ -0,0 +1,63 @@
+package helper
+
+import (
+	"math"
+	"sort"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+)
+
+// Summarize calculates min, max, mean, median, p95, p99 and sample standard deviation of values
+func Summarize(values []float64) entity.MetricSummary {
+	if len(values) == 0 {
+		return entity.MetricSummary{}
+	}
+
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	mean := sum / float64(len(sorted))
+
+	var stdDev float64
+	if len(sorted) > 1 {
+		var sq float64
+		for _, v := range sorted {
+			sq += (v - mean) * (v - mean)
+		}
+		stdDev = math.Sqrt(sq / float64(len(sorted)-1))
+	}
+
+	return entity.MetricSummary{
+		Min:    sorted[0],
+		Max:    sorted[len(sorted)-1],
+		Mean:   mean,
+		Median: Percentile(sorted, 50),
+		P95:    Percentile(sorted, 95),
+		P99:    Percentile(sorted, 99),
+		StdDev: stdDev,
+	}
+}
+
+// Percentile returns the p-th percentile of an ascending sorted slice using linear interpolation
+func Percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower == upper {
+		return sorted[lower]
+	}
+
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
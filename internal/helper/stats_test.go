@@ -0,0 +1,73 @@
+package helper_test
+
+import (
+	"testing"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/helper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarize(t *testing.T) {
+	testcases := []struct {
+		name   string
+		values []float64
+		want   entity.MetricSummary
+	}{
+		{
+			name:   "Empty",
+			values: nil,
+			want:   entity.MetricSummary{},
+		},
+		{
+			name:   "Single Value",
+			values: []float64{42},
+			want:   entity.MetricSummary{Min: 42, Max: 42, Mean: 42, Median: 42, P95: 42, P99: 42, StdDev: 0},
+		},
+		{
+			name:   "Unsorted Values",
+			values: []float64{40, 10, 30, 20},
+			want:   entity.MetricSummary{Min: 10, Max: 40, Mean: 25, Median: 25, P95: 38.5, P99: 39.7, StdDev: 12.909944487358056},
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			got := helper.Summarize(tt.values)
+			assert.Equal(t, tt.want.Min, got.Min)
+			assert.Equal(t, tt.want.Max, got.Max)
+			assert.InDelta(t, tt.want.Mean, got.Mean, 1e-9)
+			assert.InDelta(t, tt.want.Median, got.Median, 1e-9)
+			assert.InDelta(t, tt.want.P95, got.P95, 1e-9)
+			assert.InDelta(t, tt.want.P99, got.P99, 1e-9)
+			assert.InDelta(t, tt.want.StdDev, got.StdDev, 1e-9)
+		})
+	}
+}
+
+func TestSummarizeDoesNotMutateInput(t *testing.T) {
+	values := []float64{3, 1, 2}
+	helper.Summarize(values)
+	assert.Equal(t, []float64{3, 1, 2}, values)
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	testcases := []struct {
+		name string
+		p    float64
+		want float64
+	}{
+		{name: "P0", p: 0, want: 1},
+		{name: "P50", p: 50, want: 5.5},
+		{name: "P95", p: 95, want: 9.55},
+		{name: "P100", p: 100, want: 10},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, helper.Percentile(sorted, tt.p), 1e-9)
+		})
+	}
+}
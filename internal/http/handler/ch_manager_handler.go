@@ -5,6 +5,7 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/helper"
 	"github.com/rahmatrdn/go-ch-manager/internal/parser"
 	"github.com/rahmatrdn/go-ch-manager/internal/presenter/json"
 	"github.com/rahmatrdn/go-ch-manager/internal/usecase"
@@ -32,10 +33,24 @@ func (h *ConnectionHandler) Register(api fiber.Router) {
 	connections.Get("/:id/status", h.GetConnectionStatus)
 	connections.Get("/:id/tables", h.GetConnectionTables)
 	connections.Get("/:id/tables/:table/schema", h.GetTableSchema)
+	connections.Post("/compare-query", h.CompareConnections)
 	connections.Post("/:id/compare-query", h.CompareQueries)
 	connections.Post("/:id/compare-query", h.CompareQueries)
 	connections.Get("/:id/history", h.GetConnectionHistory)
+	connections.Put("/:id/history/:history_id/pin", h.PinHistory)
 	connections.Post("/:id/query", h.HandleExecuteQuery)
+	connections.Post("/:id/query/stream", h.HandleStreamQuery)
+	connections.Post("/:id/query/parameters", h.DetectQueryParameters)
+	connections.Post("/:id/query/format", h.FormatQuery)
+	connections.Post("/:id/query/validate", h.ValidateQuery)
+	connections.Post("/:id/script", h.HandleExecuteScript)
+	connections.Post("/:id/export", h.HandleExportQuery)
+	connections.Get("/:id/queries/:query_id/page", h.FetchQueryPage)
+	connections.Delete("/:id/queries/:query_id/cursor", h.CloseQueryCursor)
+	connections.Delete("/:id/queries/:query_id", h.CancelQuery)
+	connections.Post("/:id/sessions", h.CreateSession)
+	connections.Get("/:id/sessions/:session_id", h.GetSession)
+	connections.Delete("/:id/sessions/:session_id", h.CloseSession)
 }
 
 func (h *ConnectionHandler) CreateConnection(c *fiber.Ctx) error {
@@ -113,9 +128,18 @@ func (h *ConnectionHandler) GetTableSchema(c *fiber.Ctx) error {
 	return h.presenter.BuildSuccess(c, schema, "Schema Retrieved", 200)
 }
 
+// CompareRequest accepts either a list of labelled variants or the legacy Query1/Query2 pair.
+// Variants without a query use Query, which allows comparing one query under different settings.
 type CompareRequest struct {
-	Query1 string `json:"query1"`
-	Query2 string `json:"query2"`
+	Query            string                `json:"query"`
+	Query1           string                `json:"query1"`
+	Query2           string                `json:"query2"`
+	Variants         []entity.QueryVariant `json:"variants"`
+	Iterations       int                   `json:"iterations"`
+	Warmup           int                   `json:"warmup"`
+	ResultCheck      string                `json:"result_check"`
+	CacheMode        string                `json:"cache_mode"`
+	ConfirmCacheDrop bool                  `json:"confirm_cache_drop"`
 }
 
 func (h *ConnectionHandler) CompareQueries(c *fiber.Ctx) error {
@@ -125,7 +149,57 @@ func (h *ConnectionHandler) CompareQueries(c *fiber.Ctx) error {
 		return h.presenter.BuildError(c, err)
 	}
 
-	result, err := h.usecase.CompareQueries(c.Context(), id, req.Query1, req.Query2)
+	variants := req.Variants
+	if len(variants) == 0 {
+		variants = []entity.QueryVariant{
+			{Label: "Query 1", Query: req.Query1},
+			{Label: "Query 2", Query: req.Query2},
+		}
+	}
+	for i := range variants {
+		if variants[i].Query == "" {
+			variants[i].Query = req.Query
+		}
+	}
+
+	result, err := h.usecase.CompareQueries(c.Context(), id, variants, entity.CompareOptions{
+		Iterations:       req.Iterations,
+		Warmup:           req.Warmup,
+		ResultCheck:      req.ResultCheck,
+		CacheMode:        req.CacheMode,
+		ConfirmCacheDrop: req.ConfirmCacheDrop,
+	})
+	if err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+
+	return h.presenter.BuildSuccess(c, result, "Comparison Completed", 200)
+}
+
+type CompareConnectionsRequest struct {
+	Connection1ID    int64  `json:"connection1_id"`
+	Connection2ID    int64  `json:"connection2_id"`
+	Query            string `json:"query"`
+	Iterations       int    `json:"iterations"`
+	Warmup           int    `json:"warmup"`
+	ResultCheck      string `json:"result_check"`
+	CacheMode        string `json:"cache_mode"`
+	ConfirmCacheDrop bool   `json:"confirm_cache_drop"`
+}
+
+func (h *ConnectionHandler) CompareConnections(c *fiber.Ctx) error {
+	var req CompareConnectionsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+
+	result, err := h.usecase.CompareConnections(c.Context(), req.Connection1ID, req.Connection2ID, req.Query, entity.CompareOptions{
+		Iterations:       req.Iterations,
+		Warmup:           req.Warmup,
+		ResultCheck:      req.ResultCheck,
+		CacheMode:        req.CacheMode,
+		ConfirmCacheDrop: req.ConfirmCacheDrop,
+	})
 	if err != nil {
 		return h.presenter.BuildError(c, err)
 	}
@@ -134,9 +208,28 @@ func (h *ConnectionHandler) CompareQueries(c *fiber.Ctx) error {
 }
 
 type ExecuteQueryRequest struct {
+	Query    string `json:"query"`
+	QueryID  string `json:"query_id"`  // Optional, generated by the client so it can cancel the query
+	PageSize int    `json:"page_size"` // Optional, returns the first page and a cursor for the rest
+	Timezone string `json:"timezone"`  // Optional, IANA name date times are rendered in
+	// Parameters bind the {name:Type} placeholders of Query
+	Parameters entity.QueryParameters `json:"parameters"`
+	SessionID  string                 `json:"session_id"` // Optional, runs the query in a console session
+	// Settings are ClickHouse settings of this execution only, e.g. max_execution_time or readonly
+	Settings entity.QuerySettings `json:"settings"`
+	// Confirm is the typed confirmation of a statement the guard asks to confirm
+	Confirm string `json:"confirm"`
+}
+
+type DetectParametersRequest struct {
 	Query string `json:"query"`
 }
 
+type ExecuteScriptRequest struct {
+	Script string `json:"script"`
+	entity.ScriptOptions
+}
+
 func (h *ConnectionHandler) HandleExecuteQuery(c *fiber.Ctx) error {
 	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
 	var req ExecuteQueryRequest
@@ -144,7 +237,19 @@ func (h *ConnectionHandler) HandleExecuteQuery(c *fiber.Ctx) error {
 		return h.presenter.BuildError(c, err)
 	}
 
-	result, err := h.usecase.ExecuteQuery(c.Context(), id, req.Query)
+	// Closing the tab or aborting the request cancels the context, which kills the query on the server
+	ctx, stop := helper.WatchDisconnect(userContext(c.Context(), c), c.Context().Conn())
+	defer stop()
+
+	result, err := h.usecase.ExecuteQuery(ctx, id, req.Query, entity.QueryOptions{
+		QueryID:    req.QueryID,
+		PageSize:   req.PageSize,
+		Timezone:   req.Timezone,
+		Parameters: req.Parameters,
+		SessionID:  req.SessionID,
+		Settings:   req.Settings,
+		Confirm:    req.Confirm,
+	})
 	if err != nil {
 		return h.presenter.BuildError(c, err)
 	}
@@ -152,11 +257,134 @@ func (h *ConnectionHandler) HandleExecuteQuery(c *fiber.Ctx) error {
 	return h.presenter.BuildSuccess(c, result, "Query Executed", 200)
 }
 
+// HandleExecuteScript runs the statements of a script in order and returns a result block per statement
+func (h *ConnectionHandler) HandleExecuteScript(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	var req ExecuteScriptRequest
+	if err := c.BodyParser(&req); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+
+	// Aborting the request kills the running statement and skips the rest
+	ctx, stop := helper.WatchDisconnect(userContext(c.Context(), c), c.Context().Conn())
+	defer stop()
+
+	result, err := h.usecase.ExecuteScript(ctx, id, req.Script, req.ScriptOptions)
+	if err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+
+	return h.presenter.BuildSuccess(c, result, "Script Executed", 200)
+}
+
+// DetectQueryParameters lists the {name:Type} placeholders of a query so the console can ask for their values
+func (h *ConnectionHandler) DetectQueryParameters(c *fiber.Ctx) error {
+	var req DetectParametersRequest
+	if err := c.BodyParser(&req); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+
+	params := usecase.DetectQueryParameters(req.Query)
+	if params == nil {
+		params = []entity.QueryParameter{}
+	}
+
+	return h.presenter.BuildSuccess(c, params, "Parameters Detected", 200)
+}
+
+// FormatQuery formats the query on the server without running it, statements that do not parse come back as
+// errors with their line and column
+func (h *ConnectionHandler) FormatQuery(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	var req entity.FormatRequest
+	if err := c.BodyParser(&req); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+
+	result, err := h.usecase.FormatQuery(c.Context(), id, req)
+	if err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+
+	return h.presenter.BuildSuccess(c, result, "Query Formatted", 200)
+}
+
+// ValidateQuery checks the query with EXPLAIN AST or EXPLAIN SYNTAX without running it. An invalid query still
+// answers 200, the result then lists the errors.
+func (h *ConnectionHandler) ValidateQuery(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	var req entity.ValidateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+
+	result, err := h.usecase.ValidateQuery(c.Context(), id, req)
+	if err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+
+	return h.presenter.BuildSuccess(c, result, "Query Validated", 200)
+}
+
+func (h *ConnectionHandler) FetchQueryPage(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+
+	result, err := h.usecase.FetchQueryPage(c.Context(), id, c.Params("query_id"), pageSize)
+	if err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+
+	return h.presenter.BuildSuccess(c, result, "Page Retrieved", 200)
+}
+
+func (h *ConnectionHandler) CloseQueryCursor(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err := h.usecase.CloseQueryCursor(c.Context(), id, c.Params("query_id")); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+
+	return h.presenter.BuildSuccess(c, nil, "Cursor Closed", 200)
+}
+
+func (h *ConnectionHandler) CancelQuery(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err := h.usecase.CancelQuery(c.Context(), id, c.Params("query_id")); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+
+	return h.presenter.BuildSuccess(c, nil, "Query Cancelled", 200)
+}
+
+// GetConnectionHistory lists the console history, filtered by ?q= (full text), ?pinned=, ?failed=, ?user= and ?limit=
 func (h *ConnectionHandler) GetConnectionHistory(c *fiber.Ctx) error {
 	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
-	history, err := h.usecase.GetQueryHistory(c.Context(), id)
+	var filter entity.HistoryFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+
+	history, err := h.usecase.GetQueryHistory(c.Context(), id, filter)
 	if err != nil {
 		return h.presenter.BuildError(c, err)
 	}
 	return h.presenter.BuildSuccess(c, history, "History Retrieved", 200)
 }
+
+type PinHistoryRequest struct {
+	Pinned bool `json:"pinned"`
+}
+
+func (h *ConnectionHandler) PinHistory(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	historyID, _ := strconv.ParseInt(c.Params("history_id"), 10, 64)
+	var req PinHistoryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+
+	if err := h.usecase.PinQueryHistory(c.Context(), id, historyID, req.Pinned); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+	return h.presenter.BuildSuccess(c, req, "History Updated", 200)
+}
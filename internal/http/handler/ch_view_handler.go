@@ -251,19 +251,32 @@ func (h *ViewHandler) ComparePage(c *fiber.Ctx) error {
 		// handle error or redirect
 	}
 
+	// The label is needed to warn before dropping caches on production
+	label := entity.LabelDevelopment
+	if conns, err := h.usecase.GetAllConnections(c.Context()); err == nil {
+		for _, target := range conns {
+			if target.ID == id {
+				label = target.Label
+				break
+			}
+		}
+	}
+
 	return h.render(c, "connections/compare", fiber.Map{
-		"ConnectionID": id,
-		"Status":       conn,
-		"ActiveMenu":   " compare",
+		"ConnectionID":    id,
+		"Status":          conn,
+		"ConnectionLabel": label,
+		"ActiveMenu":      " compare",
 	})
 }
 
 func (h *ViewHandler) SaveCompareFavorite(c *fiber.Ctx) error {
 	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
 	var input struct {
-		Title  string `json:"title"`
-		Query1 string `json:"query1"`
-		Query2 string `json:"query2"`
+		Title    string                `json:"title"`
+		Query1   string                `json:"query1"`
+		Query2   string                `json:"query2"`
+		Variants []entity.QueryVariant `json:"variants"`
 	}
 
 	if err := c.BodyParser(&input); err != nil {
@@ -275,6 +288,7 @@ func (h *ViewHandler) SaveCompareFavorite(c *fiber.Ctx) error {
 		Title:        input.Title,
 		Query1:       input.Query1,
 		Query2:       input.Query2,
+		Variants:     input.Variants,
 	}
 
 	if err := h.usecase.SaveFavoriteComparison(c.Context(), fav); err != nil {
@@ -306,10 +320,23 @@ func (h *ViewHandler) DeleteCompareFavorite(c *fiber.Ctx) error {
 func (h *ViewHandler) ConsolePage(c *fiber.Ctx) error {
 	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
 
+	// Shown next to the editor so nobody wonders why their INSERT is rejected
+	readOnly := entity.ReadOnlyOff
+	if conns, err := h.usecase.GetAllConnections(c.Context()); err == nil {
+		for _, target := range conns {
+			if target.ID == id {
+				readOnly = target.ReadOnly
+				break
+			}
+		}
+	}
+
 	return h.render(c, "connections/console", fiber.Map{
-		"ConnectionID": id,
-		"PageTitle":    "Query Console",
-		"ActiveMenu":   " console",
+		"ConnectionID":  id,
+		"PageTitle":     "Query Console",
+		"ActiveMenu":    " console",
+		"ExportFormats": entity.ExportFormats,
+		"ReadOnly":      readOnly,
 	})
 }
 
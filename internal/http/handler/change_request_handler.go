@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/presenter/json"
+	"github.com/rahmatrdn/go-ch-manager/internal/usecase"
+)
+
+type ChangeRequestHandler struct {
+	presenter            json.JsonPresenter
+	changeRequestUsecase usecase.ChangeRequestUsecase
+	connectionUsecase    *usecase.ConnectionUsecase
+}
+
+func NewChangeRequestHandler(presenter json.JsonPresenter, changeRequestUsecase usecase.ChangeRequestUsecase, connectionUsecase *usecase.ConnectionUsecase) *ChangeRequestHandler {
+	return &ChangeRequestHandler{
+		presenter:            presenter,
+		changeRequestUsecase: changeRequestUsecase,
+		connectionUsecase:    connectionUsecase,
+	}
+}
+
+func (h *ChangeRequestHandler) Register(app *fiber.App) {
+	app.Get("/connections/:id/change-requests", h.ChangeRequestsPage)
+
+	api := app.Group("/api/v1/connections/:id/change-requests")
+	api.Get("", h.GetChangeRequests)
+	api.Post("", h.CreateChangeRequest)
+	api.Get("/:request_id", h.GetChangeRequest)
+	api.Post("/:request_id/approve", h.ApproveChangeRequest)
+	api.Post("/:request_id/reject", h.RejectChangeRequest)
+	api.Post("/:request_id/cancel", h.CancelChangeRequest)
+	api.Post("/:request_id/execute", h.ExecuteChangeRequest)
+}
+
+func (h *ChangeRequestHandler) ChangeRequestsPage(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	connections, _ := h.connectionUsecase.GetAllConnections(c.Context())
+
+	return c.Render("change_requests/index", fiber.Map{
+		"ConnectionID":       id,
+		"PageTitle":          "Change Requests",
+		"ActiveMenu":         " changes",
+		"SidebarConnections": connections,
+		// Lets the page hide the review buttons of the user's own requests
+		"User": requestUser(c),
+	}, "layouts/main")
+}
+
+func (h *ChangeRequestHandler) GetChangeRequests(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	var filter entity.ChangeRequestFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+
+	requests, err := h.changeRequestUsecase.GetChangeRequests(c.Context(), id, filter)
+	if err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+	return h.presenter.BuildSuccess(c, requests, "Change Requests Retrieved", 200)
+}
+
+func (h *ChangeRequestHandler) GetChangeRequest(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	requestID, _ := strconv.ParseInt(c.Params("request_id"), 10, 64)
+
+	request, err := h.changeRequestUsecase.GetChangeRequest(c.Context(), id, requestID)
+	if err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+	return h.presenter.BuildSuccess(c, request, "Change Request Retrieved", 200)
+}
+
+func (h *ChangeRequestHandler) CreateChangeRequest(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	var request entity.ChangeRequest
+	if err := c.BodyParser(&request); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+
+	if err := h.changeRequestUsecase.CreateChangeRequest(userContext(c.Context(), c), id, &request); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+	return h.presenter.BuildSuccess(c, request, "Change Request Created", 201)
+}
+
+func (h *ChangeRequestHandler) ApproveChangeRequest(c *fiber.Ctx) error {
+	return h.review(c, h.changeRequestUsecase.ApproveChangeRequest, "Change Request Approved")
+}
+
+func (h *ChangeRequestHandler) RejectChangeRequest(c *fiber.Ctx) error {
+	return h.review(c, h.changeRequestUsecase.RejectChangeRequest, "Change Request Rejected")
+}
+
+func (h *ChangeRequestHandler) CancelChangeRequest(c *fiber.Ctx) error {
+	return h.review(c, h.changeRequestUsecase.CancelChangeRequest, "Change Request Cancelled")
+}
+
+// review runs an approval, rejection or cancellation, the comment is optional
+func (h *ChangeRequestHandler) review(c *fiber.Ctx, action func(ctx context.Context, connectionID, id int64, review entity.ChangeRequestReview) (*entity.ChangeRequest, error), message string) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	requestID, _ := strconv.ParseInt(c.Params("request_id"), 10, 64)
+	var review entity.ChangeRequestReview
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&review); err != nil {
+			return h.presenter.BuildError(c, err)
+		}
+	}
+
+	request, err := action(userContext(c.Context(), c), id, requestID, review)
+	if err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+	return h.presenter.BuildSuccess(c, request, message, 200)
+}
+
+// ExecuteChangeRequest runs an approved request and returns it with its result and execution log. A statement
+// that fails on the server still answers 200, the request then has the failed status and the error.
+func (h *ChangeRequestHandler) ExecuteChangeRequest(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	requestID, _ := strconv.ParseInt(c.Params("request_id"), 10, 64)
+
+	request, err := h.changeRequestUsecase.ExecuteChangeRequest(userContext(c.Context(), c), id, requestID)
+	if err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+	message := "Change Request Executed"
+	if request.Status == entity.ChangeRequestFailed {
+		message = "Change Request Failed"
+	}
+	return h.presenter.BuildSuccess(c, request, message, 200)
+}
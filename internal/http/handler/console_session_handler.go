@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CreateSession opens a console session whose SET, USE and temporary tables persist across runs
+func (h *ConnectionHandler) CreateSession(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	session, err := h.usecase.CreateSession(c.Context(), id)
+	if err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+
+	return h.presenter.BuildSuccess(c, session, "Session Created", 201)
+}
+
+func (h *ConnectionHandler) GetSession(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	session, err := h.usecase.GetSession(c.Context(), id, c.Params("session_id"))
+	if err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+
+	return h.presenter.BuildSuccess(c, session, "Session Retrieved", 200)
+}
+
+func (h *ConnectionHandler) CloseSession(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err := h.usecase.CloseSession(c.Context(), id, c.Params("session_id")); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+
+	return h.presenter.BuildSuccess(c, nil, "Session Closed", 200)
+}
@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	presenter "github.com/rahmatrdn/go-ch-manager/internal/presenter/json"
+	"github.com/rahmatrdn/go-ch-manager/internal/usecase"
+)
+
+// loadTestStreamInterval is how often the progress stream pushes a new snapshot
+const loadTestStreamInterval = 500 * time.Millisecond
+
+type LoadTestHandler struct {
+	presenter         presenter.JsonPresenter
+	loadTestUsecase   usecase.LoadTestUsecase
+	connectionUsecase *usecase.ConnectionUsecase
+}
+
+func NewLoadTestHandler(presenter presenter.JsonPresenter, loadTestUsecase usecase.LoadTestUsecase, connectionUsecase *usecase.ConnectionUsecase) *LoadTestHandler {
+	return &LoadTestHandler{
+		presenter:         presenter,
+		loadTestUsecase:   loadTestUsecase,
+		connectionUsecase: connectionUsecase,
+	}
+}
+
+func (h *LoadTestHandler) Register(app *fiber.App) {
+	app.Get("/connections/:id/load-test", h.LoadTestPage)
+
+	api := app.Group("/api/v1/connections/:id/load-tests")
+	api.Get("", h.GetLoadTests)
+	api.Post("", h.StartLoadTest)
+	api.Get("/:test_id", h.GetLoadTest)
+	api.Get("/:test_id/stream", h.StreamLoadTest)
+	api.Delete("/:test_id", h.CancelLoadTest)
+}
+
+func (h *LoadTestHandler) LoadTestPage(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	connections, _ := h.connectionUsecase.GetAllConnections(c.Context())
+
+	return c.Render("load_test/index", fiber.Map{
+		"ConnectionID":       id,
+		"PageTitle":          "Load Test",
+		"ActiveMenu":         " loadtest",
+		"SidebarConnections": connections,
+		"MaxConcurrency":     entity.MaxLoadTestConcurrency,
+		"MaxDuration":        entity.MaxLoadTestDurationSeconds,
+	}, "layouts/main")
+}
+
+func (h *LoadTestHandler) StartLoadTest(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	var config entity.LoadTestConfig
+	if err := c.BodyParser(&config); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+
+	report, err := h.loadTestUsecase.StartLoadTest(c.Context(), id, config)
+	if err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+	return h.presenter.BuildSuccess(c, report, "Load Test Started", 201)
+}
+
+func (h *LoadTestHandler) GetLoadTests(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	reports, err := h.loadTestUsecase.GetLoadTests(c.Context(), id)
+	if err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+	return h.presenter.BuildSuccess(c, reports, "Load Tests Retrieved", 200)
+}
+
+func (h *LoadTestHandler) GetLoadTest(c *fiber.Ctx) error {
+	report, err := h.loadTestUsecase.GetLoadTest(c.Context(), c.Params("test_id"))
+	if err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+	if report == nil {
+		return h.presenter.BuildError(c, fmt.Errorf("load test not found"))
+	}
+	return h.presenter.BuildSuccess(c, report, "Load Test Retrieved", 200)
+}
+
+// StreamLoadTest pushes the live report as Server-Sent Events until the load test has finished
+func (h *LoadTestHandler) StreamLoadTest(c *fiber.Ctx) error {
+	testID := c.Params("test_id")
+	report, err := h.loadTestUsecase.GetLoadTest(c.Context(), testID)
+	if err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+	if report == nil {
+		return h.presenter.BuildError(c, fmt.Errorf("load test not found"))
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		ticker := time.NewTicker(loadTestStreamInterval)
+		defer ticker.Stop()
+
+		for {
+			payload, err := json.Marshal(report)
+			if err != nil {
+				return
+			}
+
+			event := "progress"
+			if report.Status != entity.LoadTestStatusRunning {
+				event = "done"
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+			// A failing flush means the client went away
+			if err := w.Flush(); err != nil || event == "done" {
+				return
+			}
+
+			<-ticker.C
+			// The request context is recycled by fasthttp once streaming starts, a fresh one is used for reads
+			report, err = h.loadTestUsecase.GetLoadTest(context.Background(), testID)
+			if err != nil || report == nil {
+				return
+			}
+		}
+	})
+
+	return nil
+}
+
+func (h *LoadTestHandler) CancelLoadTest(c *fiber.Ctx) error {
+	if err := h.loadTestUsecase.CancelLoadTest(c.Context(), c.Params("test_id")); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+	return h.presenter.BuildSuccess(c, nil, "Load Test Cancelled", 200)
+}
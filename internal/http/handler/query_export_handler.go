@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/usecase"
+)
+
+// HandleExportQuery re-runs a console query and streams the result as a file download
+func (h *ConnectionHandler) HandleExportQuery(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	var req entity.ExportRequest
+	if err := c.BodyParser(&req); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+
+	// The download outlives the handler, the query is stopped once it is written or the client goes away
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, format, err := h.usecase.ExportQuery(ctx, id, req)
+	if err != nil {
+		cancel()
+		return h.presenter.BuildError(c, err)
+	}
+
+	fileName := usecase.ExportFileName(req.FileName, format, time.Now())
+	c.Set("Content-Type", format.ContentType)
+	c.Set("Content-Disposition", contentDisposition(fileName))
+	c.Set("Cache-Control", "no-cache")
+
+	conn := c.Context().Conn()
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+		defer stream.Close()
+
+		if _, err := io.Copy(w, stream); err != nil {
+			// The status is long sent, closing the connection before the last chunk is the only way to
+			// tell the client the file is incomplete
+			log.Printf("Export of connection %d failed after the download started: %v", id, err)
+			_ = conn.Close()
+			return
+		}
+		_ = w.Flush()
+	})
+
+	return nil
+}
+
+// contentDisposition names the download, with an ASCII fallback for clients without RFC 5987 support
+func contentDisposition(fileName string) string {
+	fallback := strings.Map(func(r rune) rune {
+		if r > 0x7e || r < 0x20 || r == '"' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, fileName)
+
+	return `attachment; filename="` + fallback + `"; filename*=UTF-8''` + url.PathEscape(fileName)
+}
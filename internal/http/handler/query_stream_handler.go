@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/usecase"
+)
+
+const (
+	// Streamed rows are flushed to the client in small batches or at least this often
+	streamFlushRows     = 200
+	streamFlushInterval = 200 * time.Millisecond
+)
+
+// HandleStreamQuery executes a console query and streams its result as newline delimited JSON:
+// a meta line with the columns, one line per row and an end line with the stats, or an error line
+func (h *ConnectionHandler) HandleStreamQuery(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	var req ExecuteQueryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+
+	user := requestUser(c)
+
+	c.Set("Content-Type", "application/x-ndjson")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		// The stream outlives the handler, a failing write means the client went away and cancels the query
+		ctx, cancel := context.WithCancel(usecase.WithUser(context.Background(), user))
+		defer cancel()
+
+		encoder := json.NewEncoder(w)
+		pending, lastFlush := 0, time.Now()
+		emit := func(event entity.QueryStreamEvent) error {
+			if err := encoder.Encode(event); err != nil {
+				return err
+			}
+			pending++
+			if event.Type != entity.QueryStreamEventRow || pending >= streamFlushRows || time.Since(lastFlush) >= streamFlushInterval {
+				pending, lastFlush = 0, time.Now()
+				return w.Flush()
+			}
+			return nil
+		}
+
+		err := h.usecase.StreamQuery(ctx, id, req.Query, entity.QueryOptions{
+			QueryID:    req.QueryID,
+			Timezone:   req.Timezone,
+			Parameters: req.Parameters,
+			SessionID:  req.SessionID,
+			Settings:   req.Settings,
+			Confirm:    req.Confirm,
+		}, emit)
+		if err != nil {
+			event := entity.QueryStreamEvent{Type: entity.QueryStreamEventError, Error: err.Error()}
+			var guardErr *entity.StatementGuardError
+			if errors.As(err, &guardErr) {
+				event.ErrorCode = guardErr.Code()
+			}
+			_ = encoder.Encode(event)
+			_ = w.Flush()
+		}
+	})
+
+	return nil
+}
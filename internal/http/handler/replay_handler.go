@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/presenter/json"
+	"github.com/rahmatrdn/go-ch-manager/internal/usecase"
+)
+
+type ReplayHandler struct {
+	presenter         json.JsonPresenter
+	replayUsecase     usecase.ReplayUsecase
+	connectionUsecase *usecase.ConnectionUsecase
+}
+
+func NewReplayHandler(presenter json.JsonPresenter, replayUsecase usecase.ReplayUsecase, connectionUsecase *usecase.ConnectionUsecase) *ReplayHandler {
+	return &ReplayHandler{
+		presenter:         presenter,
+		replayUsecase:     replayUsecase,
+		connectionUsecase: connectionUsecase,
+	}
+}
+
+func (h *ReplayHandler) Register(app *fiber.App) {
+	app.Get("/connections/:id/replay", h.ReplayPage)
+
+	api := app.Group("/api/v1/connections/:id/replays")
+	api.Get("", h.GetReplays)
+	api.Post("", h.StartReplay)
+	api.Get("/:replay_id", h.GetReplay)
+	api.Post("/:replay_id/cancel", h.CancelReplay)
+	api.Delete("/:replay_id", h.DeleteReplay)
+}
+
+func (h *ReplayHandler) ReplayPage(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	connections, _ := h.connectionUsecase.GetAllConnections(c.Context())
+
+	return c.Render("replay/index", fiber.Map{
+		"ConnectionID":       id,
+		"PageTitle":          "Query Replay",
+		"ActiveMenu":         " replay",
+		"SidebarConnections": connections,
+		"Connections":        connections,
+	}, "layouts/main")
+}
+
+type RunReplayRequest struct {
+	TargetConnectionID     int64   `json:"target_connection_id"`
+	WindowHours            int     `json:"window_hours"`
+	MaxFingerprints        int     `json:"max_fingerprints"`
+	SamplesPerFingerprint  int     `json:"samples_per_fingerprint"`
+	CompareResults         bool    `json:"compare_results"`
+	RegressionThresholdPct float64 `json:"regression_threshold_pct"`
+}
+
+// StartReplay starts the replay in the background and returns the running replay, poll GetReplay for its progress
+func (h *ReplayHandler) StartReplay(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	var req RunReplayRequest
+	if err := c.BodyParser(&req); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+
+	run, err := h.replayUsecase.StartReplay(c.Context(), &entity.ReplayRun{
+		SourceConnectionID:     id,
+		TargetConnectionID:     req.TargetConnectionID,
+		WindowHours:            req.WindowHours,
+		MaxFingerprints:        req.MaxFingerprints,
+		SamplesPerFingerprint:  req.SamplesPerFingerprint,
+		CompareResults:         req.CompareResults,
+		RegressionThresholdPct: req.RegressionThresholdPct,
+	})
+	if err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+	return h.presenter.BuildSuccess(c, run, "Replay Started", 200)
+}
+
+func (h *ReplayHandler) GetReplays(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	runs, err := h.replayUsecase.GetReplays(c.Context(), id)
+	if err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+	return h.presenter.BuildSuccess(c, runs, "Replays Retrieved", 200)
+}
+
+func (h *ReplayHandler) GetReplay(c *fiber.Ctx) error {
+	replayID, _ := strconv.ParseInt(c.Params("replay_id"), 10, 64)
+	run, err := h.replayUsecase.GetReplay(c.Context(), replayID)
+	if err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+	return h.presenter.BuildSuccess(c, run, "Replay Retrieved", 200)
+}
+
+func (h *ReplayHandler) CancelReplay(c *fiber.Ctx) error {
+	replayID, _ := strconv.ParseInt(c.Params("replay_id"), 10, 64)
+	if err := h.replayUsecase.CancelReplay(c.Context(), replayID); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+	return h.presenter.BuildSuccess(c, nil, "Replay Cancelled", 200)
+}
+
+func (h *ReplayHandler) DeleteReplay(c *fiber.Ctx) error {
+	replayID, _ := strconv.ParseInt(c.Params("replay_id"), 10, 64)
+	if err := h.replayUsecase.DeleteReplay(c.Context(), replayID); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+	return h.presenter.BuildSuccess(c, nil, "Replay Deleted", 200)
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/presenter/json"
+	"github.com/rahmatrdn/go-ch-manager/internal/usecase"
+)
+
+type SavedQueryHandler struct {
+	presenter         json.JsonPresenter
+	savedQueryUsecase usecase.SavedQueryUsecase
+	connectionUsecase *usecase.ConnectionUsecase
+}
+
+func NewSavedQueryHandler(presenter json.JsonPresenter, savedQueryUsecase usecase.SavedQueryUsecase, connectionUsecase *usecase.ConnectionUsecase) *SavedQueryHandler {
+	return &SavedQueryHandler{
+		presenter:         presenter,
+		savedQueryUsecase: savedQueryUsecase,
+		connectionUsecase: connectionUsecase,
+	}
+}
+
+func (h *SavedQueryHandler) Register(app *fiber.App) {
+	app.Get("/connections/:id/saved-queries", h.SavedQueriesPage)
+
+	api := app.Group("/api/v1/connections/:id/saved-queries")
+	api.Get("", h.GetSavedQueries)
+	api.Post("", h.CreateSavedQuery)
+	api.Get("/export", h.ExportSavedQueries)
+	api.Post("/import", h.ImportSavedQueries)
+	api.Get("/:query_id", h.GetSavedQuery)
+	api.Put("/:query_id", h.UpdateSavedQuery)
+	api.Delete("/:query_id", h.DeleteSavedQuery)
+}
+
+func (h *SavedQueryHandler) SavedQueriesPage(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	connections, _ := h.connectionUsecase.GetAllConnections(c.Context())
+
+	return c.Render("saved_queries/index", fiber.Map{
+		"ConnectionID":       id,
+		"PageTitle":          "Saved Queries",
+		"ActiveMenu":         " saved",
+		"SidebarConnections": connections,
+	}, "layouts/main")
+}
+
+func (h *SavedQueryHandler) GetSavedQueries(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	var filter entity.SavedQueryFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+
+	queries, err := h.savedQueryUsecase.GetSavedQueries(c.Context(), id, filter)
+	if err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+	return h.presenter.BuildSuccess(c, queries, "Saved Queries Retrieved", 200)
+}
+
+func (h *SavedQueryHandler) GetSavedQuery(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	queryID, _ := strconv.ParseInt(c.Params("query_id"), 10, 64)
+
+	query, err := h.savedQueryUsecase.GetSavedQuery(c.Context(), id, queryID)
+	if err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+	return h.presenter.BuildSuccess(c, query, "Saved Query Retrieved", 200)
+}
+
+func (h *SavedQueryHandler) CreateSavedQuery(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	var query entity.SavedQuery
+	if err := c.BodyParser(&query); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+
+	if err := h.savedQueryUsecase.CreateSavedQuery(userContext(c.Context(), c), id, &query); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+	return h.presenter.BuildSuccess(c, query, "Saved Query Created", 201)
+}
+
+func (h *SavedQueryHandler) UpdateSavedQuery(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	queryID, _ := strconv.ParseInt(c.Params("query_id"), 10, 64)
+	var query entity.SavedQuery
+	if err := c.BodyParser(&query); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+
+	if err := h.savedQueryUsecase.UpdateSavedQuery(userContext(c.Context(), c), id, queryID, &query); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+	return h.presenter.BuildSuccess(c, query, "Saved Query Updated", 200)
+}
+
+func (h *SavedQueryHandler) DeleteSavedQuery(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	queryID, _ := strconv.ParseInt(c.Params("query_id"), 10, 64)
+
+	if err := h.savedQueryUsecase.DeleteSavedQuery(c.Context(), id, queryID); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+	return h.presenter.BuildSuccess(c, nil, "Saved Query Deleted", 200)
+}
+
+// ExportSavedQueries downloads the queries matching the filter as a .sql bundle
+func (h *SavedQueryHandler) ExportSavedQueries(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	var filter entity.SavedQueryFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+
+	bundle, err := h.savedQueryUsecase.ExportSavedQueries(c.Context(), id, filter)
+	if err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+
+	c.Set("Content-Type", "application/sql; charset=utf-8")
+	c.Set("Content-Disposition", contentDisposition("saved_queries_"+time.Now().Format("20060102_150405")+".sql"))
+	return c.SendString(bundle)
+}
+
+// ImportSavedQueries reads a .sql bundle uploaded as the "file" form field, or sent as the request body
+func (h *SavedQueryHandler) ImportSavedQueries(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+
+	bundle := string(c.Body())
+	if header, err := c.FormFile("file"); err == nil {
+		file, err := header.Open()
+		if err != nil {
+			return h.presenter.BuildError(c, err)
+		}
+		defer file.Close()
+
+		content, err := io.ReadAll(file)
+		if err != nil {
+			return h.presenter.BuildError(c, err)
+		}
+		bundle = string(content)
+	}
+
+	result, err := h.savedQueryUsecase.ImportSavedQueries(userContext(c.Context(), c), id, bundle)
+	if err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+	return h.presenter.BuildSuccess(c, result, "Saved Queries Imported", 200)
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/presenter/json"
+	"github.com/rahmatrdn/go-ch-manager/internal/usecase"
+)
+
+type SuiteHandler struct {
+	presenter         json.JsonPresenter
+	suiteUsecase      usecase.SuiteUsecase
+	connectionUsecase *usecase.ConnectionUsecase
+}
+
+func NewSuiteHandler(presenter json.JsonPresenter, suiteUsecase usecase.SuiteUsecase, connectionUsecase *usecase.ConnectionUsecase) *SuiteHandler {
+	return &SuiteHandler{
+		presenter:         presenter,
+		suiteUsecase:      suiteUsecase,
+		connectionUsecase: connectionUsecase,
+	}
+}
+
+func (h *SuiteHandler) Register(app *fiber.App) {
+	app.Get("/connections/:id/suites", h.SuitesPage)
+
+	api := app.Group("/api/v1/connections/:id/suites")
+	api.Get("", h.GetSuites)
+	api.Post("", h.CreateSuite)
+	api.Put("/:suite_id", h.UpdateSuite)
+	api.Delete("/:suite_id", h.DeleteSuite)
+	api.Post("/:suite_id/run", h.RunSuite)
+	api.Get("/:suite_id/runs", h.GetRuns)
+	api.Get("/:suite_id/runs/:run_id", h.GetRun)
+	api.Post("/:suite_id/runs/:run_id/baseline", h.SetBaseline)
+}
+
+func (h *SuiteHandler) SuitesPage(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	connections, _ := h.connectionUsecase.GetAllConnections(c.Context())
+
+	return c.Render("suites/index", fiber.Map{
+		"ConnectionID":       id,
+		"PageTitle":          "Comparison Suites",
+		"ActiveMenu":         " suites",
+		"SidebarConnections": connections,
+	}, "layouts/main")
+}
+
+func (h *SuiteHandler) GetSuites(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	suites, err := h.suiteUsecase.GetSuites(c.Context(), id)
+	if err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+	return h.presenter.BuildSuccess(c, suites, "Suites Retrieved", 200)
+}
+
+func (h *SuiteHandler) CreateSuite(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	var suite entity.ComparisonSuite
+	if err := c.BodyParser(&suite); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+
+	suite.ConnectionID = id
+	if err := h.suiteUsecase.CreateSuite(c.Context(), &suite); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+	return h.presenter.BuildSuccess(c, suite, "Suite Created", 201)
+}
+
+func (h *SuiteHandler) UpdateSuite(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	suiteID, _ := strconv.ParseInt(c.Params("suite_id"), 10, 64)
+	var suite entity.ComparisonSuite
+	if err := c.BodyParser(&suite); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+
+	if err := h.suiteUsecase.UpdateSuite(c.Context(), id, suiteID, &suite); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+	return h.presenter.BuildSuccess(c, suite, "Suite Updated", 200)
+}
+
+func (h *SuiteHandler) DeleteSuite(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	suiteID, _ := strconv.ParseInt(c.Params("suite_id"), 10, 64)
+	if err := h.suiteUsecase.DeleteSuite(c.Context(), id, suiteID); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+	return h.presenter.BuildSuccess(c, nil, "Suite Deleted", 200)
+}
+
+// RunSuite starts a run in the background, the page polls GetRun until it finishes
+func (h *SuiteHandler) RunSuite(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	suiteID, _ := strconv.ParseInt(c.Params("suite_id"), 10, 64)
+	run, err := h.suiteUsecase.StartSuite(c.Context(), id, suiteID)
+	if err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+	return h.presenter.BuildSuccess(c, run, "Suite Run Started", 200)
+}
+
+func (h *SuiteHandler) GetRuns(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	suiteID, _ := strconv.ParseInt(c.Params("suite_id"), 10, 64)
+	runs, err := h.suiteUsecase.GetRuns(c.Context(), id, suiteID)
+	if err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+	return h.presenter.BuildSuccess(c, runs, "Suite Runs Retrieved", 200)
+}
+
+func (h *SuiteHandler) GetRun(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	suiteID, _ := strconv.ParseInt(c.Params("suite_id"), 10, 64)
+	runID, _ := strconv.ParseInt(c.Params("run_id"), 10, 64)
+	run, err := h.suiteUsecase.GetRun(c.Context(), id, suiteID, runID)
+	if err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+	return h.presenter.BuildSuccess(c, run, "Suite Run Retrieved", 200)
+}
+
+func (h *SuiteHandler) SetBaseline(c *fiber.Ctx) error {
+	id, _ := strconv.ParseInt(c.Params("id"), 10, 64)
+	suiteID, _ := strconv.ParseInt(c.Params("suite_id"), 10, 64)
+	runID, _ := strconv.ParseInt(c.Params("run_id"), 10, 64)
+	if err := h.suiteUsecase.SetBaseline(c.Context(), id, suiteID, runID); err != nil {
+		return h.presenter.BuildError(c, err)
+	}
+	return h.presenter.BuildSuccess(c, nil, "Baseline Updated", 200)
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rahmatrdn/go-ch-manager/internal/usecase"
+)
+
+const (
+	// userHeader names the signed in user, set by the authenticating proxy in front of the manager
+	userHeader = "X-Forwarded-User"
+	// proxySecretHeader carries the secret shared with the authenticating proxy
+	proxySecretHeader = "X-Proxy-Secret"
+	// userLocal is where UserIdentity.Middleware keeps the trusted user of the request
+	userLocal = "user"
+)
+
+// UserIdentity trusts the user header only on requests of the authenticating proxy: those from an
+// allow-listed address or carrying the shared secret. Anyone else could name any user, so without a
+// trusted proxy every request is anonymous, and anonymous users can't create or review change requests.
+type UserIdentity struct {
+	proxies []*net.IPNet
+	secret  string
+}
+
+// NewUserIdentity takes the addresses of the trusted proxies as IPs or CIDR ranges, and their shared secret
+func NewUserIdentity(proxies []string, secret string) (*UserIdentity, error) {
+	identity := &UserIdentity{secret: strings.TrimSpace(secret)}
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("trusted proxy %q is not an IP or CIDR range", proxy)
+			}
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			identity.proxies = append(identity.proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q is not an IP or CIDR range", proxy)
+		}
+		identity.proxies = append(identity.proxies, network)
+	}
+	return identity, nil
+}
+
+// Configured reports whether any proxy is trusted, without one no request has a user
+func (u *UserIdentity) Configured() bool {
+	return len(u.proxies) > 0 || u.secret != ""
+}
+
+// Middleware records the user of requests from a trusted proxy, see requestUser
+func (u *UserIdentity) Middleware(c *fiber.Ctx) error {
+	if u.trusted(c) {
+		c.Locals(userLocal, strings.TrimSpace(c.Get(userHeader)))
+	}
+	return c.Next()
+}
+
+// trusted reports whether the request comes from the authenticating proxy
+func (u *UserIdentity) trusted(c *fiber.Ctx) bool {
+	if u.secret != "" && subtle.ConstantTimeCompare([]byte(c.Get(proxySecretHeader)), []byte(u.secret)) == 1 {
+		return true
+	}
+	ip := c.Context().RemoteIP()
+	for _, proxy := range u.proxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestUser returns the user named by a trusted proxy, empty for anonymous requests
+func requestUser(c *fiber.Ctx) string {
+	user, _ := c.Locals(userLocal).(string)
+	return user
+}
+
+// userContext records the user of the request in ctx
+func userContext(ctx context.Context, c *fiber.Ctx) context.Context {
+	return usecase.WithUser(ctx, requestUser(c))
+}
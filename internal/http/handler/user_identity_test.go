@@ -0,0 +1,79 @@
+package handler_test
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rahmatrdn/go-ch-manager/internal/http/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserIdentity(t *testing.T) {
+	testcases := []struct {
+		name    string
+		proxies []string
+		secret  string
+		headers map[string]string
+		want    string
+	}{
+		{
+			name:    "No Trusted Proxy",
+			headers: map[string]string{"X-Forwarded-User": "alice"},
+			want:    "",
+		},
+		{
+			name:    "Trusted Address",
+			proxies: []string{"10.0.0.0/8", "0.0.0.0"},
+			headers: map[string]string{"X-Forwarded-User": " alice "},
+			want:    "alice",
+		},
+		{
+			name:    "Untrusted Address",
+			proxies: []string{"10.0.0.0/8"},
+			headers: map[string]string{"X-Forwarded-User": "alice"},
+			want:    "",
+		},
+		{
+			name:    "Shared Secret",
+			secret:  "s3cret",
+			headers: map[string]string{"X-Forwarded-User": "alice", "X-Proxy-Secret": "s3cret"},
+			want:    "alice",
+		},
+		{
+			name:    "Wrong Secret",
+			secret:  "s3cret",
+			headers: map[string]string{"X-Forwarded-User": "alice", "X-Proxy-Secret": "guess"},
+			want:    "",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			identity, err := handler.NewUserIdentity(tc.proxies, tc.secret)
+			assert.NoError(t, err)
+
+			app := fiber.New()
+			app.Use(identity.Middleware)
+			app.Get("/", func(c *fiber.Ctx) error {
+				user, _ := c.Locals("user").(string)
+				return c.SendString(user)
+			})
+
+			req := httptest.NewRequest("GET", "/", nil)
+			for name, value := range tc.headers {
+				req.Header.Set(name, value)
+			}
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			body, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tc.want, string(body))
+		})
+	}
+}
+
+func TestNewUserIdentityInvalidProxy(t *testing.T) {
+	_, err := handler.NewUserIdentity([]string{"proxy.local"}, "")
+	assert.Error(t, err)
+}
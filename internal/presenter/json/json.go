@@ -42,6 +42,16 @@ func (p *Json) BuildSuccess(c *fiber.Ctx, data interface{}, message string, code
 }
 
 func (p *Json) BuildError(c *fiber.Ctx, err error) error {
+	// Statements stopped by the guard keep their code, so clients can ask for the confirmation or a change request
+	var guardErr *entity.StatementGuardError
+	if errors.As(err, &guardErr) {
+		httpCode := http.StatusPreconditionRequired
+		if guardErr.Action == entity.GuardActionBlock || guardErr.Action == entity.GuardActionApprove {
+			httpCode = http.StatusForbidden
+		}
+		return c.Status(httpCode).JSON(apperr.CustomError(err.Error(), guardErr.Code(), httpCode))
+	}
+
 	unwrappedErr := errors.Unwrap(err)
 
 	if unwrappedErr != nil {
@@ -3,8 +3,14 @@ package clickhouse
 import (
 	"context"
 	"crypto/tls"
+	"database/sql"
+	"errors"
 	"fmt"
-	"reflect"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,6 +18,7 @@ import (
 	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
 	"github.com/google/uuid"
 	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/sqlparse"
 )
 
 type ClickHouseClient interface {
@@ -20,9 +27,23 @@ type ClickHouseClient interface {
 	GetTables(ctx context.Context, conn *entity.CHConnection) ([]entity.TableMeta, error)
 	GetCreateSQL(ctx context.Context, conn *entity.CHConnection, tableName string) (string, error)
 	GetServerInfo(ctx context.Context, conn *entity.CHConnection) (string, error)
+	FormatQuery(ctx context.Context, conn *entity.CHConnection, query string, singleLine bool) (string, error)
+	ExplainQuery(ctx context.Context, conn *entity.CHConnection, kind string, query string) (string, error)
 	GetSchema(ctx context.Context, conn *entity.CHConnection, tableName string) (*entity.TableSchema, error)
 	ExecuteQueryWithStats(ctx context.Context, conn *entity.CHConnection, query string) (*entity.QueryStats, error)
 	ExecuteQueryWithResults(ctx context.Context, conn *entity.CHConnection, query string) (*entity.QueryResult, error)
+	ExecuteStatement(ctx context.Context, conn *entity.CHConnection, query string) (*entity.QueryStats, error)
+	OpenQuery(ctx context.Context, conn *entity.CHConnection, query string) (*ResultReader, error)
+	OpenExport(ctx context.Context, conn *entity.CHConnection, query string, format entity.ExportFormat) (io.ReadCloser, error)
+	GetResultHash(ctx context.Context, conn *entity.CHConnection, query string) (*entity.ResultHash, error)
+	DropCaches(ctx context.Context, conn *entity.CHConnection) error
+	KillQuery(ctx context.Context, conn *entity.CHConnection, queryID string) error
+	OpenSession(ctx context.Context, conn *entity.CHConnection, sessionID string) error
+	GetSessionState(ctx context.Context, sessionID string) (*entity.ConsoleSession, error)
+	CloseSession(sessionID string) error
+	OpenLoadRunner(conn *entity.CHConnection) (*LoadRunner, error)
+	GetLoadTestServerStats(ctx context.Context, conn *entity.CHConnection, queryIDPrefix string, since time.Time) (map[int]*entity.LoadTestServerStats, error)
+	GetQueryLogSamples(ctx context.Context, conn *entity.CHConnection, windowHours, fingerprints, samples int) ([]entity.QueryLogSample, error)
 
 	// Configuration Menu Methods
 	GetClusterConfig(ctx context.Context, conn *entity.CHConnection) (*entity.ClusterInfo, error)
@@ -36,18 +57,30 @@ type ClickHouseClient interface {
 
 type clientImpl struct {
 	conns map[string]driver.Conn
-	mu    sync.RWMutex
+	// sessions are the dedicated connections of console sessions, by session ID
+	sessions map[string]*sessionConn
+	mu       sync.RWMutex
+	// httpClient talks to the HTTP interface directly, for responses the driver cannot pass through (exports)
+	httpClient *http.Client
 }
 
 func NewClickHouseClient() ClickHouseClient {
 	return &clientImpl{
-		conns: make(map[string]driver.Conn),
+		conns:    make(map[string]driver.Conn),
+		sessions: make(map[string]*sessionConn),
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				// Same as the driver connections, self-signed certificates are accepted
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		},
 	}
 }
 
 func (c *clientImpl) getConnection(conn *entity.CHConnection) (driver.Conn, error) {
 	// Create a unique key for the connection configuration
-	key := fmt.Sprintf("%v|%s|%d|%s|%s|%v|%s", conn.ID, conn.Host, conn.Port, conn.Username, conn.Database, conn.UseSSL, conn.Protocol)
+	key := fmt.Sprintf("%v|%s|%d|%s|%s|%v|%s|%d", conn.ID, conn.Host, conn.Port, conn.Username, conn.Database, conn.UseSSL, conn.Protocol, conn.ReadOnly)
 
 	c.mu.RLock()
 	existingConn, ok := c.conns[key]
@@ -65,6 +98,34 @@ func (c *clientImpl) getConnection(conn *entity.CHConnection) (driver.Conn, erro
 		c.mu.Unlock()
 	}
 
+	newConn, err := c.openConn(connectionOptions(conn))
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.conns[key] = newConn
+	c.mu.Unlock()
+
+	return newConn, nil
+}
+
+func (c *clientImpl) openConn(options *clickhouse.Options) (driver.Conn, error) {
+	newConn, err := clickhouse.Open(options)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify new connection immediately
+	if err := newConn.Ping(context.Background()); err != nil {
+		_ = newConn.Close()
+		return nil, err
+	}
+
+	return newConn, nil
+}
+
+func connectionOptions(conn *entity.CHConnection) *clickhouse.Options {
 	addr := fmt.Sprintf("%s:%d", conn.Host, conn.Port)
 
 	options := &clickhouse.Options{
@@ -84,33 +145,26 @@ func (c *clientImpl) getConnection(conn *entity.CHConnection) (driver.Conn, erro
 			},
 		},
 		Debug: false,
+		// Leave room for a full load test next to the regular requests of the UI
+		MaxOpenConns: entity.MaxLoadTestConcurrency + 8,
 	}
 
 	if conn.Protocol == "http" {
 		options.Protocol = clickhouse.HTTP
 	}
 
+	if conn.IsReadOnly() {
+		// Sent with every query, so the server rejects writes whatever the user's profile allows
+		options.Settings = clickhouse.Settings{"readonly": conn.ReadOnly}
+	}
+
 	if conn.UseSSL {
 		options.TLS = &tls.Config{
 			InsecureSkipVerify: true, // For now, allow self-signed or just skip verify to avoid complex cert loading UI. User just wants to toggle SSL.
 		}
 	}
 
-	newConn, err := clickhouse.Open(options)
-	if err != nil {
-		return nil, err
-	}
-
-	// Verify new connection immediately
-	if err := newConn.Ping(context.Background()); err != nil {
-		return nil, err
-	}
-
-	c.mu.Lock()
-	c.conns[key] = newConn
-	c.mu.Unlock()
-
-	return newConn, nil
+	return options
 }
 
 func (c *clientImpl) Ping(ctx context.Context, conn *entity.CHConnection) error {
@@ -232,6 +286,59 @@ func (c *clientImpl) GetServerInfo(ctx context.Context, conn *entity.CHConnectio
 	return version, nil
 }
 
+// FormatQuery formats a single statement with the server's formatQuery, or formatQuerySingleLine. The statement
+// is only parsed, positions in a syntax error count from its start.
+func (c *clientImpl) FormatQuery(ctx context.Context, conn *entity.CHConnection, query string, singleLine bool) (string, error) {
+	db, err := c.getConnection(conn)
+	if err != nil {
+		return "", err
+	}
+
+	function := "formatQuery"
+	if singleLine {
+		function = "formatQuerySingleLine"
+	}
+
+	var formatted string
+	if err := db.QueryRow(ctx, "SELECT "+function+"(?)", query).Scan(&formatted); err != nil {
+		return "", err
+	}
+	return formatted, nil
+}
+
+// ExplainStatement is the statement ExplainQuery sends, positions in its syntax errors count from its start
+func ExplainStatement(kind string, query string) string {
+	return "EXPLAIN " + kind + " " + query
+}
+
+// ExplainQuery returns the output of EXPLAIN kind (AST, SYNTAX ...) for a single statement, which checks it
+// without running it
+func (c *clientImpl) ExplainQuery(ctx context.Context, conn *entity.CHConnection, kind string, query string) (string, error) {
+	db, err := c.getConnection(conn)
+	if err != nil {
+		return "", err
+	}
+
+	rows, err := db.Query(ctx, ExplainStatement(kind, query))
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return "", err
+		}
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
 func (c *clientImpl) GetSchema(ctx context.Context, conn *entity.CHConnection, tableName string) (*entity.TableSchema, error) {
 	db, err := c.getConnection(conn)
 	if err != nil {
@@ -281,10 +388,11 @@ func (c *clientImpl) ExecuteQueryWithStats(ctx context.Context, conn *entity.CHC
 
 	queryID := uuid.New().String()
 
-	// Context with QueryID
-	ctxQuery := clickhouse.Context(ctx, clickhouse.WithQueryID(queryID))
-
-	clickhouse.WithQueryID(queryID)
+	// Context with QueryID and per-query settings
+	ctxQuery, err := queryContext(ctx, conn, queryID)
+	if err != nil {
+		return nil, err
+	}
 
 	start := time.Now()
 	// Execute main query
@@ -295,21 +403,118 @@ func (c *clientImpl) ExecuteQueryWithStats(ctx context.Context, conn *entity.CHC
 	rows.Close() // Close immediately, we just want execution
 	duration := time.Since(start).Milliseconds()
 
-	// Flush logs to ensure data is written to system.query_log
-	if err := db.Exec(ctx, "SYSTEM FLUSH LOGS"); err != nil {
-		// Log warning? For now just proceed, might miss data if not flushed
+	stats, err := c.waitQueryLogStats(ctx, db, conn, queryID)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		// The row never showed up, only the client side duration is known
+		return &entity.QueryStats{ExecutionTimeMs: duration, Unlogged: true}, nil
 	}
 
-	// Fetch stats from system.query_log
-	// We wait a tiny bit? Ideally flush logs handles it.
+	return stats, nil
+}
+
+// queryLogAttempts bounds how often waitQueryLogStats looks for the query_log row of a query after a flush
+const queryLogAttempts = 5
+
+// queryLogFlushWait is how long a query_log row can take to show up without SYSTEM FLUSH LOGS: the server
+// writes its logs every flush_interval_milliseconds, 7.5 seconds by default
+const queryLogFlushWait = 8 * time.Second
+
+// flushLogs asks the server to write its logs now and reports whether it did. The server rejects SYSTEM
+// statements in readonly mode, so read-only connections don't ask and wait for the periodic flush instead.
+func flushLogs(ctx context.Context, db driver.Conn, conn *entity.CHConnection) bool {
+	if conn.IsReadOnly() {
+		return false
+	}
+	return db.Exec(ctx, "SYSTEM FLUSH LOGS") == nil
+}
+
+// waitQueryLogStats reads the query_log stats of a query, retrying while the row is not there yet. After a
+// flush it retries a few times with a growing delay, the flush of a busy server can return before the row is
+// written. Without one it keeps looking until the periodic flush must have written the row.
+func (c *clientImpl) waitQueryLogStats(ctx context.Context, db driver.Conn, conn *entity.CHConnection, queryID string) (*entity.QueryStats, error) {
+	flushed := flushLogs(ctx, db, conn)
+	deadline := time.Now().Add(queryLogFlushWait)
+	for attempt := 1; ; attempt++ {
+		stats, err := c.getQueryLogStats(ctx, db, queryID)
+		if err == nil {
+			return stats, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) || flushed && attempt == queryLogAttempts || !flushed && time.Now().After(deadline) {
+			return nil, err
+		}
+
+		delay := 250 * time.Millisecond
+		if flushed {
+			delay = time.Duration(attempt) * 50 * time.Millisecond
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+
+		if flushed {
+			flushed = flushLogs(ctx, db, conn)
+		}
+	}
+}
+
+// ExecuteStatement runs a statement that returns no rows (DDL, INSERT, SET ...) under the ID from WithQueryID,
+// or a random one, and returns its query_log stats. When ctx is cancelled the statement is killed on the server.
+func (c *clientImpl) ExecuteStatement(ctx context.Context, conn *entity.CHConnection, query string) (*entity.QueryStats, error) {
+	db, err := c.connectionFor(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	queryID := QueryIDFromContext(ctx)
+	if queryID == "" {
+		queryID = uuid.New().String()
+	}
+
+	ctxQuery, err := queryContext(ctx, conn, queryID)
+	if err != nil {
+		return nil, err
+	}
+
+	stopKill := context.AfterFunc(ctx, func() {
+		killCtx, cancelKill := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancelKill()
+		_ = c.KillQuery(killCtx, conn, queryID)
+	})
+
+	start := time.Now()
+	err = db.Exec(ctxQuery, query)
+	stopKill()
+	if err != nil {
+		return nil, err
+	}
+	duration := time.Since(start).Milliseconds()
+
+	flushLogs(ctx, db, conn)
+	stats, err := c.getQueryLogStats(ctx, db, queryID)
+	if err != nil {
+		// Some statements (SET, USE) are not logged, and read-only connections can't flush the log
+		return &entity.QueryStats{ExecutionTimeMs: duration, Unlogged: true}, nil
+	}
+
+	return stats, nil
+}
+
+// getQueryLogStats reads the metrics and the full ProfileEvents map of a finished query from system.query_log
+func (c *clientImpl) getQueryLogStats(ctx context.Context, db driver.Conn, queryID string) (*entity.QueryStats, error) {
 	statsQuery := `
 		SELECT
 			query_duration_ms,
 			read_rows,
 			read_bytes,
+			written_rows,
+			written_bytes,
 			memory_usage,
-			ProfileEvents['SelectedParts'] as parts,
-			ProfileEvents['SelectedMarks'] as marks
+			ProfileEvents
 		FROM system.query_log
 		WHERE type = 'QueryFinish' 
 			AND query_id = ? 
@@ -318,133 +523,359 @@ func (c *clientImpl) ExecuteQueryWithStats(ctx context.Context, conn *entity.CHC
 	`
 
 	var (
-		qDuration   uint64 // CH stores as UInt64
-		readRows    uint64
-		readBytes   uint64
-		memoryUsage uint64
-		parts       uint64
-		marks       uint64
+		qDuration     uint64 // CH stores as UInt64
+		readRows      uint64
+		readBytes     uint64
+		writtenRows   uint64
+		writtenBytes  uint64
+		memoryUsage   uint64
+		profileEvents map[string]uint64
 	)
 
-	// Retry loop? just once for now.
-	err = db.QueryRow(ctx, statsQuery, queryID).Scan(&qDuration, &readRows, &readBytes, &memoryUsage, &parts, &marks)
-	if err != nil {
-		// Fallback to client side timing if log not found immediately (async insert issue?)
-		// But user wants log data. Return partially empty or error?
-		// Let's return what we have (client side duration) and zeros if log fails.
-		return &entity.QueryStats{
-			ExecutionTimeMs: duration,
-			RowsRead:        0, // Unknown from log
-			BytesRead:       0,
-			MemoryPeak:      0,
-			PartsRead:       0,
-		}, nil
+	if err := db.QueryRow(ctx, statsQuery, queryID).Scan(&qDuration, &readRows, &readBytes, &writtenRows, &writtenBytes, &memoryUsage, &profileEvents); err != nil {
+		return nil, err
 	}
 
 	return &entity.QueryStats{
 		ExecutionTimeMs: int64(qDuration),
 		RowsRead:        readRows,
 		BytesRead:       readBytes,
+		WrittenRows:     writtenRows,
+		WrittenBytes:    writtenBytes,
 		MemoryPeak:      memoryUsage,
-		PartsRead:       parts,
+		PartsRead:       profileEvents["SelectedParts"],
+		MarksRead:       profileEvents["SelectedMarks"],
+		ProfileEvents:   profileEvents,
 	}, nil
 }
 
+// ExecuteQueryWithResults runs the query under the ID from WithQueryID, or a random one, and collects its rows
+// up to the row and byte caps of the connection. When ctx is cancelled before the query finishes, the query is
+// killed on the server.
 func (c *clientImpl) ExecuteQueryWithResults(ctx context.Context, conn *entity.CHConnection, query string) (*entity.QueryResult, error) {
+	reader, err := c.OpenQuery(ctx, conn, query)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	result := &entity.QueryResult{
+		QueryID:     reader.QueryID(),
+		Columns:     reader.Columns(),
+		ColumnTypes: reader.ColumnTypes(),
+		Rows:        make([]map[string]interface{}, 0),
+	}
+
+	maxRows, maxBytes := conn.ResultLimits()
+	if err := ReadRows(reader, result, math.MaxInt, maxRows, maxBytes); err != nil {
+		return nil, err
+	}
+	reader.Close()
+
+	result.Stats = reader.Stats(ctx)
+	return result, nil
+}
+
+// ReadRows appends up to pageSize rows of reader to result. A row that takes the reader past maxRows rows
+// or maxBytes bytes in total is dropped and the result is marked truncated. HasMore is set when the page
+// filled up before the query ended.
+func ReadRows(reader *ResultReader, result *entity.QueryResult, pageSize, maxRows int, maxBytes int64) error {
+	for read := 0; read < pageSize; read++ {
+		row, err := reader.Next()
+		if err == io.EOF {
+			result.HasMore = false
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if reader.RowCount() > maxRows {
+			result.Truncated = true
+			result.TruncatedReason = fmt.Sprintf("result capped at %d rows", maxRows)
+			result.HasMore = false
+			return nil
+		}
+		if reader.ByteCount() > maxBytes {
+			result.Truncated = true
+			result.TruncatedReason = fmt.Sprintf("result capped at %d bytes", maxBytes)
+			result.HasMore = false
+			return nil
+		}
+		result.Rows = append(result.Rows, row)
+	}
+
+	result.HasMore = true
+	return nil
+}
+
+// GetResultHash computes the row count and an order-insensitive hash of the query result on the server,
+// so large result sets can be compared without transferring them
+func (c *clientImpl) GetResultHash(ctx context.Context, conn *entity.CHConnection, query string) (*entity.ResultHash, error) {
 	db, err := c.getConnection(conn)
 	if err != nil {
 		return nil, err
 	}
 
-	queryID := uuid.New().String()
-	ctxQuery := clickhouse.Context(ctx, clickhouse.WithQueryID(queryID))
+	subquery, err := ResultHashSubquery(query)
+	if err != nil {
+		return nil, err
+	}
 
-	start := time.Now()
-	rows, err := db.Query(ctxQuery, query)
+	// sum() over UInt64 wraps around, which keeps the hash independent of row order while still counting duplicates
+	hashQuery := "SELECT count(), sum(cityHash64(*)) FROM " + subquery
+
+	ctxQuery, err := queryContext(ctx, conn, uuid.New().String())
 	if err != nil {
 		return nil, err
 	}
-	// Get Columns
-	columns := rows.Columns()
-	result := &entity.QueryResult{
+
+	var rows, hash uint64
+	if err := db.QueryRow(ctxQuery, hashQuery).Scan(&rows, &hash); err != nil {
+		return nil, err
+	}
+
+	// The hash ignores column names and can't tell every type apart, they are read from an empty result
+	ctxColumns, err := queryContext(ctx, conn, uuid.New().String())
+	if err != nil {
+		return nil, err
+	}
+	columnRows, err := db.Query(ctxColumns, "SELECT * FROM "+subquery+" LIMIT 0")
+	if err != nil {
+		return nil, err
+	}
+	defer columnRows.Close()
+
+	columns := make([]string, 0, len(columnRows.ColumnTypes()))
+	for _, col := range columnRows.ColumnTypes() {
+		columns = append(columns, col.Name()+" "+col.DatabaseTypeName())
+	}
+
+	return &entity.ResultHash{
+		Rows:    rows,
+		Hash:    strconv.FormatUint(hash, 16),
 		Columns: columns,
-		Rows:    make([]map[string]interface{}, 0),
+	}, nil
+}
+
+// ResultHashSubquery returns the query the way GetResultHash nests it: a single statement without its FORMAT
+// clause, in parentheses on lines of their own so a trailing line comment can't swallow the closing one
+func ResultHashSubquery(query string) (string, error) {
+	statements := sqlparse.Split(query)
+	if len(statements) != 1 {
+		return "", fmt.Errorf("comparing results needs exactly one statement, got %d", len(statements))
 	}
+	statement, _ := sqlparse.StripFormat(statements[0])
+	return "(\n" + statement + "\n)", nil
+}
 
-	// Dynamic Scan
-	columnTypes := rows.ColumnTypes()
+// DropCaches clears the mark, uncompressed and query caches of the server so the next query runs cold.
+// The OS page cache cannot be dropped from a client and is left untouched.
+func (c *clientImpl) DropCaches(ctx context.Context, conn *entity.CHConnection) error {
+	db, err := c.getConnection(conn)
+	if err != nil {
+		return err
+	}
 
-	for rows.Next() {
-		valuePtrs := make([]interface{}, len(columns))
-		for i, ct := range columnTypes {
-			// ClickHouse native driver requires scanning into specific types
-			// We use reflection to allocate a pointer to the type the driver expects
-			valuePtrs[i] = reflect.New(ct.ScanType()).Interface()
-		}
+	if err := db.Exec(ctx, "SYSTEM DROP MARK CACHE"); err != nil {
+		return err
+	}
+	if err := db.Exec(ctx, "SYSTEM DROP UNCOMPRESSED CACHE"); err != nil {
+		return err
+	}
+	// The query cache only exists since ClickHouse 23.1, older servers reject the statement as a syntax error
+	// and have no query cache to drop. Any other failure leaves the cache warm.
+	if err := db.Exec(ctx, "SYSTEM DROP QUERY CACHE"); err != nil && !isSyntaxError(err) {
+		return err
+	}
 
-		if err := rows.Scan(valuePtrs...); err != nil {
-			rows.Close() // Ensure closed on error
-			return nil, err
-		}
+	return nil
+}
 
-		rowMap := make(map[string]interface{})
-		for i, col := range columns {
-			// valuePtrs[i] is a pointer to the value, we need to dereference it
-			val := reflect.ValueOf(valuePtrs[i]).Elem().Interface()
-			rowMap[col] = val
-		}
-		result.Rows = append(result.Rows, rowMap)
+// syntaxErrorCode is the code of the SYNTAX_ERROR exception
+const syntaxErrorCode = 62
+
+// isSyntaxError reports whether the server rejected a statement it cannot parse, over either protocol
+func isSyntaxError(err error) bool {
+	var exception *clickhouse.Exception
+	if errors.As(err, &exception) {
+		return exception.Code == syntaxErrorCode
 	}
+	return strings.Contains(err.Error(), "(SYNTAX_ERROR)")
+}
 
-	// Close rows explicitly to signal query finish to server for logging
-	rows.Close()
-	if err := rows.Err(); err != nil {
+// LoadRunner runs the queries of a load test on a connection checked once up front, so the latency of each
+// query is its own and not that of a ping
+type LoadRunner struct {
+	db   driver.Conn
+	conn *entity.CHConnection
+}
+
+// OpenLoadRunner checks the connection and returns a runner for the queries of a load test
+func (c *clientImpl) OpenLoadRunner(conn *entity.CHConnection) (*LoadRunner, error) {
+	db, err := c.getConnection(conn)
+	if err != nil {
 		return nil, err
 	}
+	return &LoadRunner{db: db, conn: conn}, nil
+}
+
+// Run runs the query under queryID and reads its whole result without keeping any row. The duration covers
+// sending the query and reading the result only.
+func (r *LoadRunner) Run(ctx context.Context, query string, queryID string) (time.Duration, error) {
+	ctxQuery, err := queryContext(ctx, r.conn, queryID)
+	if err != nil {
+		return 0, err
+	}
 
-	duration := time.Since(start).Milliseconds()
+	start := time.Now()
+	rows, err := r.db.Query(ctxQuery, query)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
 
-	// Flush logs
-	_ = db.Exec(ctx, "SYSTEM FLUSH LOGS")
+	for rows.Next() {
+	}
+	elapsed := time.Since(start)
+
+	return elapsed, rows.Err()
+}
+
+// GetLoadTestServerStats aggregates system.query_log for the queries whose ID starts with queryIDPrefix.
+// The IDs must look like "<queryIDPrefix><query index>-<unique suffix>", the result is keyed by the query index.
+func (c *clientImpl) GetLoadTestServerStats(ctx context.Context, conn *entity.CHConnection, queryIDPrefix string, since time.Time) (map[int]*entity.LoadTestServerStats, error) {
+	db, err := c.getConnection(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without a flush only the periodic one writes the last queries of the test
+	if !flushLogs(ctx, db, conn) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(queryLogFlushWait):
+		}
+	}
 
-	// Get Stats
 	statsQuery := `
 		SELECT
-			query_duration_ms,
-			read_rows,
-			read_bytes,
-			memory_usage,
-			ProfileEvents['SelectedParts'] as parts,
-			ProfileEvents['SelectedMarks'] as marks
+			toInt64OrZero(splitByChar('-', substring(query_id, length(?) + 1))[1]) AS idx,
+			count(),
+			sum(ProfileEvents['UserTimeMicroseconds'] + ProfileEvents['SystemTimeMicroseconds']),
+			max(memory_usage),
+			avg(memory_usage),
+			sum(read_bytes),
+			sum(read_rows)
 		FROM system.query_log
-		WHERE type = 'QueryFinish' 
-			AND query_id = ? 
-			AND query != 'SELECT displayName(), version(), revision(), timezone()'
-		LIMIT 1
+		WHERE type IN ('QueryFinish', 'ExceptionWhileProcessing')
+			AND event_time >= ?
+			AND startsWith(query_id, ?)
+		GROUP BY idx
 	`
-	stats := &entity.QueryStats{
-		ExecutionTimeMs: duration, // Fallback
+
+	rows, err := db.Query(ctx, statsQuery, queryIDPrefix, since.Add(-time.Second), queryIDPrefix)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	var (
-		qDuration   uint64
-		readRows    uint64
-		readBytes   uint64
-		memoryUsage uint64
-		parts       uint64
-		marks       uint64
-	)
+	result := make(map[int]*entity.LoadTestServerStats)
+	for rows.Next() {
+		var (
+			idx       int64
+			count     uint64
+			cpuMicros uint64
+			peakMem   uint64
+			avgMem    float64
+			readBytes uint64
+			readRows  uint64
+		)
+		if err := rows.Scan(&idx, &count, &cpuMicros, &peakMem, &avgMem, &readBytes, &readRows); err != nil {
+			return nil, err
+		}
 
-	err = db.QueryRow(ctx, statsQuery, queryID).Scan(&qDuration, &readRows, &readBytes, &memoryUsage, &parts, &marks)
-	if err == nil {
-		stats.ExecutionTimeMs = int64(qDuration)
-		stats.RowsRead = readRows
-		stats.BytesRead = readBytes
-		stats.MemoryPeak = memoryUsage
-		stats.PartsRead = parts
-		stats.MarksRead = marks
+		stats := &entity.LoadTestServerStats{
+			QueryCount:     count,
+			CPUTimeMs:      float64(cpuMicros) / 1000,
+			PeakMemory:     peakMem,
+			AvgMemory:      avgMem,
+			TotalReadBytes: readBytes,
+			TotalReadRows:  readRows,
+		}
+		if count > 0 {
+			stats.AvgCPUTimeMs = stats.CPUTimeMs / float64(count)
+		}
+		result[int(idx)] = stats
 	}
 
-	result.Stats = stats
-	return result, nil
+	return result, rows.Err()
+}
+
+// GetQueryLogSamples groups the finished initial SELECTs of the last windowHours by their normalizeQuery
+// fingerprint, the same grouping as the slow query report, and draws a random sample of statements per group.
+// The most executed fingerprints come first.
+func (c *clientImpl) GetQueryLogSamples(ctx context.Context, conn *entity.CHConnection, windowHours, fingerprints, samples int) ([]entity.QueryLogSample, error) {
+	db, err := c.getConnection(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Queries of this tool and queries on system tables are not user traffic
+	samplesQuery := fmt.Sprintf(`
+		SELECT
+			fingerprint,
+			executions,
+			avg_duration_ms,
+			arrayMap(s -> s.1, samples),
+			arrayMap(s -> s.2, samples),
+			arrayMap(s -> s.3, samples)
+		FROM (
+			SELECT
+				normalizeQuery(query)                                         AS fingerprint,
+				count()                                                       AS executions,
+				avg(query_duration_ms)                                        AS avg_duration_ms,
+				groupArraySample(%d)((query, query_duration_ms, read_bytes))  AS samples
+			FROM system.query_log
+			WHERE
+				event_time >= now() - INTERVAL %d HOUR
+				AND type = 'QueryFinish'
+				AND query_kind = 'Select'
+				AND is_initial_query = 1
+				AND NOT has(databases, 'system')
+				AND client_name NOT LIKE '%%go-ch-manager%%'
+				AND http_user_agent NOT LIKE '%%go-ch-manager%%'
+			GROUP BY fingerprint
+			ORDER BY executions DESC
+			LIMIT %d
+		)
+	`, samples, windowHours, fingerprints)
+
+	rows, err := db.Query(ctx, samplesQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []entity.QueryLogSample
+	for rows.Next() {
+		var sample entity.QueryLogSample
+		if err := rows.Scan(&sample.Fingerprint, &sample.Executions, &sample.AvgDurationMs, &sample.Queries, &sample.DurationsMs, &sample.BytesRead); err != nil {
+			return nil, err
+		}
+		result = append(result, sample)
+	}
+
+	return result, rows.Err()
+}
+
+// KillQuery stops a running query on the server. Killing a query that already finished is not an error.
+func (c *clientImpl) KillQuery(ctx context.Context, conn *entity.CHConnection, queryID string) error {
+	db, err := c.getConnection(conn)
+	if err != nil {
+		return err
+	}
+
+	return db.Exec(ctx, "KILL QUERY WHERE query_id = ? ASYNC", queryID)
 }
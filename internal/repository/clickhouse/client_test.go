@@ -0,0 +1,36 @@
+package clickhouse_test
+
+import (
+	"testing"
+
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/clickhouse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultHashSubquery(t *testing.T) {
+	testcases := []struct {
+		name    string
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{name: "Plain", query: "SELECT 1;", want: "(\nSELECT 1\n)"},
+		{name: "Trailing Line Comment", query: "SELECT 1 -- the answer", want: "(\nSELECT 1 -- the answer\n)"},
+		{name: "Trailing Format", query: "SELECT 1 FORMAT JSON;", want: "(\nSELECT 1\n)"},
+		{name: "Format Before Settings", query: "SELECT 1 FORMAT CSV SETTINGS max_threads = 1", want: "(\nSELECT 1  SETTINGS max_threads = 1\n)"},
+		{name: "Several Statements", query: "SELECT 1; SELECT 2", wantErr: true},
+		{name: "Empty", query: "-- nothing", wantErr: true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := clickhouse.ResultHashSubquery(tc.query)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/sqlparse"
+)
+
+// maxExportErrorBytes bounds how much of an error response of the HTTP interface is read
+const maxExportErrorBytes = 4096
+
+// OpenExport runs the query and returns its result encoded in the given format. On HTTP connections the server
+// renders the format itself and its response is passed through unchanged; otherwise the rows are read with a
+// ResultReader and encoded by the manager. Closing the stream early stops the query.
+func (c *clientImpl) OpenExport(ctx context.Context, conn *entity.CHConnection, query string, format entity.ExportFormat) (io.ReadCloser, error) {
+	if conn.Protocol == "http" && format.ClickHouseFormat != "" {
+		return c.exportOverHTTP(ctx, conn, query, format.ClickHouseFormat)
+	}
+	if format.NeedsHTTP {
+		return nil, fmt.Errorf("%s export needs a connection using the HTTP protocol", format.Label)
+	}
+
+	reader, err := c.OpenQuery(ctx, conn, query)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := TimezoneFromContext(ctx)
+	pr, pw := io.Pipe()
+	go func() {
+		defer reader.Close()
+		pw.CloseWithError(writeExport(pw, reader, format.Name, loc))
+	}()
+
+	return pr, nil
+}
+
+// writeExport encodes every row of reader, in the column order of the query. Values are converted with
+// EncodeValue first so the file matches what the console shows.
+func writeExport(w io.Writer, reader *ResultReader, format string, loc *time.Location) error {
+	exportWriter, err := NewExportWriter(w, format)
+	if err != nil {
+		return err
+	}
+
+	columns, types := reader.Columns(), reader.ColumnTypes()
+	if err := exportWriter.WriteHeader(columns, types); err != nil {
+		return err
+	}
+
+	values := make([]interface{}, len(columns))
+	for {
+		row, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		for i, col := range columns {
+			values[i] = EncodeValue(types[i], row[col], loc)
+		}
+		if err := exportWriter.WriteRow(values); err != nil {
+			return err
+		}
+	}
+
+	return exportWriter.Close()
+}
+
+// exportOverHTTP sends the query with a FORMAT clause to the HTTP interface and returns the response body
+func (c *clientImpl) exportOverHTTP(ctx context.Context, conn *entity.CHConnection, query, chFormat string) (io.ReadCloser, error) {
+	queryID := QueryIDFromContext(ctx)
+	if queryID == "" {
+		queryID = uuid.New().String()
+	}
+
+	settings, err := NormalizeSettings(QuerySettingsFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if loc := TimezoneFromContext(ctx); loc != nil {
+		if _, ok := settings["session_timezone"]; !ok {
+			// Only sent when asked for, servers before 23.6 don't know the setting
+			settings = withSetting(settings, "session_timezone", loc.String())
+		}
+	}
+	settings = ApplyReadOnly(conn, settings)
+	if err := CheckReadOnlySettings(conn, settings); err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Set("query_id", queryID)
+	if sessionID := SessionFromContext(ctx); sessionID != "" {
+		// The session keeps its own current database, set by USE
+		params.Set("session_id", sessionID)
+	} else if conn.Database != "" {
+		params.Set("database", conn.Database)
+	}
+	if conn.IsReadOnly() {
+		params.Set("readonly", strconv.Itoa(conn.ReadOnly))
+	}
+	for name, value := range settings {
+		params.Set(name, fmt.Sprint(value))
+	}
+	for name, value := range QueryParametersFromContext(ctx) {
+		params.Set("param_"+name, value)
+	}
+	// With readonly=1 the server refuses any setting, the defaults below are left out
+	if conn.ReadOnly != entity.ReadOnlyStrict {
+		// Without it the server keeps running the export after the download is aborted
+		params.Set("cancel_http_readonly_queries_on_client_close", "1")
+		if strings.HasPrefix(chFormat, "JSON") {
+			// Render JSON the same way as EncodeValue, unless the caller asked otherwise
+			for name, value := range map[string]string{
+				"output_format_json_quote_64bit_integers": "1",
+				"output_format_json_quote_decimals":       "1",
+				"output_format_json_quote_denormals":      "1",
+			} {
+				if !params.Has(name) {
+					params.Set(name, value)
+				}
+			}
+		}
+	}
+
+	scheme := "http"
+	if conn.UseSSL {
+		scheme = "https"
+	}
+	endpoint := url.URL{
+		Scheme:   scheme,
+		Host:     conn.Host + ":" + strconv.Itoa(conn.Port),
+		Path:     "/",
+		RawQuery: params.Encode(),
+	}
+
+	body := strings.NewReader(withFormat(query, chFormat))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-ClickHouse-User", conn.Username)
+	req.Header.Set("X-ClickHouse-Key", conn.Password)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxExportErrorBytes))
+		return nil, fmt.Errorf("export failed: %s", strings.TrimSpace(string(msg)))
+	}
+
+	return resp.Body, nil
+}
+
+// withSetting returns a copy of settings with name set to value
+func withSetting(settings entity.QuerySettings, name string, value interface{}) entity.QuerySettings {
+	result := make(entity.QuerySettings, len(settings)+1)
+	for k, v := range settings {
+		result[k] = v
+	}
+	result[name] = value
+	return result
+}
+
+// withFormat ends the query with a FORMAT clause, replacing the one the query already has. Trailing semicolons
+// are dropped and the clause goes on its own line so a trailing line comment does not swallow it.
+func withFormat(query, chFormat string) string {
+	query = strings.TrimRight(strings.TrimSpace(query), "; \t\r\n")
+	query, _ = sqlparse.StripFormat(query)
+	query = strings.TrimRight(query, "; \t\r\n")
+	return query + "\nFORMAT " + chFormat
+}
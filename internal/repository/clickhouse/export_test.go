@@ -0,0 +1,151 @@
+package clickhouse_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/clickhouse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenExportOverHTTP(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name       string
+		query      string
+		format     string
+		readOnly   int
+		timezone   *time.Location
+		settings   entity.QuerySettings
+		wantBody   string
+		wantParams map[string]string
+		noParams   []string
+		wantErr    string
+	}{
+		{
+			name:       "CSV Sends No JSON Or Timezone Settings",
+			query:      "SELECT 1;",
+			format:     entity.ExportFormatCSV,
+			wantBody:   "SELECT 1\nFORMAT CSVWithNames",
+			wantParams: map[string]string{"cancel_http_readonly_queries_on_client_close": "1"},
+			noParams:   []string{"session_timezone", "output_format_json_quote_64bit_integers", "readonly"},
+		},
+		{
+			name:       "JSON Quotes Numbers",
+			query:      "SELECT 1",
+			format:     entity.ExportFormatJSONEachRow,
+			wantBody:   "SELECT 1\nFORMAT JSONEachRow",
+			wantParams: map[string]string{"output_format_json_quote_64bit_integers": "1", "output_format_json_quote_decimals": "1"},
+		},
+		{
+			name:       "Caller Setting Wins",
+			query:      "SELECT 1",
+			format:     entity.ExportFormatJSONEachRow,
+			settings:   entity.QuerySettings{"output_format_json_quote_64bit_integers": float64(0)},
+			wantBody:   "SELECT 1\nFORMAT JSONEachRow",
+			wantParams: map[string]string{"output_format_json_quote_64bit_integers": "0"},
+		},
+		{
+			name:       "Requested Timezone",
+			query:      "SELECT now()",
+			format:     entity.ExportFormatCSV,
+			timezone:   berlin,
+			wantBody:   "SELECT now()\nFORMAT CSVWithNames",
+			wantParams: map[string]string{"session_timezone": "Europe/Berlin"},
+		},
+		{
+			name:     "Existing Format Replaced",
+			query:    "SELECT 1 FORMAT JSON;",
+			format:   entity.ExportFormatCSV,
+			wantBody: "SELECT 1\nFORMAT CSVWithNames",
+		},
+		{
+			name:     "Format Before Settings Replaced",
+			query:    "SELECT 1 FORMAT TSV SETTINGS max_threads = 1 -- note",
+			format:   entity.ExportFormatCSV,
+			wantBody: "SELECT 1  SETTINGS max_threads = 1 -- note\nFORMAT CSVWithNames",
+		},
+		{
+			name:     "Subquery Format Kept",
+			query:    "SELECT * FROM (SELECT 1 FORMAT JSON)",
+			format:   entity.ExportFormatCSV,
+			wantBody: "SELECT * FROM (SELECT 1 FORMAT JSON)\nFORMAT CSVWithNames",
+		},
+		{
+			name:       "Strict Read-Only Sends No Settings",
+			query:      "SELECT 1",
+			format:     entity.ExportFormatJSONEachRow,
+			readOnly:   entity.ReadOnlyStrict,
+			wantBody:   "SELECT 1\nFORMAT JSONEachRow",
+			wantParams: map[string]string{"readonly": "1"},
+			noParams:   []string{"cancel_http_readonly_queries_on_client_close", "output_format_json_quote_64bit_integers"},
+		},
+		{
+			name:       "Read-Only Allowing Settings",
+			query:      "SELECT 1",
+			format:     entity.ExportFormatJSONEachRow,
+			readOnly:   entity.ReadOnlyAllowSettings,
+			wantBody:   "SELECT 1\nFORMAT JSONEachRow",
+			wantParams: map[string]string{"readonly": "2", "cancel_http_readonly_queries_on_client_close": "1", "output_format_json_quote_64bit_integers": "1"},
+		},
+		{
+			name:     "Timezone On Strict Read-Only",
+			query:    "SELECT now()",
+			format:   entity.ExportFormatCSV,
+			readOnly: entity.ReadOnlyStrict,
+			timezone: berlin,
+			wantErr:  "session_timezone",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotParams url.Values
+			var gotBody string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotParams = r.URL.Query()
+				body, _ := io.ReadAll(r.Body)
+				gotBody = string(body)
+				_, _ = w.Write([]byte("1\n"))
+			}))
+			defer server.Close()
+
+			serverURL, err := url.Parse(server.URL)
+			require.NoError(t, err)
+			port, err := strconv.Atoi(serverURL.Port())
+			require.NoError(t, err)
+			conn := &entity.CHConnection{Name: "analytics", Host: serverURL.Hostname(), Port: port, Protocol: "http", ReadOnly: tc.readOnly}
+
+			format, ok := entity.FindExportFormat(tc.format)
+			require.True(t, ok)
+			ctx := clickhouse.WithTimezone(context.Background(), tc.timezone)
+			ctx = clickhouse.WithQuerySettings(ctx, tc.settings)
+
+			stream, err := clickhouse.NewClickHouseClient().OpenExport(ctx, conn, tc.query, format)
+			if tc.wantErr != "" {
+				assert.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			defer stream.Close()
+
+			assert.Equal(t, tc.wantBody, gotBody)
+			for name, value := range tc.wantParams {
+				assert.Equal(t, value, gotParams.Get(name), name)
+			}
+			for _, name := range tc.noParams {
+				assert.False(t, gotParams.Has(name), name)
+			}
+		})
+	}
+}
@@ -0,0 +1,383 @@
+package clickhouse
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+)
+
+const (
+	// exportTimeLayout matches how ClickHouse prints DateTime and DateTime64 values
+	exportTimeLayout = "2006-01-02 15:04:05.999999999"
+	// exportNull is the NULL representation of the ClickHouse text formats
+	exportNull = `\N`
+	// maxXLSXRows is the row limit of an Excel worksheet, the header included
+	maxXLSXRows = 1048576
+	// maxXLSXInteger is the largest integer Excel stores exactly, larger ones are written as text
+	maxXLSXInteger = 1 << 53
+)
+
+// ExportWriter encodes result rows in an export format. Rows hold the values in column order,
+// types are the ClickHouse types of the columns.
+type ExportWriter interface {
+	WriteHeader(columns, types []string) error
+	WriteRow(values []interface{}) error
+	Close() error
+}
+
+// NewExportWriter returns the manager side encoder of an export format. Parquet is left to the server.
+func NewExportWriter(w io.Writer, format string) (ExportWriter, error) {
+	switch format {
+	case entity.ExportFormatCSV:
+		return &csvExportWriter{w: csv.NewWriter(w)}, nil
+	case entity.ExportFormatTSV:
+		return &tsvExportWriter{w: bufio.NewWriter(w)}, nil
+	case entity.ExportFormatJSONEachRow:
+		return &jsonExportWriter{w: bufio.NewWriter(w)}, nil
+	case entity.ExportFormatXLSX:
+		return &xlsxExportWriter{zw: zip.NewWriter(w)}, nil
+	}
+	return nil, fmt.Errorf("export format %s is not supported on this connection", format)
+}
+
+type csvExportWriter struct {
+	w *csv.Writer
+}
+
+func (e *csvExportWriter) WriteHeader(columns, types []string) error {
+	return e.w.Write(columns)
+}
+
+func (e *csvExportWriter) WriteRow(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		text, null := exportText(v)
+		if null {
+			text = exportNull
+		}
+		record[i] = text
+	}
+	return e.w.Write(record)
+}
+
+func (e *csvExportWriter) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type tsvExportWriter struct {
+	w *bufio.Writer
+}
+
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+func (e *tsvExportWriter) WriteHeader(columns, types []string) error {
+	return e.writeLine(columns)
+}
+
+func (e *tsvExportWriter) WriteRow(values []interface{}) error {
+	fields := make([]string, len(values))
+	for i, v := range values {
+		text, null := exportText(v)
+		if null {
+			fields[i] = exportNull
+			continue
+		}
+		fields[i] = tsvEscaper.Replace(text)
+	}
+	return e.writeLine(fields)
+}
+
+func (e *tsvExportWriter) writeLine(fields []string) error {
+	for i, field := range fields {
+		if i > 0 {
+			if err := e.w.WriteByte('\t'); err != nil {
+				return err
+			}
+		}
+		if _, err := e.w.WriteString(field); err != nil {
+			return err
+		}
+	}
+	return e.w.WriteByte('\n')
+}
+
+func (e *tsvExportWriter) Close() error {
+	return e.w.Flush()
+}
+
+// jsonExportWriter writes one object per row, keys keep the column order (a map would sort them)
+type jsonExportWriter struct {
+	w    *bufio.Writer
+	keys [][]byte
+}
+
+func (e *jsonExportWriter) WriteHeader(columns, types []string) error {
+	e.keys = make([][]byte, len(columns))
+	for i, col := range columns {
+		key, err := json.Marshal(col)
+		if err != nil {
+			return err
+		}
+		e.keys[i] = key
+	}
+	return nil
+}
+
+func (e *jsonExportWriter) WriteRow(values []interface{}) error {
+	e.w.WriteByte('{')
+	for i, v := range values {
+		if i > 0 {
+			e.w.WriteByte(',')
+		}
+		e.w.Write(e.keys[i])
+		e.w.WriteByte(':')
+
+		value, err := exportJSON(v)
+		if err != nil {
+			return err
+		}
+		e.w.Write(value)
+	}
+	e.w.WriteByte('}')
+	return e.w.WriteByte('\n')
+}
+
+func (e *jsonExportWriter) Close() error {
+	return e.w.Flush()
+}
+
+// xlsxExportWriter streams a single sheet workbook. Cells are written inline so no shared string table
+// has to be kept in memory; numbers stay numeric, everything else becomes text.
+type xlsxExportWriter struct {
+	zw    *zip.Writer
+	sheet *bufio.Writer
+	rows  int
+	// numeric marks the number columns, their values encoded as text (Int64, Decimal) may still fit a cell
+	numeric []bool
+}
+
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Result" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`},
+}
+
+func (e *xlsxExportWriter) WriteHeader(columns, types []string) error {
+	for _, part := range xlsxParts {
+		f, err := e.zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return err
+		}
+	}
+
+	// The sheet is the last part, it stays open while the rows are streamed
+	f, err := e.zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	e.sheet = bufio.NewWriter(f)
+	e.sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	values := make([]interface{}, len(columns))
+	for i, col := range columns {
+		values[i] = col
+	}
+	if err := e.WriteRow(values); err != nil {
+		return err
+	}
+
+	e.numeric = make([]bool, len(columns))
+	for i := range columns {
+		e.numeric[i] = i < len(types) && isNumericType(types[i])
+	}
+	return nil
+}
+
+func (e *xlsxExportWriter) WriteRow(values []interface{}) error {
+	if e.rows >= maxXLSXRows {
+		return fmt.Errorf("XLSX holds at most %d rows, use CSV or Parquet for larger results", maxXLSXRows-1)
+	}
+	e.rows++
+
+	e.sheet.WriteString("<row>")
+	for i, v := range values {
+		if number, ok := xlsxNumber(v, i < len(e.numeric) && e.numeric[i]); ok {
+			e.sheet.WriteString("<c><v>" + number + "</v></c>")
+			continue
+		}
+
+		text, null := exportText(v)
+		if null {
+			e.sheet.WriteString("<c/>")
+			continue
+		}
+		e.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+		if err := xml.EscapeText(e.sheet, []byte(text)); err != nil {
+			return err
+		}
+		e.sheet.WriteString("</t></is></c>")
+	}
+	_, err := e.sheet.WriteString("</row>")
+	return err
+}
+
+func (e *xlsxExportWriter) Close() error {
+	if e.sheet != nil {
+		e.sheet.WriteString("</sheetData></worksheet>")
+		if err := e.sheet.Flush(); err != nil {
+			return err
+		}
+	}
+	return e.zw.Close()
+}
+
+// exportText renders a scanned value the way the ClickHouse text formats print it, null reports a NULL
+func exportText(value interface{}) (text string, null bool) {
+	value, ok := derefValue(value)
+	if !ok {
+		return "", true
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, false
+	case []byte:
+		return string(v), false
+	case time.Time:
+		return v.Format(exportTimeLayout), false
+	case bool:
+		return strconv.FormatBool(v), false
+	case fmt.Stringer:
+		// Decimals, UUIDs, IPs, big integers
+		return v.String(), false
+	case encoding.TextMarshaler:
+		b, err := v.MarshalText()
+		if err == nil {
+			return string(b), false
+		}
+	}
+
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+		// Arrays, maps and tuples
+		if b, err := json.Marshal(value); err == nil {
+			return string(b), false
+		}
+	}
+	return fmt.Sprint(value), false
+}
+
+// exportJSON renders a scanned value as JSON, date times use the ClickHouse layout instead of RFC 3339
+func exportJSON(value interface{}) ([]byte, error) {
+	value, ok := derefValue(value)
+	if !ok {
+		return []byte("null"), nil
+	}
+	if t, ok := value.(time.Time); ok {
+		return json.Marshal(t.Format(exportTimeLayout))
+	}
+	if f, ok := value.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+		return json.Marshal(strconv.FormatFloat(f, 'g', -1, 64))
+	}
+	if f, ok := value.(float32); ok && (math.IsNaN(float64(f)) || math.IsInf(float64(f), 0)) {
+		return json.Marshal(strconv.FormatFloat(float64(f), 'g', -1, 32))
+	}
+	return json.Marshal(value)
+}
+
+// xlsxNumber returns the text of an integer or float value Excel can hold as a number. Text values of
+// numeric columns qualify when they have no more digits than Excel keeps.
+func xlsxNumber(value interface{}, numericColumn bool) (string, bool) {
+	value, ok := derefValue(value)
+	if !ok {
+		return "", false
+	}
+
+	if text, isText := value.(string); isText {
+		if !numericColumn || significantDigits(text) > 15 {
+			return "", false
+		}
+		f, err := strconv.ParseFloat(text, 64)
+		return text, err == nil && !math.IsNaN(f) && !math.IsInf(f, 0)
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := rv.Int()
+		return strconv.FormatInt(n, 10), n <= maxXLSXInteger && n >= -maxXLSXInteger
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n := rv.Uint()
+		return strconv.FormatUint(n, 10), n <= maxXLSXInteger
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", false
+		}
+		return strconv.FormatFloat(f, 'g', -1, 64), true
+	}
+	return "", false
+}
+
+// significantDigits counts the digits of a number in text form, leading zeros excluded
+func significantDigits(text string) int {
+	digits, leading := 0, true
+	for _, r := range text {
+		if r < '0' || r > '9' {
+			continue
+		}
+		if leading && r == '0' {
+			continue
+		}
+		leading = false
+		digits++
+	}
+	return digits
+}
+
+// isNumericType reports whether a ClickHouse type holds integers, floats or decimals
+func isNumericType(chType string) bool {
+	name, args := splitType(chType)
+	if (name == "Nullable" || name == "LowCardinality") && len(args) == 1 {
+		return isNumericType(args[0])
+	}
+	return strings.HasPrefix(name, "Int") || strings.HasPrefix(name, "UInt") ||
+		strings.HasPrefix(name, "Float") || strings.HasPrefix(name, "Decimal") || name == "BFloat16"
+}
+
+// derefValue unwraps the pointers of Nullable columns, ok is false for NULL
+func derefValue(value interface{}) (interface{}, bool) {
+	for value != nil {
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Ptr {
+			return value, true
+		}
+		if rv.IsNil() {
+			return nil, false
+		}
+		value = rv.Elem().Interface()
+	}
+	return nil, false
+}
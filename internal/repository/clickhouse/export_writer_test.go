@@ -0,0 +1,106 @@
+package clickhouse_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/clickhouse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportWriter(t *testing.T) {
+	name := "tab\there"
+	var nullName *string
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	columns := []string{"zeta", "alpha", "created", "tags"}
+	rows := [][]interface{}{
+		{uint64(1), &name, created, []string{"a", "b"}},
+		{uint64(2), nullName, created, []string{}},
+	}
+
+	testcases := []struct {
+		name   string
+		format string
+		want   string
+	}{
+		{
+			name:   "CSV",
+			format: entity.ExportFormatCSV,
+			want: "zeta,alpha,created,tags\n" +
+				"1,tab\there,2026-01-02 03:04:05,\"[\"\"a\"\",\"\"b\"\"]\"\n" +
+				"2,\\N,2026-01-02 03:04:05,[]\n",
+		},
+		{
+			name:   "TSV",
+			format: entity.ExportFormatTSV,
+			want: "zeta\talpha\tcreated\ttags\n" +
+				"1\ttab\\there\t2026-01-02 03:04:05\t[\"a\",\"b\"]\n" +
+				"2\t\\N\t2026-01-02 03:04:05\t[]\n",
+		},
+		{
+			name:   "JSONEachRow Keeps Column Order",
+			format: entity.ExportFormatJSONEachRow,
+			want: `{"zeta":1,"alpha":"tab\there","created":"2026-01-02 03:04:05","tags":["a","b"]}` + "\n" +
+				`{"zeta":2,"alpha":null,"created":"2026-01-02 03:04:05","tags":[]}` + "\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := clickhouse.NewExportWriter(&buf, tc.format)
+			assert.NoError(t, err)
+
+			assert.NoError(t, w.WriteHeader(columns, nil))
+			for _, row := range rows {
+				assert.NoError(t, w.WriteRow(row))
+			}
+			assert.NoError(t, w.Close())
+			assert.Equal(t, tc.want, buf.String())
+		})
+	}
+}
+
+func TestExportWriterXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := clickhouse.NewExportWriter(&buf, entity.ExportFormatXLSX)
+	assert.NoError(t, err)
+
+	assert.NoError(t, w.WriteHeader(
+		[]string{"id", "name", "total", "price"},
+		[]string{"Int32", "String", "UInt64", "Nullable(Decimal(10, 2))"},
+	))
+	assert.NoError(t, w.WriteRow([]interface{}{int32(7), "a<b", "42", "12.50"}))
+	assert.NoError(t, w.WriteRow([]interface{}{int32(8), "123", "1152921504606846976", nil}))
+	assert.NoError(t, w.Close())
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+
+	parts := map[string]string{}
+	for _, f := range archive.File {
+		r, err := f.Open()
+		assert.NoError(t, err)
+		content, _ := io.ReadAll(r)
+		parts[f.Name] = string(content)
+	}
+
+	assert.Contains(t, parts, "[Content_Types].xml")
+	assert.Contains(t, parts, "xl/workbook.xml")
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	assert.Contains(t, sheet, `<row><c t="inlineStr"><is><t xml:space="preserve">id</t></is></c>`)
+	assert.Contains(t, sheet, `<row><c><v>7</v></c><c t="inlineStr"><is><t xml:space="preserve">a&lt;b</t></is></c><c><v>42</v></c><c><v>12.50</v></c></row>`)
+	// Text columns stay text, integers beyond what Excel holds exactly are kept as text
+	assert.Contains(t, sheet, `<row><c><v>8</v></c><c t="inlineStr"><is><t xml:space="preserve">123</t></is></c><c t="inlineStr"><is><t xml:space="preserve">1152921504606846976</t></is></c><c/></row>`)
+	assert.Contains(t, sheet, `</sheetData></worksheet>`)
+}
+
+func TestNewExportWriterParquet(t *testing.T) {
+	_, err := clickhouse.NewExportWriter(io.Discard, entity.ExportFormatParquet)
+	assert.Error(t, err)
+}
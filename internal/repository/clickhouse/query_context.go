@@ -0,0 +1,194 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/rahmatrdn/go-ch-manager/entity"
+)
+
+type querySettingsKey struct{}
+
+type queryIDKey struct{}
+
+type timezoneKey struct{}
+
+type queryParametersKey struct{}
+
+type sessionKey struct{}
+
+// WithQueryID returns a context asking the next user statement to run under the given query ID,
+// so the caller knows it before the statement finishes and can cancel it
+func WithQueryID(ctx context.Context, queryID string) context.Context {
+	if queryID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, queryIDKey{}, queryID)
+}
+
+// QueryIDFromContext returns the query ID stored by WithQueryID
+func QueryIDFromContext(ctx context.Context) string {
+	queryID, _ := ctx.Value(queryIDKey{}).(string)
+	return queryID
+}
+
+// WithSession returns a context running the user statement on the dedicated connection of a console session
+func WithSession(ctx context.Context, sessionID string) context.Context {
+	if sessionID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, sessionKey{}, sessionID)
+}
+
+// SessionFromContext returns the session ID stored by WithSession
+func SessionFromContext(ctx context.Context) string {
+	sessionID, _ := ctx.Value(sessionKey{}).(string)
+	return sessionID
+}
+
+// WithTimezone returns a context asking for the date times of the result to be rendered in loc
+func WithTimezone(ctx context.Context, loc *time.Location) context.Context {
+	if loc == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, timezoneKey{}, loc)
+}
+
+// TimezoneFromContext returns the location stored by WithTimezone, nil keeps each column's own timezone
+func TimezoneFromContext(ctx context.Context) *time.Location {
+	loc, _ := ctx.Value(timezoneKey{}).(*time.Location)
+	return loc
+}
+
+// WithQueryParameters returns a context binding the {name:Type} placeholders of the user statement
+func WithQueryParameters(ctx context.Context, params entity.QueryParameters) context.Context {
+	if len(params) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, queryParametersKey{}, params)
+}
+
+// QueryParametersFromContext returns the parameters stored by WithQueryParameters
+func QueryParametersFromContext(ctx context.Context) entity.QueryParameters {
+	params, _ := ctx.Value(queryParametersKey{}).(entity.QueryParameters)
+	return params
+}
+
+// WithQuerySettings returns a context carrying ClickHouse settings for the statements executed with it.
+// Only the user statement receives them, internal lookups such as reading system.query_log do not.
+func WithQuerySettings(ctx context.Context, settings entity.QuerySettings) context.Context {
+	if len(settings) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, querySettingsKey{}, settings)
+}
+
+// QuerySettingsFromContext returns the settings stored by WithQuerySettings
+func QuerySettingsFromContext(ctx context.Context) entity.QuerySettings {
+	settings, _ := ctx.Value(querySettingsKey{}).(entity.QuerySettings)
+	return settings
+}
+
+// NormalizeSettings validates the setting names and converts JSON values into types the driver can send.
+// Whole numbers decoded as float64 become int so they are not sent as "1e+06", booleans become 0/1.
+func NormalizeSettings(settings entity.QuerySettings) (entity.QuerySettings, error) {
+	if len(settings) == 0 {
+		return nil, nil
+	}
+
+	result := make(entity.QuerySettings, len(settings))
+	for name, value := range settings {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("setting name must not be empty")
+		}
+
+		switch v := value.(type) {
+		case bool:
+			if v {
+				result[name] = 1
+			} else {
+				result[name] = 0
+			}
+		case int:
+			result[name] = v
+		case int64:
+			result[name] = int(v)
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) < math.MaxInt64 {
+				result[name] = int(v)
+			} else {
+				result[name] = fmt.Sprint(v)
+			}
+		case string:
+			result[name] = v
+		default:
+			return nil, fmt.Errorf("setting %s has unsupported value %v", name, value)
+		}
+	}
+
+	return result, nil
+}
+
+// ApplyReadOnly keeps the per-query settings from lifting the read-only mode of the connection. Query settings
+// override the connection's in the driver, so a readonly setting is replaced unless it is stricter.
+func ApplyReadOnly(conn *entity.CHConnection, settings entity.QuerySettings) entity.QuerySettings {
+	value, ok := settings["readonly"]
+	if !conn.IsReadOnly() || !ok || fmt.Sprint(value) == fmt.Sprint(entity.ReadOnlyStrict) {
+		return settings
+	}
+
+	result := make(entity.QuerySettings, len(settings))
+	for name, value := range settings {
+		result[name] = value
+	}
+	result["readonly"] = conn.ReadOnly
+	return result
+}
+
+// CheckReadOnlySettings fails when the settings can't be sent on the connection: with readonly=1 the server
+// refuses to change any setting, readonly=2 allows them
+func CheckReadOnlySettings(conn *entity.CHConnection, settings entity.QuerySettings) error {
+	if conn.ReadOnly != entity.ReadOnlyStrict {
+		return nil
+	}
+
+	var names []string
+	for name := range settings {
+		if name != "readonly" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+	return fmt.Errorf("connection %s is read-only with readonly=1, which forbids query settings (%s): switch it to readonly=2 to allow them", conn.Name, strings.Join(names, ", "))
+}
+
+// queryContext builds the clickhouse-go context of a user statement with its query ID, settings and parameters
+func queryContext(ctx context.Context, conn *entity.CHConnection, queryID string) (context.Context, error) {
+	options := []clickhouse.QueryOption{clickhouse.WithQueryID(queryID)}
+
+	settings, err := NormalizeSettings(QuerySettingsFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	settings = ApplyReadOnly(conn, settings)
+	if err := CheckReadOnlySettings(conn, settings); err != nil {
+		return nil, err
+	}
+	if len(settings) > 0 {
+		options = append(options, clickhouse.WithSettings(clickhouse.Settings(settings)))
+	}
+	if params := QueryParametersFromContext(ctx); len(params) > 0 {
+		options = append(options, clickhouse.WithParameters(clickhouse.Parameters(params)))
+	}
+
+	return clickhouse.Context(ctx, options...), nil
+}
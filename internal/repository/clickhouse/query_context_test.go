@@ -0,0 +1,174 @@
+package clickhouse_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/clickhouse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeSettings(t *testing.T) {
+	testcases := []struct {
+		name     string
+		settings entity.QuerySettings
+		want     entity.QuerySettings
+		wantErr  bool
+	}{
+		{
+			name:     "Empty",
+			settings: nil,
+			want:     nil,
+		},
+		{
+			name: "JSON Numbers And Booleans",
+			settings: entity.QuerySettings{
+				"max_threads":            float64(4),
+				"max_memory_usage":       float64(10000000000),
+				"optimize_read_in_order": true,
+				"use_query_cache":        false,
+				"join_algorithm":         "hash",
+				"max_block_size":         int64(8192),
+				"priority":               1,
+			},
+			want: entity.QuerySettings{
+				"max_threads":            4,
+				"max_memory_usage":       10000000000,
+				"optimize_read_in_order": 1,
+				"use_query_cache":        0,
+				"join_algorithm":         "hash",
+				"max_block_size":         8192,
+				"priority":               1,
+			},
+		},
+		{
+			name:     "Fractional Number Becomes String",
+			settings: entity.QuerySettings{"max_bytes_ratio_before_external_sort": 0.5},
+			want:     entity.QuerySettings{"max_bytes_ratio_before_external_sort": "0.5"},
+		},
+		{
+			name:     "Trims Names",
+			settings: entity.QuerySettings{" max_threads ": float64(2)},
+			want:     entity.QuerySettings{"max_threads": 2},
+		},
+		{
+			name:     "Empty Name",
+			settings: entity.QuerySettings{" ": float64(2)},
+			wantErr:  true,
+		},
+		{
+			name:     "Unsupported Value",
+			settings: entity.QuerySettings{"max_threads": []interface{}{1}},
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := clickhouse.NormalizeSettings(tc.settings)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestQuerySettingsFromContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Nil(t, clickhouse.QuerySettingsFromContext(ctx))
+	assert.Equal(t, ctx, clickhouse.WithQuerySettings(ctx, nil))
+
+	settings := entity.QuerySettings{"max_threads": 1}
+	assert.Equal(t, settings, clickhouse.QuerySettingsFromContext(clickhouse.WithQuerySettings(ctx, settings)))
+}
+
+func TestQueryIDFromContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Empty(t, clickhouse.QueryIDFromContext(ctx))
+	assert.Equal(t, ctx, clickhouse.WithQueryID(ctx, ""))
+	assert.Equal(t, "console-1", clickhouse.QueryIDFromContext(clickhouse.WithQueryID(ctx, "console-1")))
+}
+
+func TestApplyReadOnly(t *testing.T) {
+	testcases := []struct {
+		name     string
+		readOnly int
+		settings entity.QuerySettings
+		want     entity.QuerySettings
+	}{
+		{
+			name:     "Writable Connection",
+			readOnly: entity.ReadOnlyOff,
+			settings: entity.QuerySettings{"readonly": 0, "max_threads": 2},
+			want:     entity.QuerySettings{"readonly": 0, "max_threads": 2},
+		},
+		{
+			name:     "No Readonly Setting",
+			readOnly: entity.ReadOnlyStrict,
+			settings: entity.QuerySettings{"max_threads": 2},
+			want:     entity.QuerySettings{"max_threads": 2},
+		},
+		{
+			name:     "Lifting Is Replaced",
+			readOnly: entity.ReadOnlyStrict,
+			settings: entity.QuerySettings{"readonly": 0, "max_threads": 2},
+			want:     entity.QuerySettings{"readonly": entity.ReadOnlyStrict, "max_threads": 2},
+		},
+		{
+			name:     "Loosening Is Replaced",
+			readOnly: entity.ReadOnlyStrict,
+			settings: entity.QuerySettings{"readonly": "2"},
+			want:     entity.QuerySettings{"readonly": entity.ReadOnlyStrict},
+		},
+		{
+			name:     "Stricter Is Kept",
+			readOnly: entity.ReadOnlyAllowSettings,
+			settings: entity.QuerySettings{"readonly": 1},
+			want:     entity.QuerySettings{"readonly": 1},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			conn := &entity.CHConnection{ReadOnly: tc.readOnly}
+			assert.Equal(t, tc.want, clickhouse.ApplyReadOnly(conn, tc.settings))
+		})
+	}
+}
+
+func TestCheckReadOnlySettings(t *testing.T) {
+	testcases := []struct {
+		name     string
+		readOnly int
+		settings entity.QuerySettings
+		wantErr  string
+	}{
+		{name: "Writable Connection", readOnly: entity.ReadOnlyOff, settings: entity.QuerySettings{"max_threads": 2}},
+		{name: "Settings Allowed", readOnly: entity.ReadOnlyAllowSettings, settings: entity.QuerySettings{"max_threads": 2}},
+		{name: "Strict Without Settings", readOnly: entity.ReadOnlyStrict},
+		{name: "Strict Keeps Readonly", readOnly: entity.ReadOnlyStrict, settings: entity.QuerySettings{"readonly": 1}},
+		{
+			name:     "Strict Rejects Settings",
+			readOnly: entity.ReadOnlyStrict,
+			settings: entity.QuerySettings{"readonly": 1, "max_threads": 2, "join_algorithm": "hash"},
+			wantErr:  "connection analytics is read-only with readonly=1, which forbids query settings (join_algorithm, max_threads): switch it to readonly=2 to allow them",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			conn := &entity.CHConnection{Name: "analytics", ReadOnly: tc.readOnly}
+			err := clickhouse.CheckReadOnlySettings(conn, tc.settings)
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
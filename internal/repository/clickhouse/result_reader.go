@@ -0,0 +1,255 @@
+package clickhouse
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/google/uuid"
+	"github.com/rahmatrdn/go-ch-manager/entity"
+)
+
+// ResultReader reads the rows of a running query one at a time, so callers can cap, page or stream
+// the result instead of holding all of it in memory
+type ResultReader struct {
+	client      *clientImpl
+	conn        *entity.CHConnection
+	db          driver.Conn
+	rows        driver.Rows
+	queryID     string
+	columns     []string
+	columnTypes []driver.ColumnType
+	start       time.Time
+	cancel      context.CancelFunc
+	stopKill    func() bool
+
+	mu        sync.Mutex
+	finished  bool
+	closed    bool
+	rowCount  int
+	byteCount int64
+	duration  time.Duration
+}
+
+// OpenQuery starts the query under the ID from WithQueryID, or a random one, and returns a reader of its rows.
+// Until the reader is exhausted or closed, cancelling ctx kills the query on the server.
+func (c *clientImpl) OpenQuery(ctx context.Context, conn *entity.CHConnection, query string) (*ResultReader, error) {
+	db, err := c.connectionFor(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	queryID := QueryIDFromContext(ctx)
+	if queryID == "" {
+		queryID = uuid.New().String()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	ctxQuery, err := queryContext(ctx, conn, queryID)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// The driver only abandons the query on its side, the server keeps running it unless it is killed
+	stopKill := context.AfterFunc(ctx, func() {
+		killCtx, cancelKill := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancelKill()
+		_ = c.KillQuery(killCtx, conn, queryID)
+	})
+
+	start := time.Now()
+	rows, err := db.Query(ctxQuery, query)
+	if err != nil {
+		stopKill()
+		cancel()
+		return nil, err
+	}
+
+	return &ResultReader{
+		client:      c,
+		conn:        conn,
+		db:          db,
+		rows:        rows,
+		queryID:     queryID,
+		columns:     rows.Columns(),
+		columnTypes: rows.ColumnTypes(),
+		start:       start,
+		cancel:      cancel,
+		stopKill:    stopKill,
+	}, nil
+}
+
+func (r *ResultReader) QueryID() string {
+	return r.queryID
+}
+
+func (r *ResultReader) Columns() []string {
+	return r.columns
+}
+
+// ColumnTypes returns the ClickHouse types of the columns
+func (r *ResultReader) ColumnTypes() []string {
+	types := make([]string, len(r.columnTypes))
+	for i, ct := range r.columnTypes {
+		types[i] = ct.DatabaseTypeName()
+	}
+	return types
+}
+
+// RowCount and ByteCount are the rows read so far and their approximate in-memory size
+func (r *ResultReader) RowCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rowCount
+}
+
+func (r *ResultReader) ByteCount() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.byteCount
+}
+
+// Next returns the next row, or io.EOF once the query has finished
+func (r *ResultReader) Next() (map[string]interface{}, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.finished || r.closed {
+		return nil, io.EOF
+	}
+
+	if !r.rows.Next() {
+		r.finish()
+		if err := r.rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+
+	valuePtrs := make([]interface{}, len(r.columns))
+	for i, ct := range r.columnTypes {
+		// ClickHouse native driver requires scanning into specific types
+		// We use reflection to allocate a pointer to the type the driver expects
+		valuePtrs[i] = reflect.New(ct.ScanType()).Interface()
+	}
+
+	if err := r.rows.Scan(valuePtrs...); err != nil {
+		r.finish()
+		return nil, err
+	}
+
+	row := make(map[string]interface{}, len(r.columns))
+	for i, col := range r.columns {
+		// valuePtrs[i] is a pointer to the value, we need to dereference it
+		val := reflect.ValueOf(valuePtrs[i]).Elem().Interface()
+		row[col] = val
+		r.byteCount += EstimateValueSize(val)
+	}
+	r.rowCount++
+
+	return row, nil
+}
+
+// finish records a query that ran to its end, it must not be killed anymore. Callers must hold r.mu.
+func (r *ResultReader) finish() {
+	if r.finished {
+		return
+	}
+	r.finished = true
+	r.duration = time.Since(r.start)
+	r.stopKill()
+	// Close rows explicitly to signal query finish to server for logging
+	r.rows.Close()
+	r.cancel()
+}
+
+// Close stops reading. A query that has not finished yet is cancelled and killed on the server.
+func (r *ResultReader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+
+	if !r.finished {
+		r.duration = time.Since(r.start)
+		// Cancelling first keeps the driver from draining the remaining blocks
+		r.cancel()
+		r.rows.Close()
+	}
+	return nil
+}
+
+// Stats returns the query_log metrics of a finished query, or only the client side duration when the
+// query was stopped early or the log entry is missing. Read-only connections can't flush the logs, their
+// queries only have an entry once the periodic flush ran.
+func (r *ResultReader) Stats(ctx context.Context) *entity.QueryStats {
+	r.mu.Lock()
+	finished, duration := r.finished, r.duration
+	r.mu.Unlock()
+
+	if finished {
+		flushLogs(ctx, r.db, r.conn)
+		if stats, err := r.client.getQueryLogStats(ctx, r.db, r.queryID); err == nil {
+			return stats
+		}
+	}
+
+	return &entity.QueryStats{
+		ExecutionTimeMs: duration.Milliseconds(), // Fallback
+		Unlogged:        true,
+	}
+}
+
+// EstimateValueSize approximates the memory a scanned value takes, it is used to enforce byte caps
+func EstimateValueSize(value interface{}) int64 {
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case string:
+		return int64(len(v))
+	case []byte:
+		return int64(len(v))
+	case bool, int8, uint8:
+		return 1
+	case int16, uint16:
+		return 2
+	case int32, uint32, float32:
+		return 4
+	case int, int64, uint64, float64:
+		return 8
+	case time.Time:
+		return 24
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return 0
+		}
+		return EstimateValueSize(rv.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		var size int64
+		for i := 0; i < rv.Len(); i++ {
+			size += EstimateValueSize(rv.Index(i).Interface())
+		}
+		return size
+	case reflect.Map:
+		var size int64
+		iter := rv.MapRange()
+		for iter.Next() {
+			size += EstimateValueSize(iter.Key().Interface()) + EstimateValueSize(iter.Value().Interface())
+		}
+		return size
+	}
+
+	// Decimals, big integers, UUIDs, IPs and other fixed size values
+	return 16
+}
@@ -0,0 +1,38 @@
+package clickhouse_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/clickhouse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateValueSize(t *testing.T) {
+	name := "clickhouse"
+	var nilName *string
+
+	testcases := []struct {
+		name  string
+		value interface{}
+		want  int64
+	}{
+		{name: "Nil", value: nil, want: 0},
+		{name: "String", value: "abc", want: 3},
+		{name: "UInt8", value: uint8(1), want: 1},
+		{name: "Int32", value: int32(1), want: 4},
+		{name: "Float64", value: float64(1), want: 8},
+		{name: "DateTime", value: time.Now(), want: 24},
+		{name: "Nullable", value: &name, want: 10},
+		{name: "Nullable Null", value: nilName, want: 0},
+		{name: "Array", value: []string{"a", "bc"}, want: 3},
+		{name: "Map", value: map[string]uint64{"key": 1}, want: 11},
+		{name: "Other Fixed Size", value: struct{ A, B int64 }{}, want: 16},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, clickhouse.EstimateValueSize(tc.value))
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/rahmatrdn/go-ch-manager/entity"
+)
+
+// sessionConn is the dedicated connection of a console session. Over native it is a single TCP connection,
+// whose server side session keeps SET, USE and temporary tables; over HTTP every request carries the
+// session_id instead.
+type sessionConn struct {
+	db           driver.Conn
+	connectionID int64
+}
+
+// OpenSession opens the dedicated connection of a console session
+func (c *clientImpl) OpenSession(ctx context.Context, conn *entity.CHConnection, sessionID string) error {
+	options := connectionOptions(conn)
+	// One connection, never recycled while the session is in use, otherwise its state would be lost
+	options.MaxOpenConns = 1
+	options.MaxIdleConns = 1
+	options.ConnMaxLifetime = 24 * time.Hour
+
+	if conn.Protocol == "http" {
+		if options.Settings == nil {
+			options.Settings = clickhouse.Settings{}
+		}
+		options.Settings["session_id"] = sessionID
+		options.Settings["session_timeout"] = int(entity.ConsoleSessionIdleTimeout.Seconds())
+		// A database parameter on every request would undo USE, the session starts in it instead
+		options.Auth.Database = ""
+	}
+
+	db, err := c.openConn(options)
+	if err != nil {
+		return err
+	}
+
+	if conn.Protocol == "http" && conn.Database != "" {
+		if err := db.Exec(ctx, "USE "+quoteIdentifier(conn.Database)); err != nil {
+			_ = db.Close()
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.sessions[sessionID]; exists {
+		_ = db.Close()
+		return fmt.Errorf("session %s is already open", sessionID)
+	}
+	c.sessions[sessionID] = &sessionConn{db: db, connectionID: conn.ID}
+	return nil
+}
+
+// GetSessionState returns the current database of a console session and the settings changed in it
+func (c *clientImpl) GetSessionState(ctx context.Context, sessionID string) (*entity.ConsoleSession, error) {
+	session, err := c.session(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &entity.ConsoleSession{ID: sessionID, ConnectionID: session.connectionID, Settings: []entity.SessionSetting{}}
+	if err := session.db.QueryRow(ctx, "SELECT currentDatabase()").Scan(&state.Database); err != nil {
+		return nil, err
+	}
+
+	rows, err := session.db.Query(ctx, "SELECT name, value FROM system.settings WHERE changed ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var setting entity.SessionSetting
+		if err := rows.Scan(&setting.Name, &setting.Value); err != nil {
+			return nil, err
+		}
+		state.Settings = append(state.Settings, setting)
+	}
+
+	return state, rows.Err()
+}
+
+// CloseSession closes the dedicated connection of a console session. Over native this ends the server side
+// session; an HTTP session expires on the server after its session_timeout.
+func (c *clientImpl) CloseSession(sessionID string) error {
+	c.mu.Lock()
+	session, ok := c.sessions[sessionID]
+	delete(c.sessions, sessionID)
+	c.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	return session.db.Close()
+}
+
+func (c *clientImpl) session(sessionID string) (*sessionConn, error) {
+	c.mu.RLock()
+	session, ok := c.sessions[sessionID]
+	c.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("console session not found, it may have expired")
+	}
+	return session, nil
+}
+
+// connectionFor returns the connection of the session from WithSession, or the shared pool of the connection.
+// A cancelled statement may close a native session connection, the next statement then starts a fresh session.
+func (c *clientImpl) connectionFor(ctx context.Context, conn *entity.CHConnection) (driver.Conn, error) {
+	sessionID := SessionFromContext(ctx)
+	if sessionID == "" {
+		return c.getConnection(conn)
+	}
+
+	session, err := c.session(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.connectionID != conn.ID {
+		return nil, fmt.Errorf("console session belongs to another connection")
+	}
+	return session.db, nil
+}
+
+// quoteIdentifier quotes a database or table name with backticks
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(strings.ReplaceAll(name, `\`, `\\`), "`", "\\`") + "`"
+}
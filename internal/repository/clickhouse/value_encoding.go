@@ -0,0 +1,370 @@
+package clickhouse
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+)
+
+// Canonical JSON encoding of ClickHouse values, shared by the console, the streaming API and exports:
+//   - Int8-32, UInt8-32 and Float32/64 are JSON numbers, NaN and infinities become "nan", "inf" and "-inf"
+//   - Int64 and wider integers and all Decimals are strings, JavaScript numbers cannot hold them exactly.
+//     Decimals keep the scale of their type, e.g. "1.50" for Decimal(10, 2)
+//   - Date and Date32 are "2006-01-02"; DateTime and DateTime64 are "2006-01-02 15:04:05[.fff]" in the
+//     requested location, or the column's own timezone when none is requested
+//   - UUID, IPv4, IPv6, Enum, String and FixedString are strings, Bool is a boolean
+//   - Array and unnamed Tuple are arrays, named Tuple and Map are objects (Map keys are rendered as text),
+//     Nested is an array of objects
+//   - Nullable and LowCardinality encode as their inner type, NULL is null
+
+const (
+	dateLayout     = "2006-01-02"
+	dateTimeLayout = "2006-01-02 15:04:05"
+)
+
+// EncodeResult replaces the scanned values of all rows of result with their canonical encoding
+func EncodeResult(result *entity.QueryResult, loc *time.Location) {
+	for _, row := range result.Rows {
+		EncodeRow(result.Columns, result.ColumnTypes, row, loc)
+	}
+}
+
+// EncodeRow replaces the scanned values of row with their canonical encoding
+func EncodeRow(columns, types []string, row map[string]interface{}, loc *time.Location) {
+	for i, col := range columns {
+		chType := ""
+		if i < len(types) {
+			chType = types[i]
+		}
+		row[col] = EncodeValue(chType, row[col], loc)
+	}
+}
+
+// EncodeValue converts a value scanned by the driver into its canonical JSON representation for the ClickHouse type.
+// Unknown types fall back to an encoding derived from the Go value.
+func EncodeValue(chType string, value interface{}, loc *time.Location) interface{} {
+	value, ok := derefValue(value)
+	if !ok {
+		return nil
+	}
+
+	name, args := splitType(chType)
+	switch name {
+	case "Nullable", "LowCardinality":
+		if len(args) == 1 {
+			return EncodeValue(args[0], value, loc)
+		}
+	case "SimpleAggregateFunction":
+		if len(args) == 2 {
+			return EncodeValue(args[1], value, loc)
+		}
+	case "Int8", "Int16", "Int32", "UInt8", "UInt16", "UInt32", "Float32", "Float64", "BFloat16":
+		return encodeNumber(value)
+	case "Int64", "UInt64", "Int128", "UInt128", "Int256", "UInt256":
+		return encodeText(value, loc)
+	case "Decimal", "Decimal32", "Decimal64", "Decimal128", "Decimal256":
+		return encodeDecimal(value, decimalScale(name, args))
+	case "Date", "Date32":
+		if t, ok := value.(time.Time); ok {
+			return t.Format(dateLayout)
+		}
+	case "DateTime":
+		if t, ok := value.(time.Time); ok {
+			return inLocation(t, loc).Format(dateTimeLayout)
+		}
+	case "DateTime64":
+		if t, ok := value.(time.Time); ok {
+			precision := 3
+			if len(args) > 0 {
+				if p, err := strconv.Atoi(args[0]); err == nil && p >= 0 && p <= 9 {
+					precision = p
+				}
+			}
+			layout := dateTimeLayout
+			if precision > 0 {
+				layout += "." + strings.Repeat("0", precision)
+			}
+			return inLocation(t, loc).Format(layout)
+		}
+	case "Array":
+		if len(args) == 1 {
+			return encodeList(value, func(int) string { return args[0] }, loc)
+		}
+	case "Nested":
+		return encodeList(value, func(int) string { return "Tuple(" + strings.Join(args, ", ") + ")" }, loc)
+	case "Tuple":
+		return encodeTuple(args, value, loc)
+	case "Map":
+		if len(args) == 2 {
+			return encodeMap(args[0], args[1], value, loc)
+		}
+	}
+
+	return encodeGeneric(value, loc)
+}
+
+func encodeNumber(value interface{}) interface{} {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		switch {
+		case math.IsNaN(f):
+			return "nan"
+		case math.IsInf(f, 1):
+			return "inf"
+		case math.IsInf(f, -1):
+			return "-inf"
+		}
+		if rv.Kind() == reflect.Float32 {
+			// Keep the shortest float32 representation instead of the widened float64 digits
+			v, _ := strconv.ParseFloat(strconv.FormatFloat(f, 'g', -1, 32), 64)
+			return v
+		}
+		return f
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return rv.Uint()
+	}
+	return encodeGeneric(value, nil)
+}
+
+func encodeDecimal(value interface{}, scale int) interface{} {
+	if d, ok := value.(interface{ StringFixed(places int32) string }); ok && scale >= 0 {
+		return d.StringFixed(int32(scale))
+	}
+	return encodeText(value, nil)
+}
+
+// decimalScale returns the scale of Decimal(P, S) or DecimalN(S), -1 when unknown
+func decimalScale(name string, args []string) int {
+	arg := ""
+	switch {
+	case name == "Decimal" && len(args) == 2:
+		arg = args[1]
+	case name != "Decimal" && len(args) == 1:
+		arg = args[0]
+	}
+	scale, err := strconv.Atoi(arg)
+	if err != nil {
+		return -1
+	}
+	return scale
+}
+
+func encodeList(value interface{}, elemType func(i int) string, loc *time.Location) interface{} {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return encodeGeneric(value, loc)
+	}
+
+	list := make([]interface{}, rv.Len())
+	for i := range list {
+		list[i] = EncodeValue(elemType(i), rv.Index(i).Interface(), loc)
+	}
+	return list
+}
+
+// encodeTuple handles tuples scanned as a slice (unnamed) or as a map keyed by element name (named)
+func encodeTuple(args []string, value interface{}, loc *time.Location) interface{} {
+	names := make([]string, len(args))
+	types := make([]string, len(args))
+	for i, arg := range args {
+		names[i], types[i] = splitTupleElement(arg)
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		elemType := func(i int) string {
+			if i < len(types) {
+				return types[i]
+			}
+			return ""
+		}
+		list := encodeList(value, elemType, loc)
+		if named(names) && rv.Len() == len(names) {
+			obj := make(map[string]interface{}, len(names))
+			for i, item := range list.([]interface{}) {
+				obj[names[i]] = item
+			}
+			return obj
+		}
+		return list
+	case reflect.Map:
+		typeOf := make(map[string]string, len(names))
+		for i, n := range names {
+			typeOf[n] = types[i]
+		}
+		obj := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			obj[key] = EncodeValue(typeOf[key], iter.Value().Interface(), loc)
+		}
+		return obj
+	}
+	return encodeGeneric(value, loc)
+}
+
+func named(names []string) bool {
+	for _, n := range names {
+		if n == "" {
+			return false
+		}
+	}
+	return len(names) > 0
+}
+
+func encodeMap(keyType, valueType string, value interface{}, loc *time.Location) interface{} {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Map {
+		return encodeGeneric(value, loc)
+	}
+
+	obj := make(map[string]interface{}, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		obj[keyText(EncodeValue(keyType, iter.Key().Interface(), loc))] = EncodeValue(valueType, iter.Value().Interface(), loc)
+	}
+	return obj
+}
+
+func keyText(key interface{}) string {
+	if s, ok := key.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(key)
+	if err != nil {
+		return fmt.Sprint(key)
+	}
+	return string(b)
+}
+
+// encodeText renders big integers, decimals and other values printed as text
+func encodeText(value interface{}, loc *time.Location) interface{} {
+	switch v := value.(type) {
+	case big.Int:
+		return v.String()
+	case fmt.Stringer:
+		return v.String()
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10)
+	}
+	return encodeGeneric(value, loc)
+}
+
+// encodeGeneric encodes values of unknown or unparsed types (JSON, Variant, Dynamic, geo types) from their Go type
+func encodeGeneric(value interface{}, loc *time.Location) interface{} {
+	value, ok := derefValue(value)
+	if !ok {
+		return nil
+	}
+
+	switch v := value.(type) {
+	case string, bool:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return inLocation(v, loc).Format(dateTimeLayout)
+	case big.Int:
+		return v.String()
+	case json.Marshaler:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int64, reflect.Uint64, reflect.Int, reflect.Uint:
+		return encodeText(value, loc)
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Float32, reflect.Float64:
+		return encodeNumber(value)
+	case reflect.Slice, reflect.Array:
+		return encodeList(value, func(int) string { return "" }, loc)
+	case reflect.Map:
+		return encodeMap("", "", value, loc)
+	}
+	return value
+}
+
+func inLocation(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		return t
+	}
+	return t.In(loc)
+}
+
+// splitType splits a ClickHouse type such as "Map(String, Array(UInt8))" into its name and top level arguments
+func splitType(chType string) (string, []string) {
+	chType = strings.TrimSpace(chType)
+	open := strings.IndexByte(chType, '(')
+	if open < 0 || !strings.HasSuffix(chType, ")") {
+		return chType, nil
+	}
+
+	name := strings.TrimSpace(chType[:open])
+	inner := chType[open+1 : len(chType)-1]
+
+	var args []string
+	depth, start := 0, 0
+	inQuote := false
+	for i := 0; i < len(inner); i++ {
+		switch c := inner[i]; {
+		case inQuote:
+			if c == '\\' {
+				i++
+			} else if c == '\'' {
+				inQuote = false
+			}
+		case c == '\'':
+			inQuote = true
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			args = append(args, strings.TrimSpace(inner[start:i]))
+			start = i + 1
+		}
+	}
+	if rest := strings.TrimSpace(inner[start:]); rest != "" {
+		args = append(args, rest)
+	}
+
+	return name, args
+}
+
+// splitTupleElement separates "name Type" of a named tuple element, unnamed elements return an empty name
+func splitTupleElement(element string) (string, string) {
+	depth := 0
+	for i, c := range element {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ' ':
+			if depth == 0 {
+				return strings.Trim(element[:i], "`\""), strings.TrimSpace(element[i+1:])
+			}
+		}
+	}
+	return "", element
+}
@@ -0,0 +1,87 @@
+package clickhouse_test
+
+import (
+	"math"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/clickhouse"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeValue(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*3600)
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 123456789, time.UTC)
+	name := "x"
+	var nullName *string
+	bigInt, _ := new(big.Int).SetString("170141183460469231731687303715884105727", 10)
+
+	testcases := []struct {
+		name   string
+		chType string
+		value  interface{}
+		loc    *time.Location
+		want   interface{}
+	}{
+		{name: "UInt32", chType: "UInt32", value: uint32(7), want: uint64(7)},
+		{name: "Float64 NaN", chType: "Float64", value: math.NaN(), want: "nan"},
+		{name: "Float64 Negative Infinity", chType: "Float64", value: math.Inf(-1), want: "-inf"},
+		{name: "Float32 Shortest", chType: "Float32", value: float32(0.1), want: 0.1},
+		{name: "UInt64 As String", chType: "UInt64", value: uint64(math.MaxUint64), want: "18446744073709551615"},
+		{name: "Int128 As String", chType: "Int128", value: bigInt, want: "170141183460469231731687303715884105727"},
+		{name: "Decimal Keeps Scale", chType: "Decimal(10, 2)", value: decimal.RequireFromString("1.5"), want: "1.50"},
+		{name: "Decimal64 Keeps Scale", chType: "Decimal64(3)", value: decimal.RequireFromString("-2"), want: "-2.000"},
+		{name: "Date", chType: "Date", value: ts, loc: jakarta, want: "2026-01-02"},
+		{name: "DateTime Column Timezone", chType: "DateTime('UTC')", value: ts, want: "2026-01-02 03:04:05"},
+		{name: "DateTime Requested Timezone", chType: "DateTime", value: ts, loc: jakarta, want: "2026-01-02 10:04:05"},
+		{name: "DateTime64 Precision", chType: "DateTime64(3, 'UTC')", value: ts, want: "2026-01-02 03:04:05.123"},
+		{name: "Nullable", chType: "Nullable(String)", value: &name, want: "x"},
+		{name: "Nullable NULL", chType: "Nullable(String)", value: nullName, want: nil},
+		{name: "LowCardinality", chType: "LowCardinality(Nullable(String))", value: &name, want: "x"},
+		{name: "UUID", chType: "UUID", value: uuid.MustParse("3f2b6c1e-8a4d-4c1b-9b7e-2d5f0a9c8e71"), want: "3f2b6c1e-8a4d-4c1b-9b7e-2d5f0a9c8e71"},
+		{name: "IPv4", chType: "IPv4", value: net.ParseIP("10.0.0.1"), want: "10.0.0.1"},
+		{name: "Enum", chType: "Enum8('a' = 1, 'b,c' = 2)", value: "b,c", want: "b,c"},
+		{name: "Bool", chType: "Bool", value: true, want: true},
+		{
+			name:   "Array Of UInt64",
+			chType: "Array(UInt64)",
+			value:  []uint64{1, 2},
+			want:   []interface{}{"1", "2"},
+		},
+		{
+			name:   "Map",
+			chType: "Map(UInt16, Decimal(5, 1))",
+			value:  map[uint16]decimal.Decimal{3: decimal.RequireFromString("4")},
+			want:   map[string]interface{}{"3": "4.0"},
+		},
+		{
+			name:   "Unnamed Tuple",
+			chType: "Tuple(String, Int64)",
+			value:  []interface{}{"a", int64(5)},
+			want:   []interface{}{"a", "5"},
+		},
+		{
+			name:   "Named Tuple",
+			chType: "Tuple(id UInt8, at DateTime('UTC'))",
+			value:  []interface{}{uint8(1), ts},
+			want:   map[string]interface{}{"id": uint64(1), "at": "2026-01-02 03:04:05"},
+		},
+		{
+			name:   "Nested",
+			chType: "Nested(k String, v Int64)",
+			value:  []interface{}{[]interface{}{"a", int64(1)}},
+			want:   []interface{}{map[string]interface{}{"k": "a", "v": "1"}},
+		},
+		{name: "Unknown Type", chType: "Dynamic", value: int64(9), want: "9"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, clickhouse.EncodeValue(tc.chType, tc.value, tc.loc))
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package sqlite
+
+import (
+	"context"
+
+	errwrap "github.com/pkg/errors"
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/helper"
+	"gorm.io/gorm"
+)
+
+type ChangeRequestRepository interface {
+	Create(ctx context.Context, request *entity.ChangeRequest) error
+	FindByID(ctx context.Context, id int64) (*entity.ChangeRequest, error)
+	FindByConnection(ctx context.Context, connectionID int64, filter entity.ChangeRequestFilter) ([]*entity.ChangeRequest, error)
+	// Transition saves the request only while the stored one is still in status from. It returns false when
+	// another request changed the status first, so a request is never approved or executed twice.
+	Transition(ctx context.Context, request *entity.ChangeRequest, from string) (bool, error)
+}
+
+type changeRequestRepository struct {
+	db *gorm.DB
+}
+
+func NewChangeRequestRepository(db *gorm.DB) ChangeRequestRepository {
+	return &changeRequestRepository{db: db}
+}
+
+func (r *changeRequestRepository) Create(ctx context.Context, request *entity.ChangeRequest) error {
+	funcName := "ChangeRequestRepository.Create"
+	if err := helper.CheckDeadline(ctx); err != nil {
+		return errwrap.Wrap(err, funcName)
+	}
+
+	return r.db.WithContext(ctx).Create(request).Error
+}
+
+func (r *changeRequestRepository) FindByID(ctx context.Context, id int64) (*entity.ChangeRequest, error) {
+	funcName := "ChangeRequestRepository.FindByID"
+	if err := helper.CheckDeadline(ctx); err != nil {
+		return nil, errwrap.Wrap(err, funcName)
+	}
+
+	var request entity.ChangeRequest
+	err := r.db.WithContext(ctx).First(&request, id).Error
+	if err != nil {
+		if errwrap.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, errwrap.Wrap(err, funcName)
+	}
+	return &request, nil
+}
+
+func (r *changeRequestRepository) FindByConnection(ctx context.Context, connectionID int64, filter entity.ChangeRequestFilter) ([]*entity.ChangeRequest, error) {
+	funcName := "ChangeRequestRepository.FindByConnection"
+	if err := helper.CheckDeadline(ctx); err != nil {
+		return nil, errwrap.Wrap(err, funcName)
+	}
+
+	query := r.db.WithContext(ctx).Where("connection_id = ?", connectionID)
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var requests []*entity.ChangeRequest
+	err := query.
+		Order("id desc").
+		Find(&requests).Error
+	if err != nil {
+		return nil, errwrap.Wrap(err, funcName)
+	}
+	return requests, nil
+}
+
+func (r *changeRequestRepository) Transition(ctx context.Context, request *entity.ChangeRequest, from string) (bool, error) {
+	funcName := "ChangeRequestRepository.Transition"
+	if err := helper.CheckDeadline(ctx); err != nil {
+		return false, errwrap.Wrap(err, funcName)
+	}
+
+	result := r.db.WithContext(ctx).
+		Model(&entity.ChangeRequest{}).
+		Where("id = ? AND status = ?", request.ID, from).
+		Select("*").
+		Omit("id", "created_at").
+		Updates(request)
+	if result.Error != nil {
+		return false, errwrap.Wrap(result.Error, funcName)
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,73 @@
+package sqlite_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gormsqlite "gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newChangeRequestRepository(t *testing.T) sqlite.ChangeRequestRepository {
+	db, err := gorm.Open(gormsqlite.Open(filepath.Join(t.TempDir(), "changes.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&entity.ChangeRequest{}))
+
+	return sqlite.NewChangeRequestRepository(db)
+}
+
+func TestChangeRequestTransition(t *testing.T) {
+	ctx := context.Background()
+	repo := newChangeRequestRepository(t)
+
+	request := &entity.ChangeRequest{
+		ConnectionID:  1,
+		Query:         "ALTER TABLE events ADD COLUMN source String",
+		Kinds:         []string{entity.StatementKindDDL},
+		Justification: "Track the source of events",
+		Status:        entity.ChangeRequestPending,
+		CreatedBy:     "alice",
+		Log:           []entity.ChangeRequestLogEntry{{User: "alice", Action: "created"}},
+	}
+	require.NoError(t, repo.Create(ctx, request))
+
+	approved := *request
+	approved.Status = entity.ChangeRequestApproved
+	approved.ReviewedBy = "bob"
+	approved.Log = append(approved.Log, entity.ChangeRequestLogEntry{User: "bob", Action: entity.ChangeRequestApproved})
+
+	testcases := []struct {
+		name   string
+		update entity.ChangeRequest
+		from   string
+		wantOK bool
+	}{
+		{name: "From Current Status", update: approved, from: entity.ChangeRequestPending, wantOK: true},
+		{name: "Status Changed Since", update: approved, from: entity.ChangeRequestPending, wantOK: false},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			update := tc.update
+			ok, err := repo.Transition(ctx, &update, tc.from)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantOK, ok)
+		})
+	}
+
+	stored, err := repo.FindByID(ctx, request.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.ChangeRequestApproved, stored.Status)
+	assert.Equal(t, "bob", stored.ReviewedBy)
+	assert.Equal(t, []string{entity.StatementKindDDL}, stored.Kinds)
+	assert.Len(t, stored.Log, 2)
+
+	requests, err := repo.FindByConnection(ctx, 1, entity.ChangeRequestFilter{Status: entity.ChangeRequestPending})
+	require.NoError(t, err)
+	assert.Empty(t, requests)
+}
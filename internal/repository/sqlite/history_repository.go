@@ -2,6 +2,7 @@ package sqlite
 
 import (
 	"context"
+	"strings"
 
 	errwrap "github.com/pkg/errors"
 	"github.com/rahmatrdn/go-ch-manager/entity"
@@ -11,18 +12,85 @@ import (
 
 type QueryHistoryRepository interface {
 	Create(ctx context.Context, history *entity.QueryHistory) error
-	FindByConnectionID(ctx context.Context, connectionID int64, limit int) ([]*entity.QueryHistory, error)
+	Update(ctx context.Context, history *entity.QueryHistory) error
+	FindByID(ctx context.Context, id int64) (*entity.QueryHistory, error)
+	// FindLatest returns the newest entry of the connection, nil when it has none
+	FindLatest(ctx context.Context, connectionID int64) (*entity.QueryHistory, error)
+	FindByConnectionID(ctx context.Context, connectionID int64, filter entity.HistoryFilter) ([]*entity.QueryHistory, error)
+	SetPinned(ctx context.Context, id int64, pinned bool) error
+	// Prune keeps the newest maxLimit unpinned entries of the connection
 	Prune(ctx context.Context, connectionID int64, maxLimit int) error
 }
 
 type QueryHistory struct {
 	db *gorm.DB
+	// fts is set once the FTS5 index of the history is in place, searches fall back to LIKE without it
+	fts bool
 }
 
 func NewQueryHistoryRepository(db *gorm.DB) *QueryHistory {
 	return &QueryHistory{db: db}
 }
 
+// historySearchTriggers keep the external content FTS5 index in step with query_histories
+var historySearchTriggers = map[string]string{
+	"query_histories_fts_ai": `CREATE TRIGGER query_histories_fts_ai AFTER INSERT ON query_histories BEGIN
+		INSERT INTO query_histories_fts(rowid, query, error) VALUES (new.id, new.query, new.error);
+	END`,
+	"query_histories_fts_ad": `CREATE TRIGGER query_histories_fts_ad AFTER DELETE ON query_histories BEGIN
+		INSERT INTO query_histories_fts(query_histories_fts, rowid, query, error) VALUES ('delete', old.id, old.query, old.error);
+	END`,
+	"query_histories_fts_au": `CREATE TRIGGER query_histories_fts_au AFTER UPDATE ON query_histories BEGIN
+		INSERT INTO query_histories_fts(query_histories_fts, rowid, query, error) VALUES ('delete', old.id, old.query, old.error);
+		INSERT INTO query_histories_fts(rowid, query, error) VALUES (new.id, new.query, new.error);
+	END`,
+}
+
+// SetupSearch creates the FTS5 index of the history. SQLite must be built with FTS5 (the sqlite_fts5 build tag),
+// otherwise the index triggers are removed so writes keep working and searches use LIKE.
+func (r *QueryHistory) SetupSearch(ctx context.Context) error {
+	funcName := "QueryHistoryRepository.SetupSearch"
+	db := r.db.WithContext(ctx)
+
+	err := db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS query_histories_fts USING fts5(query, error, content='query_histories', content_rowid='id')`).Error
+	if err == nil {
+		// IF NOT EXISTS does not load the module, an index created by an FTS5 build only fails once it is read
+		err = db.Exec(`SELECT rowid FROM query_histories_fts LIMIT 0`).Error
+	}
+	if err != nil {
+		// A database indexed by an FTS5 build would otherwise fail every insert
+		for name := range historySearchTriggers {
+			_ = db.Exec("DROP TRIGGER IF EXISTS " + name).Error
+		}
+		return errwrap.Wrap(err, funcName)
+	}
+
+	var existing []string
+	if err := db.Raw(`SELECT name FROM sqlite_master WHERE type = 'trigger' AND tbl_name = 'query_histories'`).Scan(&existing).Error; err != nil {
+		return errwrap.Wrap(err, funcName)
+	}
+
+	// Missing triggers mean the index missed writes, it is rebuilt from the table
+	rebuild := false
+	for name, statement := range historySearchTriggers {
+		if helper.InArray(name, existing) {
+			continue
+		}
+		if err := db.Exec(statement).Error; err != nil {
+			return errwrap.Wrap(err, funcName)
+		}
+		rebuild = true
+	}
+	if rebuild {
+		if err := db.Exec(`INSERT INTO query_histories_fts(query_histories_fts) VALUES ('rebuild')`).Error; err != nil {
+			return errwrap.Wrap(err, funcName)
+		}
+	}
+
+	r.fts = true
+	return nil
+}
+
 func (r *QueryHistory) Create(ctx context.Context, history *entity.QueryHistory) error {
 	funcName := "QueryHistoryRepository.Create"
 	if err := helper.CheckDeadline(ctx); err != nil {
@@ -32,18 +100,86 @@ func (r *QueryHistory) Create(ctx context.Context, history *entity.QueryHistory)
 	return r.db.WithContext(ctx).Create(history).Error
 }
 
-func (r *QueryHistory) FindByConnectionID(ctx context.Context, connectionID int64, limit int) ([]*entity.QueryHistory, error) {
-	funcName := "QueryHistoryRepository.FindByConnectionID"
+func (r *QueryHistory) Update(ctx context.Context, history *entity.QueryHistory) error {
+	funcName := "QueryHistoryRepository.Update"
+	if err := helper.CheckDeadline(ctx); err != nil {
+		return errwrap.Wrap(err, funcName)
+	}
+
+	return r.db.WithContext(ctx).Save(history).Error
+}
+
+func (r *QueryHistory) FindByID(ctx context.Context, id int64) (*entity.QueryHistory, error) {
+	funcName := "QueryHistoryRepository.FindByID"
+	if err := helper.CheckDeadline(ctx); err != nil {
+		return nil, errwrap.Wrap(err, funcName)
+	}
+
+	var history entity.QueryHistory
+	err := r.db.WithContext(ctx).First(&history, id).Error
+	if err != nil {
+		if errwrap.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, errwrap.Wrap(err, funcName)
+	}
+	return &history, nil
+}
+
+func (r *QueryHistory) FindLatest(ctx context.Context, connectionID int64) (*entity.QueryHistory, error) {
+	funcName := "QueryHistoryRepository.FindLatest"
 	if err := helper.CheckDeadline(ctx); err != nil {
 		return nil, errwrap.Wrap(err, funcName)
 	}
 
 	var histories []*entity.QueryHistory
-	// Order by CreatedAt DESC to get latest queries
 	err := r.db.WithContext(ctx).
 		Where("connection_id = ?", connectionID).
+		Order("created_at desc, id desc").
+		Limit(1).
+		Find(&histories).Error
+	if err != nil {
+		return nil, errwrap.Wrap(err, funcName)
+	}
+	if len(histories) == 0 {
+		return nil, nil
+	}
+	return histories[0], nil
+}
+
+func (r *QueryHistory) FindByConnectionID(ctx context.Context, connectionID int64, filter entity.HistoryFilter) ([]*entity.QueryHistory, error) {
+	funcName := "QueryHistoryRepository.FindByConnectionID"
+	if err := helper.CheckDeadline(ctx); err != nil {
+		return nil, errwrap.Wrap(err, funcName)
+	}
+
+	query := r.db.WithContext(ctx).Where("connection_id = ?", connectionID)
+	if filter.PinnedOnly {
+		query = query.Where("pinned = ?", true)
+	}
+	if filter.FailedOnly {
+		query = query.Where("error <> ''")
+	}
+	if filter.User != "" {
+		query = query.Where("user = ?", filter.User)
+	}
+
+	if terms := strings.Fields(filter.Search); len(terms) > 0 {
+		if r.fts {
+			query = query.Where("id IN (SELECT rowid FROM query_histories_fts WHERE query_histories_fts MATCH ?)", FTSMatchQuery(terms))
+		} else {
+			for _, term := range terms {
+				pattern := "%" + EscapeLike(term) + "%"
+				query = query.Where(`(query LIKE ? ESCAPE '\' OR error LIKE ? ESCAPE '\')`, pattern, pattern)
+			}
+		}
+	}
+
+	var histories []*entity.QueryHistory
+	// Order by CreatedAt DESC to get latest queries
+	err := query.
 		Order("created_at desc").
-		Limit(limit).
+		Limit(filter.Limit).
 		Find(&histories).Error
 
 	if err != nil {
@@ -52,6 +188,15 @@ func (r *QueryHistory) FindByConnectionID(ctx context.Context, connectionID int6
 	return histories, nil
 }
 
+func (r *QueryHistory) SetPinned(ctx context.Context, id int64, pinned bool) error {
+	funcName := "QueryHistoryRepository.SetPinned"
+	if err := helper.CheckDeadline(ctx); err != nil {
+		return errwrap.Wrap(err, funcName)
+	}
+
+	return r.db.WithContext(ctx).Model(&entity.QueryHistory{}).Where("id = ?", id).Update("pinned", pinned).Error
+}
+
 func (r *QueryHistory) Prune(ctx context.Context, connectionID int64, maxLimit int) error {
 	funcName := "QueryHistoryRepository.Prune"
 	if err := helper.CheckDeadline(ctx); err != nil {
@@ -68,13 +213,29 @@ func (r *QueryHistory) Prune(ctx context.Context, connectionID int64, maxLimit i
 	// OR using subquery delete:
 	// DELETE FROM query_histories WHERE connection_id = ? AND id NOT IN (SELECT id FROM query_histories WHERE connection_id = ? ORDER BY created_at DESC LIMIT ?)
 
+	// Pinned entries neither count against the limit nor get deleted
 	return r.db.WithContext(ctx).
-		Where("connection_id = ? AND id NOT IN (?)", connectionID,
+		Where("connection_id = ? AND pinned = ? AND id NOT IN (?)", connectionID, false,
 			r.db.Model(&entity.QueryHistory{}).
 				Select("id").
-				Where("connection_id = ?", connectionID).
+				Where("connection_id = ? AND pinned = ?", connectionID, false).
 				Order("created_at desc").
 				Limit(maxLimit),
 		).
 		Delete(&entity.QueryHistory{}).Error
 }
+
+// FTSMatchQuery turns search terms into an FTS5 query matching entries that contain every term as a prefix.
+// Terms are quoted so FTS5 operators and punctuation in them are taken literally.
+func FTSMatchQuery(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"*`
+	}
+	return strings.Join(quoted, " ")
+}
+
+// EscapeLike escapes the wildcards of a LIKE pattern, for use with ESCAPE '\'
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
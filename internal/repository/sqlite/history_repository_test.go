@@ -0,0 +1,124 @@
+package sqlite_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gormsqlite "gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestFTSMatchQuery(t *testing.T) {
+	testcases := []struct {
+		name  string
+		terms []string
+		want  string
+	}{
+		{name: "Single Term", terms: []string{"events"}, want: `"events"*`},
+		{name: "Every Term", terms: []string{"select", "uid"}, want: `"select"* "uid"*`},
+		{name: "Operators Are Literal", terms: []string{"NOT", "a:b"}, want: `"NOT"* "a:b"*`},
+		{name: "Quotes Are Doubled", terms: []string{`"x"`}, want: `"""x"""*`},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, sqlite.FTSMatchQuery(tc.terms))
+		})
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	assert.Equal(t, `100\%`, sqlite.EscapeLike("100%"))
+	assert.Equal(t, `query\_log`, sqlite.EscapeLike("query_log"))
+	assert.Equal(t, `a\\b`, sqlite.EscapeLike(`a\b`))
+}
+
+func newHistoryRepository(t *testing.T) (*sqlite.QueryHistory, *gorm.DB) {
+	db, err := gorm.Open(gormsqlite.Open(filepath.Join(t.TempDir(), "history.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&entity.QueryHistory{}))
+
+	return sqlite.NewQueryHistoryRepository(db), db
+}
+
+func TestQueryHistoryPruneKeepsPinned(t *testing.T) {
+	ctx := context.Background()
+	repo, db := newHistoryRepository(t)
+
+	start := time.Now().Add(-time.Hour)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, db.Create(&entity.QueryHistory{
+			ConnectionID: 1,
+			Query:        fmt.Sprintf("SELECT %d", i),
+			Pinned:       i == 0,
+			CreatedAt:    start.Add(time.Duration(i) * time.Minute),
+		}).Error)
+	}
+
+	require.NoError(t, repo.Prune(ctx, 1, 2))
+
+	histories, err := repo.FindByConnectionID(ctx, 1, entity.HistoryFilter{Limit: 10})
+	require.NoError(t, err)
+
+	var queries []string
+	for _, h := range histories {
+		queries = append(queries, h.Query)
+	}
+	assert.Equal(t, []string{"SELECT 4", "SELECT 3", "SELECT 0"}, queries)
+}
+
+func TestQueryHistorySearch(t *testing.T) {
+	t.Run("LIKE", func(t *testing.T) {
+		testQueryHistorySearch(t, false)
+	})
+	t.Run("FTS5", func(t *testing.T) {
+		testQueryHistorySearch(t, true)
+	})
+}
+
+func testQueryHistorySearch(t *testing.T, fts bool) {
+	ctx := context.Background()
+	repo, _ := newHistoryRepository(t)
+	if fts {
+		if err := repo.SetupSearch(ctx); err != nil {
+			t.Skipf("SQLite built without FTS5 (sqlite_fts5 build tag): %v", err)
+		}
+	}
+
+	for _, h := range []*entity.QueryHistory{
+		{ConnectionID: 1, Query: "SELECT * FROM events WHERE uid = 1"},
+		{ConnectionID: 1, Query: "SELECT count() FROM users", Error: "Unknown table users"},
+		{ConnectionID: 2, Query: "SELECT * FROM events"},
+	} {
+		require.NoError(t, repo.Create(ctx, h))
+	}
+
+	testcases := []struct {
+		name   string
+		filter entity.HistoryFilter
+		want   int
+	}{
+		{name: "Query Text", filter: entity.HistoryFilter{Search: "events"}, want: 1},
+		{name: "Every Term", filter: entity.HistoryFilter{Search: "events uid"}, want: 1},
+		{name: "Error Text", filter: entity.HistoryFilter{Search: "unknown"}, want: 1},
+		{name: "Prefix", filter: entity.HistoryFilter{Search: "even"}, want: 1},
+		{name: "Failed Only", filter: entity.HistoryFilter{FailedOnly: true}, want: 1},
+		{name: "No Match", filter: entity.HistoryFilter{Search: "orders"}, want: 0},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.filter.Limit = 10
+			histories, err := repo.FindByConnectionID(ctx, 1, tc.filter)
+			require.NoError(t, err)
+			assert.Len(t, histories, tc.want)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package sqlite
+
+import (
+	"context"
+
+	errwrap "github.com/pkg/errors"
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/helper"
+	"gorm.io/gorm"
+)
+
+type ReplayRepository interface {
+	Create(ctx context.Context, run *entity.ReplayRun) error
+	Update(ctx context.Context, run *entity.ReplayRun) error
+	FindByID(ctx context.Context, id int64) (*entity.ReplayRun, error)
+	FindBySourceConnectionID(ctx context.Context, connectionID int64, limit int) ([]*entity.ReplayRun, error)
+	Delete(ctx context.Context, id int64) error
+}
+
+type replayRepository struct {
+	db *gorm.DB
+}
+
+func NewReplayRepository(db *gorm.DB) ReplayRepository {
+	return &replayRepository{db: db}
+}
+
+func (r *replayRepository) Create(ctx context.Context, run *entity.ReplayRun) error {
+	funcName := "ReplayRepository.Create"
+	if err := helper.CheckDeadline(ctx); err != nil {
+		return errwrap.Wrap(err, funcName)
+	}
+
+	return r.db.WithContext(ctx).Create(run).Error
+}
+
+func (r *replayRepository) Update(ctx context.Context, run *entity.ReplayRun) error {
+	funcName := "ReplayRepository.Update"
+	if err := helper.CheckDeadline(ctx); err != nil {
+		return errwrap.Wrap(err, funcName)
+	}
+
+	return r.db.WithContext(ctx).Save(run).Error
+}
+
+func (r *replayRepository) FindByID(ctx context.Context, id int64) (*entity.ReplayRun, error) {
+	funcName := "ReplayRepository.FindByID"
+	if err := helper.CheckDeadline(ctx); err != nil {
+		return nil, errwrap.Wrap(err, funcName)
+	}
+
+	var run entity.ReplayRun
+	err := r.db.WithContext(ctx).First(&run, id).Error
+	if err != nil {
+		if errwrap.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, errwrap.Wrap(err, funcName)
+	}
+	return &run, nil
+}
+
+// FindBySourceConnectionID lists the latest runs without their fingerprint details
+func (r *replayRepository) FindBySourceConnectionID(ctx context.Context, connectionID int64, limit int) ([]*entity.ReplayRun, error) {
+	funcName := "ReplayRepository.FindBySourceConnectionID"
+	if err := helper.CheckDeadline(ctx); err != nil {
+		return nil, errwrap.Wrap(err, funcName)
+	}
+
+	var runs []*entity.ReplayRun
+	err := r.db.WithContext(ctx).
+		Omit("fingerprints").
+		Where("source_connection_id = ?", connectionID).
+		Order("started_at desc").
+		Limit(limit).
+		Find(&runs).Error
+	if err != nil {
+		return nil, errwrap.Wrap(err, funcName)
+	}
+	return runs, nil
+}
+
+func (r *replayRepository) Delete(ctx context.Context, id int64) error {
+	funcName := "ReplayRepository.Delete"
+	if err := helper.CheckDeadline(ctx); err != nil {
+		return errwrap.Wrap(err, funcName)
+	}
+
+	return r.db.WithContext(ctx).Delete(&entity.ReplayRun{}, id).Error
+}
@@ -0,0 +1,148 @@
+package sqlite
+
+import (
+	"context"
+	"strings"
+
+	errwrap "github.com/pkg/errors"
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/helper"
+	"gorm.io/gorm"
+)
+
+type SavedQueryRepository interface {
+	Create(ctx context.Context, query *entity.SavedQuery) error
+	Update(ctx context.Context, query *entity.SavedQuery) error
+	FindByID(ctx context.Context, id int64) (*entity.SavedQuery, error)
+	// FindVisible returns the queries of the connection and those usable on any connection
+	FindVisible(ctx context.Context, connectionID int64, filter entity.SavedQueryFilter) ([]*entity.SavedQuery, error)
+	// Import saves the queries in one transaction. A query replaces the connection's own query with the same
+	// title in the same folder, keeping its ID and creation, the others are created.
+	Import(ctx context.Context, connectionID int64, queries []*entity.SavedQuery) (*entity.SavedQueryImportResult, error)
+	Delete(ctx context.Context, id int64) error
+}
+
+type savedQueryRepository struct {
+	db *gorm.DB
+}
+
+func NewSavedQueryRepository(db *gorm.DB) SavedQueryRepository {
+	return &savedQueryRepository{db: db}
+}
+
+func (r *savedQueryRepository) Create(ctx context.Context, query *entity.SavedQuery) error {
+	funcName := "SavedQueryRepository.Create"
+	if err := helper.CheckDeadline(ctx); err != nil {
+		return errwrap.Wrap(err, funcName)
+	}
+
+	return r.db.WithContext(ctx).Create(query).Error
+}
+
+func (r *savedQueryRepository) Update(ctx context.Context, query *entity.SavedQuery) error {
+	funcName := "SavedQueryRepository.Update"
+	if err := helper.CheckDeadline(ctx); err != nil {
+		return errwrap.Wrap(err, funcName)
+	}
+
+	return r.db.WithContext(ctx).Save(query).Error
+}
+
+func (r *savedQueryRepository) FindByID(ctx context.Context, id int64) (*entity.SavedQuery, error) {
+	funcName := "SavedQueryRepository.FindByID"
+	if err := helper.CheckDeadline(ctx); err != nil {
+		return nil, errwrap.Wrap(err, funcName)
+	}
+
+	var query entity.SavedQuery
+	err := r.db.WithContext(ctx).First(&query, id).Error
+	if err != nil {
+		if errwrap.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, errwrap.Wrap(err, funcName)
+	}
+	return &query, nil
+}
+
+func (r *savedQueryRepository) FindVisible(ctx context.Context, connectionID int64, filter entity.SavedQueryFilter) ([]*entity.SavedQuery, error) {
+	funcName := "SavedQueryRepository.FindVisible"
+	if err := helper.CheckDeadline(ctx); err != nil {
+		return nil, errwrap.Wrap(err, funcName)
+	}
+
+	query := r.db.WithContext(ctx).Where("(connection_id = ? OR any_connection = ?)", connectionID, true)
+	if filter.Folder != "" {
+		query = query.Where(`(folder = ? OR folder LIKE ? ESCAPE '\')`, filter.Folder, EscapeLike(filter.Folder)+"/%")
+	}
+	if filter.Tag != "" {
+		query = query.Where("EXISTS (SELECT 1 FROM json_each(saved_queries.tags) WHERE json_each.value = ?)", filter.Tag)
+	}
+	for _, term := range strings.Fields(filter.Search) {
+		pattern := "%" + EscapeLike(term) + "%"
+		query = query.Where(`(title LIKE ? ESCAPE '\' OR description LIKE ? ESCAPE '\' OR query LIKE ? ESCAPE '\')`, pattern, pattern, pattern)
+	}
+
+	var queries []*entity.SavedQuery
+	err := query.
+		Order("folder asc, title asc").
+		Find(&queries).Error
+	if err != nil {
+		return nil, errwrap.Wrap(err, funcName)
+	}
+	return queries, nil
+}
+
+func (r *savedQueryRepository) Import(ctx context.Context, connectionID int64, queries []*entity.SavedQuery) (*entity.SavedQueryImportResult, error) {
+	funcName := "SavedQueryRepository.Import"
+	if err := helper.CheckDeadline(ctx); err != nil {
+		return nil, errwrap.Wrap(err, funcName)
+	}
+
+	result := &entity.SavedQueryImportResult{}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, query := range queries {
+			// Shared queries of other connections are never replaced, they are not the connection's to change
+			var existing []*entity.SavedQuery
+			err := tx.
+				Where("connection_id = ? AND folder = ? AND title = ?", connectionID, query.Folder, query.Title).
+				Order("id asc").
+				Limit(1).
+				Find(&existing).Error
+			if err != nil {
+				return err
+			}
+
+			query.ConnectionID = connectionID
+			if len(existing) == 0 {
+				if err := tx.Create(query).Error; err != nil {
+					return err
+				}
+				result.Created++
+				continue
+			}
+
+			query.ID = existing[0].ID
+			query.CreatedBy = existing[0].CreatedBy
+			query.CreatedAt = existing[0].CreatedAt
+			if err := tx.Save(query).Error; err != nil {
+				return err
+			}
+			result.Updated++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errwrap.Wrap(err, funcName)
+	}
+	return result, nil
+}
+
+func (r *savedQueryRepository) Delete(ctx context.Context, id int64) error {
+	funcName := "SavedQueryRepository.Delete"
+	if err := helper.CheckDeadline(ctx); err != nil {
+		return errwrap.Wrap(err, funcName)
+	}
+
+	return r.db.WithContext(ctx).Delete(&entity.SavedQuery{}, id).Error
+}
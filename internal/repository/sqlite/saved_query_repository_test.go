@@ -0,0 +1,112 @@
+package sqlite_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gormsqlite "gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newSavedQueryRepository(t *testing.T) sqlite.SavedQueryRepository {
+	db, err := gorm.Open(gormsqlite.Open(filepath.Join(t.TempDir(), "saved.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&entity.SavedQuery{}))
+
+	return sqlite.NewSavedQueryRepository(db)
+}
+
+func TestSavedQueryFindVisible(t *testing.T) {
+	ctx := context.Background()
+	repo := newSavedQueryRepository(t)
+
+	for _, q := range []*entity.SavedQuery{
+		{ConnectionID: 1, Title: "Daily events", Query: "SELECT * FROM events", Folder: "reports/daily", Tags: []string{"events"}},
+		{ConnectionID: 1, Title: "Weekly", Query: "SELECT 1", Folder: "reports_old", Tags: []string{"weekly"}},
+		{ConnectionID: 2, Title: "Shared disk usage", Query: "SELECT * FROM system.disks", Folder: "reports", AnyConnection: true},
+		{ConnectionID: 2, Title: "Private", Query: "SELECT * FROM events"},
+	} {
+		require.NoError(t, repo.Create(ctx, q))
+	}
+
+	testcases := []struct {
+		name   string
+		filter entity.SavedQueryFilter
+		want   []string
+	}{
+		{name: "All Visible", want: []string{"Shared disk usage", "Daily events", "Weekly"}},
+		{name: "Folder And Subfolders", filter: entity.SavedQueryFilter{Folder: "reports"}, want: []string{"Shared disk usage", "Daily events"}},
+		{name: "Tag", filter: entity.SavedQueryFilter{Tag: "events"}, want: []string{"Daily events"}},
+		{name: "Search SQL", filter: entity.SavedQueryFilter{Search: "events"}, want: []string{"Daily events"}},
+		{name: "Search Every Term", filter: entity.SavedQueryFilter{Search: "disk system"}, want: []string{"Shared disk usage"}},
+		{name: "Wildcards Are Literal", filter: entity.SavedQueryFilter{Search: "%"}, want: nil},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			queries, err := repo.FindVisible(ctx, 1, tc.filter)
+			require.NoError(t, err)
+
+			var titles []string
+			for _, q := range queries {
+				titles = append(titles, q.Title)
+			}
+			assert.Equal(t, tc.want, titles)
+		})
+	}
+}
+
+func TestSavedQueryImport(t *testing.T) {
+	ctx := context.Background()
+	repo := newSavedQueryRepository(t)
+
+	shared := &entity.SavedQuery{ConnectionID: 2, Title: "Top", Query: "SELECT 2", AnyConnection: true}
+	own := &entity.SavedQuery{ConnectionID: 1, Title: "Top", Query: "SELECT 1", CreatedBy: "alice"}
+	require.NoError(t, repo.Create(ctx, shared))
+	require.NoError(t, repo.Create(ctx, own))
+
+	result, err := repo.Import(ctx, 1, []*entity.SavedQuery{
+		{Title: "Top", Query: "SELECT 10", CreatedBy: "bob"},
+		{Title: "Top", Folder: "other", Query: "SELECT 11"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &entity.SavedQueryImportResult{Created: 1, Updated: 1}, result)
+
+	replaced, err := repo.FindByID(ctx, own.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT 10", replaced.Query)
+	assert.Equal(t, "alice", replaced.CreatedBy)
+
+	// A connection importing the title of another connection's shared query gets its own copy
+	result, err = repo.Import(ctx, 3, []*entity.SavedQuery{{Title: "Top", Query: "SELECT 30"}})
+	require.NoError(t, err)
+	assert.Equal(t, &entity.SavedQueryImportResult{Created: 1}, result)
+
+	unchanged, err := repo.FindByID(ctx, shared.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT 2", unchanged.Query)
+}
+
+func TestSavedQueryImportRollsBack(t *testing.T) {
+	ctx := context.Background()
+	repo := newSavedQueryRepository(t)
+
+	other := &entity.SavedQuery{ConnectionID: 2, Title: "Other", Query: "SELECT 2"}
+	require.NoError(t, repo.Create(ctx, other))
+
+	// The second query reuses the ID of another query, the first must not be kept
+	_, err := repo.Import(ctx, 1, []*entity.SavedQuery{
+		{Title: "First", Query: "SELECT 1"},
+		{ID: other.ID, Title: "Second", Query: "SELECT 1"},
+	})
+	assert.Error(t, err)
+
+	queries, err := repo.FindVisible(ctx, 1, entity.SavedQueryFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, queries)
+}
@@ -0,0 +1,172 @@
+package sqlite
+
+import (
+	"context"
+
+	errwrap "github.com/pkg/errors"
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/helper"
+	"gorm.io/gorm"
+)
+
+type SuiteRepository interface {
+	Create(ctx context.Context, suite *entity.ComparisonSuite) error
+	Update(ctx context.Context, suite *entity.ComparisonSuite) error
+	FindByID(ctx context.Context, id int64) (*entity.ComparisonSuite, error)
+	FindAllByConnectionID(ctx context.Context, connectionID int64) ([]*entity.ComparisonSuite, error)
+	FindScheduled(ctx context.Context) ([]*entity.ComparisonSuite, error)
+	Delete(ctx context.Context, id int64) error
+
+	CreateRun(ctx context.Context, run *entity.ComparisonSuiteRun) error
+	UpdateRun(ctx context.Context, run *entity.ComparisonSuiteRun) error
+	FindRunByID(ctx context.Context, id int64) (*entity.ComparisonSuiteRun, error)
+	FindRunsBySuiteID(ctx context.Context, suiteID int64, limit int) ([]*entity.ComparisonSuiteRun, error)
+}
+
+type suiteRepository struct {
+	db *gorm.DB
+}
+
+func NewSuiteRepository(db *gorm.DB) SuiteRepository {
+	return &suiteRepository{db: db}
+}
+
+func (r *suiteRepository) Create(ctx context.Context, suite *entity.ComparisonSuite) error {
+	funcName := "SuiteRepository.Create"
+	if err := helper.CheckDeadline(ctx); err != nil {
+		return errwrap.Wrap(err, funcName)
+	}
+
+	return r.db.WithContext(ctx).Create(suite).Error
+}
+
+func (r *suiteRepository) Update(ctx context.Context, suite *entity.ComparisonSuite) error {
+	funcName := "SuiteRepository.Update"
+	if err := helper.CheckDeadline(ctx); err != nil {
+		return errwrap.Wrap(err, funcName)
+	}
+
+	return r.db.WithContext(ctx).Save(suite).Error
+}
+
+func (r *suiteRepository) FindByID(ctx context.Context, id int64) (*entity.ComparisonSuite, error) {
+	funcName := "SuiteRepository.FindByID"
+	if err := helper.CheckDeadline(ctx); err != nil {
+		return nil, errwrap.Wrap(err, funcName)
+	}
+
+	var suite entity.ComparisonSuite
+	err := r.db.WithContext(ctx).First(&suite, id).Error
+	if err != nil {
+		if errwrap.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, errwrap.Wrap(err, funcName)
+	}
+	return &suite, nil
+}
+
+func (r *suiteRepository) FindAllByConnectionID(ctx context.Context, connectionID int64) ([]*entity.ComparisonSuite, error) {
+	funcName := "SuiteRepository.FindAllByConnectionID"
+	if err := helper.CheckDeadline(ctx); err != nil {
+		return nil, errwrap.Wrap(err, funcName)
+	}
+
+	var suites []*entity.ComparisonSuite
+	err := r.db.WithContext(ctx).
+		Where("connection_id = ?", connectionID).
+		Order("created_at desc").
+		Find(&suites).Error
+	if err != nil {
+		return nil, errwrap.Wrap(err, funcName)
+	}
+	return suites, nil
+}
+
+func (r *suiteRepository) FindScheduled(ctx context.Context) ([]*entity.ComparisonSuite, error) {
+	funcName := "SuiteRepository.FindScheduled"
+	if err := helper.CheckDeadline(ctx); err != nil {
+		return nil, errwrap.Wrap(err, funcName)
+	}
+
+	var suites []*entity.ComparisonSuite
+	err := r.db.WithContext(ctx).
+		Where("schedule_interval_minutes > 0").
+		Find(&suites).Error
+	if err != nil {
+		return nil, errwrap.Wrap(err, funcName)
+	}
+	return suites, nil
+}
+
+func (r *suiteRepository) Delete(ctx context.Context, id int64) error {
+	funcName := "SuiteRepository.Delete"
+	if err := helper.CheckDeadline(ctx); err != nil {
+		return errwrap.Wrap(err, funcName)
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		runIDs := tx.Model(&entity.ComparisonSuiteRun{}).Select("id").Where("suite_id = ?", id)
+		if err := tx.Where("run_id IN (?)", runIDs).Delete(&entity.ComparisonSuiteRunResult{}).Error; err != nil {
+			return errwrap.Wrap(err, funcName)
+		}
+		if err := tx.Where("suite_id = ?", id).Delete(&entity.ComparisonSuiteRun{}).Error; err != nil {
+			return errwrap.Wrap(err, funcName)
+		}
+		return tx.Delete(&entity.ComparisonSuite{}, id).Error
+	})
+}
+
+func (r *suiteRepository) CreateRun(ctx context.Context, run *entity.ComparisonSuiteRun) error {
+	funcName := "SuiteRepository.CreateRun"
+	if err := helper.CheckDeadline(ctx); err != nil {
+		return errwrap.Wrap(err, funcName)
+	}
+
+	return r.db.WithContext(ctx).Create(run).Error
+}
+
+// UpdateRun saves the run together with its results
+func (r *suiteRepository) UpdateRun(ctx context.Context, run *entity.ComparisonSuiteRun) error {
+	funcName := "SuiteRepository.UpdateRun"
+	if err := helper.CheckDeadline(ctx); err != nil {
+		return errwrap.Wrap(err, funcName)
+	}
+
+	return r.db.WithContext(ctx).Session(&gorm.Session{FullSaveAssociations: true}).Save(run).Error
+}
+
+func (r *suiteRepository) FindRunByID(ctx context.Context, id int64) (*entity.ComparisonSuiteRun, error) {
+	funcName := "SuiteRepository.FindRunByID"
+	if err := helper.CheckDeadline(ctx); err != nil {
+		return nil, errwrap.Wrap(err, funcName)
+	}
+
+	var run entity.ComparisonSuiteRun
+	err := r.db.WithContext(ctx).Preload("Results").First(&run, id).Error
+	if err != nil {
+		if errwrap.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, errwrap.Wrap(err, funcName)
+	}
+	return &run, nil
+}
+
+func (r *suiteRepository) FindRunsBySuiteID(ctx context.Context, suiteID int64, limit int) ([]*entity.ComparisonSuiteRun, error) {
+	funcName := "SuiteRepository.FindRunsBySuiteID"
+	if err := helper.CheckDeadline(ctx); err != nil {
+		return nil, errwrap.Wrap(err, funcName)
+	}
+
+	var runs []*entity.ComparisonSuiteRun
+	err := r.db.WithContext(ctx).
+		Where("suite_id = ?", suiteID).
+		Order("started_at desc").
+		Limit(limit).
+		Find(&runs).Error
+	if err != nil {
+		return nil, errwrap.Wrap(err, funcName)
+	}
+	return runs, nil
+}
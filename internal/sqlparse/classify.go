@@ -0,0 +1,168 @@
+package sqlparse
+
+import "strings"
+
+// Kind is the kind of a statement
+type Kind string
+
+const (
+	KindSelect Kind = "select"
+	KindInsert Kind = "insert"
+	// KindDDL changes the schema or the access rights: CREATE, ALTER, DROP, TRUNCATE, RENAME, GRANT ...
+	KindDDL Kind = "ddl"
+	// KindMutation rewrites existing rows: ALTER TABLE ... UPDATE/DELETE and lightweight DELETE/UPDATE
+	KindMutation Kind = "mutation"
+	KindSystem   Kind = "system"
+	KindKill     Kind = "kill"
+	KindSet      Kind = "set"
+	KindUse      Kind = "use"
+	// KindShow describes the server without reading tables: SHOW, DESCRIBE, EXPLAIN, EXISTS and CHECK
+	KindShow Kind = "show"
+	// KindOther is any other statement, e.g. OPTIMIZE, BACKUP or WATCH
+	KindOther Kind = "other"
+)
+
+// statementKinds maps leading keywords to the kind of their statements, ALTER is told apart by its commands
+var statementKinds = map[string]Kind{
+	"SELECT":   KindSelect,
+	"WITH":     KindSelect,
+	"FROM":     KindSelect,
+	"(":        KindSelect,
+	"INSERT":   KindInsert,
+	"CREATE":   KindDDL,
+	"ALTER":    KindDDL,
+	"REPLACE":  KindDDL,
+	"DROP":     KindDDL,
+	"TRUNCATE": KindDDL,
+	"RENAME":   KindDDL,
+	"ATTACH":   KindDDL,
+	"DETACH":   KindDDL,
+	"EXCHANGE": KindDDL,
+	"UNDROP":   KindDDL,
+	"GRANT":    KindDDL,
+	"REVOKE":   KindDDL,
+	"DELETE":   KindMutation,
+	"UPDATE":   KindMutation,
+	"SYSTEM":   KindSystem,
+	"KILL":     KindKill,
+	"SET":      KindSet,
+	"USE":      KindUse,
+	"SHOW":     KindShow,
+	"DESCRIBE": KindShow,
+	"DESC":     KindShow,
+	"EXPLAIN":  KindShow,
+	"EXISTS":   KindShow,
+	"CHECK":    KindShow,
+}
+
+// Statement is what the package makes of one statement
+type Statement struct {
+	Text string
+	// Keyword is the leading keyword, see LeadingKeyword
+	Keyword string
+	Kind    Kind
+	// Tables are the referenced tables and databases in order of first appearance
+	Tables []TableRef
+}
+
+// ReturnsRows reports whether the statement produces a result set rather than only a status
+func (s Statement) ReturnsRows() bool {
+	return s.Kind == KindSelect || s.Kind == KindShow
+}
+
+// Databases returns the databases the statement names explicitly, each once
+func (s Statement) Databases() []string {
+	var databases []string
+	seen := make(map[string]bool)
+	for _, ref := range s.Tables {
+		if ref.Database != "" && !seen[ref.Database] {
+			seen[ref.Database] = true
+			databases = append(databases, ref.Database)
+		}
+	}
+	return databases
+}
+
+// Parse splits the script and analyzes each of its statements
+func Parse(script string) []Statement {
+	texts := Split(script)
+	statements := make([]Statement, 0, len(texts))
+	for _, text := range texts {
+		statements = append(statements, Analyze(text))
+	}
+	return statements
+}
+
+// Analyze classifies a single statement and extracts the tables it references
+func Analyze(statement string) Statement {
+	tokens := significantTokens(statement)
+	keyword, kind := classify(tokens)
+	return Statement{
+		Text:    strings.TrimSpace(statement),
+		Keyword: keyword,
+		Kind:    kind,
+		Tables:  tables(tokens, keyword),
+	}
+}
+
+// Classify returns the kind of a single statement, empty when it holds only whitespace or comments
+func Classify(statement string) Kind {
+	_, kind := classify(significantTokens(statement))
+	return kind
+}
+
+func classify(tokens []Token) (string, Kind) {
+	if len(tokens) == 0 {
+		return "", ""
+	}
+
+	keyword := tokens[0].Text[:1]
+	if tokens[0].Kind == Word {
+		keyword = strings.ToUpper(tokens[0].Text)
+	}
+	kind, ok := statementKinds[keyword]
+	if !ok {
+		return keyword, KindOther
+	}
+	if keyword == "ALTER" && alterRewritesRows(tokens) {
+		kind = KindMutation
+	}
+	return keyword, kind
+}
+
+// alterRewritesRows reports whether one of the commands of an ALTER TABLE is UPDATE or DELETE. Commands are
+// separated by commas outside parentheses and follow the table name and its ON CLUSTER clause.
+func alterRewritesRows(tokens []Token) bool {
+	i := 1
+	if i < len(tokens) && tokens[i].Is("TEMPORARY") {
+		i++
+	}
+	if i >= len(tokens) || !tokens[i].Is("TABLE") {
+		return false
+	}
+	i = skipIfExists(tokens, i+1)
+	if _, next, ok := readName(tokens, i); ok {
+		i = next
+	}
+	if i+2 < len(tokens) && tokens[i].Is("ON") && tokens[i+1].Is("CLUSTER") {
+		i += 3
+	}
+
+	depth, commandStart := 0, true
+	for ; i < len(tokens); i++ {
+		token := tokens[i]
+		switch {
+		case token.IsPunctuation("("):
+			depth++
+		case token.IsPunctuation(")"):
+			depth--
+		case depth == 0 && token.IsPunctuation(","):
+			commandStart = true
+			continue
+		case depth == 0 && commandStart && (token.Is("UPDATE") || token.Is("DELETE")):
+			return true
+		}
+		commandStart = false
+	}
+	return false
+}
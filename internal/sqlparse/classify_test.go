@@ -0,0 +1,69 @@
+package sqlparse_test
+
+import (
+	"testing"
+
+	"github.com/rahmatrdn/go-ch-manager/internal/sqlparse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify(t *testing.T) {
+	testcases := []struct {
+		name      string
+		statement string
+		want      sqlparse.Kind
+		rows      bool
+	}{
+		{name: "Select", statement: "SELECT * FROM events", want: sqlparse.KindSelect, rows: true},
+		{name: "With", statement: "WITH 1 AS x SELECT x", want: sqlparse.KindSelect, rows: true},
+		{name: "From First", statement: "FROM events SELECT count()", want: sqlparse.KindSelect, rows: true},
+		{name: "Parenthesized", statement: "(SELECT 1) UNION ALL (SELECT 2)", want: sqlparse.KindSelect, rows: true},
+		{name: "Insert", statement: "INSERT INTO events VALUES (1)", want: sqlparse.KindInsert},
+		{name: "Create", statement: "CREATE TABLE t (x UInt8) ENGINE = Memory", want: sqlparse.KindDDL},
+		{name: "Create Or Replace", statement: "REPLACE TABLE t (x UInt8) ENGINE = Memory", want: sqlparse.KindDDL},
+		{name: "Drop", statement: "-- cleanup\nDROP TABLE events", want: sqlparse.KindDDL},
+		{name: "Grant", statement: "GRANT SELECT ON db.* TO analyst", want: sqlparse.KindDDL},
+		{name: "Alter Schema", statement: "ALTER TABLE events ADD COLUMN deleted UInt8", want: sqlparse.KindDDL},
+		{name: "Alter Update", statement: "alter table events update x = 1 where 1", want: sqlparse.KindMutation},
+		{name: "Alter Delete On Cluster", statement: "ALTER TABLE db.events ON CLUSTER main DELETE WHERE id = 1", want: sqlparse.KindMutation},
+		{name: "Alter Delete After Other Command", statement: "ALTER TABLE events ADD COLUMN y UInt8, DELETE WHERE y = 0", want: sqlparse.KindMutation},
+		{name: "Alter Keyword In Literal", statement: "ALTER TABLE events COMMENT COLUMN x 'DELETE me'", want: sqlparse.KindDDL},
+		{name: "Alter TTL Delete", statement: "ALTER TABLE events MODIFY TTL d + INTERVAL 1 DAY DELETE", want: sqlparse.KindDDL},
+		{name: "Alter Column Named Update", statement: "ALTER TABLE events ADD COLUMN `update` DateTime", want: sqlparse.KindDDL},
+		{name: "Lightweight Delete", statement: "DELETE FROM events WHERE id = 1", want: sqlparse.KindMutation},
+		{name: "Lightweight Update", statement: "UPDATE events SET x = 1 WHERE id = 1", want: sqlparse.KindMutation},
+		{name: "System", statement: "SYSTEM DROP MARK CACHE", want: sqlparse.KindSystem},
+		{name: "Kill", statement: "KILL QUERY WHERE query_id = 'x'", want: sqlparse.KindKill},
+		{name: "Set", statement: "SET max_threads = 1", want: sqlparse.KindSet},
+		{name: "Use", statement: "USE analytics", want: sqlparse.KindUse},
+		{name: "Show", statement: "SHOW TABLES", want: sqlparse.KindShow, rows: true},
+		{name: "Describe Short", statement: "DESC system.one", want: sqlparse.KindShow, rows: true},
+		{name: "Explain Drop", statement: "EXPLAIN AST DROP TABLE events", want: sqlparse.KindShow, rows: true},
+		{name: "Optimize", statement: "OPTIMIZE TABLE events FINAL", want: sqlparse.KindOther},
+		{name: "Drop After Hash Comment", statement: "# '\nDROP TABLE t", want: sqlparse.KindDDL},
+		{name: "Drop After Shebang Comment", statement: "#!/usr/bin/env clickhouse-client '\nDROP TABLE t", want: sqlparse.KindDDL},
+		{name: "Comment Only", statement: "-- nothing", want: ""},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, sqlparse.Classify(tc.statement))
+			assert.Equal(t, tc.rows, sqlparse.Analyze(tc.statement).ReturnsRows())
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	statements := sqlparse.Parse("USE logs;\n-- load\nINSERT INTO raw SELECT * FROM staging.raw; SELECT 1")
+
+	assert.Equal(t, []sqlparse.Statement{
+		{Text: "USE logs", Keyword: "USE", Kind: sqlparse.KindUse, Tables: []sqlparse.TableRef{{Database: "logs"}}},
+		{
+			Text:    "-- load\nINSERT INTO raw SELECT * FROM staging.raw",
+			Keyword: "INSERT",
+			Kind:    sqlparse.KindInsert,
+			Tables:  []sqlparse.TableRef{{Table: "raw"}, {Database: "staging", Table: "raw"}},
+		},
+		{Text: "SELECT 1", Keyword: "SELECT", Kind: sqlparse.KindSelect},
+	}, statements)
+}
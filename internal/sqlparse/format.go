@@ -0,0 +1,38 @@
+package sqlparse
+
+import "strings"
+
+// StripFormat removes the FORMAT clause ending a query, which only a SETTINGS clause and semicolons may
+// follow. It returns the query without the clause and the name of the format, or the query unchanged and an
+// empty name when there is none. A FORMAT inside parentheses belongs to a subquery and is left alone.
+func StripFormat(query string) (string, string) {
+	tokens := Tokenize(query)
+	depth := 0
+	for i, token := range tokens {
+		switch {
+		case token.IsPunctuation("("):
+			depth++
+		case token.IsPunctuation(")"):
+			depth--
+		case depth == 0 && token.Is("FORMAT"):
+			name := nextSignificant(tokens, i+1)
+			if name == len(tokens) || tokens[name].Kind != Word && tokens[name].Kind != QuotedIdentifier {
+				continue
+			}
+			if next := nextSignificant(tokens, name+1); next < len(tokens) && !tokens[next].IsPunctuation(";") && !tokens[next].Is("SETTINGS") {
+				continue
+			}
+			end := tokens[name].Pos + len(tokens[name].Text)
+			return strings.TrimSpace(query[:token.Pos] + query[end:]), tokens[name].Name()
+		}
+	}
+	return query, ""
+}
+
+// nextSignificant returns the index of the first significant token at or after i, len(tokens) when there is none
+func nextSignificant(tokens []Token, i int) int {
+	for i < len(tokens) && !tokens[i].Significant() {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,35 @@
+package sqlparse_test
+
+import (
+	"testing"
+
+	"github.com/rahmatrdn/go-ch-manager/internal/sqlparse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStripFormat(t *testing.T) {
+	testcases := []struct {
+		name       string
+		query      string
+		want       string
+		wantFormat string
+	}{
+		{name: "No Format", query: "SELECT 1", want: "SELECT 1"},
+		{name: "Trailing Format", query: "SELECT 1 FORMAT JSONEachRow", want: "SELECT 1", wantFormat: "JSONEachRow"},
+		{name: "Before Semicolon", query: "SELECT 1\nformat CSV;", want: "SELECT 1\n;", wantFormat: "CSV"},
+		{name: "Before Settings", query: "SELECT 1 FORMAT TSV SETTINGS max_threads = 1", want: "SELECT 1  SETTINGS max_threads = 1", wantFormat: "TSV"},
+		{name: "After Comment", query: "SELECT 1 -- FORMAT CSV\nFORMAT `Pretty`", want: "SELECT 1 -- FORMAT CSV", wantFormat: "Pretty"},
+		{name: "Subquery Format Is Kept", query: "SELECT * FROM (SELECT 1 FORMAT CSV)", want: "SELECT * FROM (SELECT 1 FORMAT CSV)"},
+		{name: "Format Function", query: "SELECT format('{}', 1)", want: "SELECT format('{}', 1)"},
+		{name: "Column Named Format", query: "SELECT format FROM t", want: "SELECT format FROM t"},
+		{name: "Format In Literal", query: "SELECT 'FORMAT CSV'", want: "SELECT 'FORMAT CSV'"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, format := sqlparse.StripFormat(tc.query)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantFormat, format)
+		})
+	}
+}
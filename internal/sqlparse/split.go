@@ -0,0 +1,57 @@
+package sqlparse
+
+import "strings"
+
+// Split splits a script on the semicolons that end its statements. Semicolons inside literals, quoted
+// identifiers and comments are kept, and chunks holding only whitespace or comments are dropped. Each
+// statement keeps its leading comments.
+func Split(script string) []string {
+	var statements []string
+	start, significant := 0, false
+
+	add := func(end int) {
+		if significant {
+			statements = append(statements, strings.TrimSpace(script[start:end]))
+		}
+	}
+
+	for _, token := range Tokenize(script) {
+		if token.IsPunctuation(";") {
+			add(token.Pos)
+			start, significant = token.Pos+1, false
+			continue
+		}
+		significant = significant || token.Significant()
+	}
+	add(len(script))
+
+	return statements
+}
+
+// significantTokens returns the tokens of the SQL without whitespace and comments
+func significantTokens(sql string) []Token {
+	var tokens []Token
+	for _, token := range Tokenize(sql) {
+		if token.Significant() {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// LeadingKeyword returns the upper-cased first word of a statement after any comments, or "(" for a
+// parenthesized query. For a statement starting with anything else it is that first character, and it
+// is empty when the statement holds only whitespace or comments.
+func LeadingKeyword(statement string) string {
+	for _, token := range Tokenize(statement) {
+		switch {
+		case !token.Significant():
+			continue
+		case token.Kind == Word:
+			return strings.ToUpper(token.Text)
+		default:
+			return token.Text[:1]
+		}
+	}
+	return ""
+}
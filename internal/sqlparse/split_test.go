@@ -0,0 +1,83 @@
+package sqlparse_test
+
+import (
+	"testing"
+
+	"github.com/rahmatrdn/go-ch-manager/internal/sqlparse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplit(t *testing.T) {
+	testcases := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{
+			name:   "Single Without Semicolon",
+			script: "SELECT 1",
+			want:   []string{"SELECT 1"},
+		},
+		{
+			name:   "Several Statements",
+			script: "CREATE TABLE t (x UInt8) ENGINE = Memory;\nINSERT INTO t VALUES (1);\nSELECT * FROM t;",
+			want: []string{
+				"CREATE TABLE t (x UInt8) ENGINE = Memory",
+				"INSERT INTO t VALUES (1)",
+				"SELECT * FROM t",
+			},
+		},
+		{
+			name:   "Semicolons In Literals",
+			script: `SELECT 'a;b', "c;d", ` + "`e;f`" + `; SELECT 'it\'s;', 'x'';'`,
+			want:   []string{`SELECT 'a;b', "c;d", ` + "`e;f`", `SELECT 'it\'s;', 'x'';'`},
+		},
+		{
+			name:   "Semicolons In Comments",
+			script: "SELECT 1 -- first; still a comment\n; /* block; /* nested; */ comment */ SELECT 2",
+			want:   []string{"SELECT 1 -- first; still a comment", "/* block; /* nested; */ comment */ SELECT 2"},
+		},
+		{
+			name:   "Semicolon In Heredoc",
+			script: "SELECT $$a;b$$; SELECT 2",
+			want:   []string{"SELECT $$a;b$$", "SELECT 2"},
+		},
+		{
+			name:   "Empty And Comment Only Chunks",
+			script: ";; SELECT 1;\n  ;\n-- trailing comment\n/* done */",
+			want:   []string{"SELECT 1"},
+		},
+		{
+			name:   "Empty Script",
+			script: "   ",
+			want:   nil,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, sqlparse.Split(tc.script))
+		})
+	}
+}
+
+func TestLeadingKeyword(t *testing.T) {
+	testcases := []struct {
+		name      string
+		statement string
+		want      string
+	}{
+		{name: "Lower Case", statement: "select 1", want: "SELECT"},
+		{name: "Leading Comments", statement: "-- note\n/* more */ SHOW TABLES", want: "SHOW"},
+		{name: "Parenthesized", statement: "(SELECT 1) UNION ALL (SELECT 2)", want: "("},
+		{name: "Other Character", statement: "#x", want: "#"},
+		{name: "Comment Only", statement: "-- nothing", want: ""},
+		{name: "Empty", statement: "", want: ""},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, sqlparse.LeadingKeyword(tc.statement))
+		})
+	}
+}
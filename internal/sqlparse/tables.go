@@ -0,0 +1,293 @@
+package sqlparse
+
+import "strings"
+
+// TableRef is a table a statement references, or only a database when Table is empty. Database is empty
+// for tables of the current database.
+type TableRef struct {
+	Database string
+	Table    string
+}
+
+func (r TableRef) String() string {
+	switch {
+	case r.Table == "":
+		return r.Database
+	case r.Database == "":
+		return r.Table
+	}
+	return r.Database + "." + r.Table
+}
+
+// objectKeywords name the object a DDL or SHOW statement is about, the statement then references it by name
+var objectKeywords = map[string]bool{
+	"TABLE":      true,
+	"TABLES":     true,
+	"VIEW":       true,
+	"DICTIONARY": true,
+	"DATABASE":   true,
+}
+
+// objectVerbs are the words right before an object keyword when it names the object, e.g. DROP in DROP TABLE.
+// Anywhere else TABLE or DATABASE are columns, like in SELECT database, table FROM system.tables.
+var objectVerbs = map[string]bool{
+	"CREATE":       true,
+	"ALTER":        true,
+	"DROP":         true,
+	"TRUNCATE":     true,
+	"RENAME":       true,
+	"ATTACH":       true,
+	"DETACH":       true,
+	"EXCHANGE":     true,
+	"UNDROP":       true,
+	"OPTIMIZE":     true,
+	"CHECK":        true,
+	"EXISTS":       true,
+	"DESCRIBE":     true,
+	"DESC":         true,
+	"RELOAD":       true,
+	"REPLACE":      true,
+	"TEMPORARY":    true,
+	"MATERIALIZED": true,
+	"LIVE":         true,
+	"WINDOW":       true,
+	"TO":           true,
+}
+
+// clauseKeywords may follow a table in a FROM list, so they are never read as its alias
+var clauseKeywords = map[string]bool{
+	"WHERE": true, "PREWHERE": true, "GROUP": true, "ORDER": true, "LIMIT": true, "OFFSET": true, "HAVING": true,
+	"WINDOW": true, "QUALIFY": true, "SETTINGS": true, "FORMAT": true, "UNION": true, "EXCEPT": true,
+	"INTERSECT": true, "JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true, "FULL": true, "CROSS": true,
+	"OUTER": true, "SEMI": true, "ANTI": true, "ANY": true, "ALL": true, "ASOF": true, "GLOBAL": true,
+	"ARRAY": true, "PASTE": true, "ON": true, "USING": true, "FINAL": true, "SAMPLE": true, "INTO": true,
+	"LIKE": true, "ILIKE": true, "NOT": true, "IN": true, "FROM": true, "WITH": true,
+}
+
+// tableScanner collects the tables of a statement, each once and in order of first appearance
+type tableScanner struct {
+	tokens  []Token
+	keyword string
+	ctes    map[string]bool
+	seen    map[TableRef]bool
+	refs    []TableRef
+}
+
+// tables returns the tables and databases the statement references: the sources of its queries, the target
+// of an INSERT and the objects of DDL, SHOW, USE and GRANT statements. Names of common table expressions and
+// table functions are left out.
+func tables(tokens []Token, keyword string) []TableRef {
+	s := &tableScanner{
+		tokens:  tokens,
+		keyword: keyword,
+		ctes:    cteNames(tokens),
+		seen:    make(map[TableRef]bool),
+	}
+	s.leading()
+
+	// The open parentheses, true for those holding a query. Elsewhere FROM belongs to a function like
+	// extract(DAY FROM d), and GRANT and REVOKE use FROM for users.
+	queries := []bool{keyword != "GRANT" && keyword != "REVOKE"}
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		inQuery := queries[len(queries)-1]
+		switch {
+		case token.IsPunctuation("("):
+			queries = append(queries, i+1 < len(tokens) && (tokens[i+1].Is("SELECT") || tokens[i+1].Is("WITH")))
+		case token.IsPunctuation(")"):
+			if len(queries) > 1 {
+				queries = queries[:len(queries)-1]
+			}
+		case token.Kind != Word:
+		case inQuery && token.Is("FROM"):
+			i = s.sources(i+1) - 1
+		case inQuery && token.Is("JOIN") && !(i > 0 && tokens[i-1].Is("ARRAY")):
+			i = s.sources(i+1) - 1
+		case token.Is("INTO"):
+			i = s.into(i+1) - 1
+		case keyword == "SHOW" && (token.Is("TABLES") || token.Is("DICTIONARIES")):
+			if i+1 < len(tokens) && (tokens[i+1].Is("FROM") || tokens[i+1].Is("IN")) {
+				i = s.objectName(i+2, true) - 1
+			}
+		case objectKeywords[strings.ToUpper(token.Text)] && i > 0 && tokens[i-1].Kind == Word && objectVerbs[strings.ToUpper(tokens[i-1].Text)]:
+			i = s.object(i) - 1
+		case (keyword == "GRANT" || keyword == "REVOKE") && token.Is("ON") && !(i+1 < len(tokens) && tokens[i+1].Is("CLUSTER")):
+			i = s.grantTarget(i+1) - 1
+		}
+	}
+	return s.refs
+}
+
+func (s *tableScanner) add(ref TableRef) {
+	if ref.Database == "" && s.ctes[ref.Table] || s.seen[ref] {
+		return
+	}
+	s.seen[ref] = true
+	s.refs = append(s.refs, ref)
+}
+
+// leading reads the name right after the leading keyword of USE, UPDATE, DESCRIBE, EXISTS and TRUNCATE
+func (s *tableScanner) leading() {
+	if len(s.tokens) < 2 {
+		return
+	}
+	next := strings.ToUpper(s.tokens[1].Text)
+	if objectKeywords[next] || next == "TEMPORARY" || next == "ALL" {
+		return
+	}
+
+	switch s.keyword {
+	case "USE":
+		s.objectName(1, true)
+	case "UPDATE", "TRUNCATE":
+		s.objectName(1, false)
+	case "DESCRIBE", "DESC", "EXISTS":
+		s.source(1)
+	}
+}
+
+// sources reads a FROM list or a joined table: tables separated by commas, each with an optional alias
+// and FINAL. It stops at subqueries and table functions, their parentheses are scanned like the rest.
+func (s *tableScanner) sources(i int) int {
+	for {
+		next, ok := s.source(i)
+		if !ok {
+			return i
+		}
+		i = next
+
+		if i < len(s.tokens) && s.tokens[i].Is("AS") {
+			i++
+		}
+		if i < len(s.tokens) && isName(s.tokens[i]) && !clauseKeywords[strings.ToUpper(s.tokens[i].Text)] {
+			i++
+		}
+		if i < len(s.tokens) && s.tokens[i].Is("FINAL") {
+			i++
+		}
+		if i >= len(s.tokens) || !s.tokens[i].IsPunctuation(",") {
+			return i
+		}
+		i++
+	}
+}
+
+// source adds the table at i and returns the offset after its name, false for a subquery, a table function
+// or anything else that is not a table name
+func (s *tableScanner) source(i int) (int, bool) {
+	if i < len(s.tokens) && (s.tokens[i].Is("SELECT") || s.tokens[i].Is("WITH") || s.tokens[i].Is("INFILE")) {
+		return i, false
+	}
+	ref, next, ok := readName(s.tokens, i)
+	if !ok || next < len(s.tokens) && s.tokens[next].IsPunctuation("(") {
+		return i, false
+	}
+	s.add(ref)
+	return next, true
+}
+
+// into reads the target of INSERT INTO [TABLE], INTO OUTFILE and INTO FUNCTION have none
+func (s *tableScanner) into(i int) int {
+	if i < len(s.tokens) && (s.tokens[i].Is("OUTFILE") || s.tokens[i].Is("FUNCTION")) {
+		return i
+	}
+	if i < len(s.tokens) && s.tokens[i].Is("TABLE") {
+		i++
+	}
+	return s.objectName(i, false)
+}
+
+// object reads the names after an object keyword: the object itself, the other side of RENAME ... TO and
+// EXCHANGE ... AND, the target of CREATE VIEW ... TO and the table CREATE TABLE ... AS copies
+func (s *tableScanner) object(i int) int {
+	database := s.tokens[i].Is("DATABASE")
+	i = s.objectName(skipIfExists(s.tokens, i+1), database)
+
+	for i < len(s.tokens) {
+		token := s.tokens[i]
+		switch {
+		case token.Is("ON") && i+2 < len(s.tokens) && s.tokens[i+1].Is("CLUSTER"):
+			i += 3
+		case token.Is("TO") || token.Is("AND") || token.IsPunctuation(","):
+			i = s.objectName(i+1, database)
+		case token.Is("AS") && !database:
+			s.source(i + 1)
+			return i + 1
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// objectName adds the table or, with database set, the database named at i and returns the offset after it
+func (s *tableScanner) objectName(i int, database bool) int {
+	ref, next, ok := readName(s.tokens, i)
+	if !ok {
+		return i
+	}
+	if database {
+		ref = TableRef{Database: ref.Table}
+	}
+	s.add(ref)
+	return next
+}
+
+// grantTarget reads the target of GRANT ... ON: db.table, db.* or a table of the current database. *.* and
+// * name no database.
+func (s *tableScanner) grantTarget(i int) int {
+	if i >= len(s.tokens) || !isName(s.tokens[i]) {
+		return i
+	}
+	if i+2 < len(s.tokens) && s.tokens[i+1].IsPunctuation(".") && s.tokens[i+2].IsPunctuation("*") {
+		s.add(TableRef{Database: s.tokens[i].Name()})
+		return i + 3
+	}
+	return s.objectName(i, false)
+}
+
+// readName reads a table name at i, optionally qualified with its database, and returns the offset after it
+func readName(tokens []Token, i int) (TableRef, int, bool) {
+	if i >= len(tokens) || !isName(tokens[i]) {
+		return TableRef{}, i, false
+	}
+	if i+2 < len(tokens) && tokens[i+1].IsPunctuation(".") && isName(tokens[i+2]) {
+		return TableRef{Database: tokens[i].Name(), Table: tokens[i+2].Name()}, i + 3, true
+	}
+	return TableRef{Table: tokens[i].Name()}, i + 1, true
+}
+
+func isName(token Token) bool {
+	return token.Kind == Word || token.Kind == QuotedIdentifier
+}
+
+// skipIfExists returns the offset after IF EXISTS or IF NOT EXISTS at i, i when there is none
+func skipIfExists(tokens []Token, i int) int {
+	if i >= len(tokens) || !tokens[i].Is("IF") {
+		return i
+	}
+	if i+1 < len(tokens) && tokens[i+1].Is("NOT") {
+		i++
+	}
+	if i+1 < len(tokens) && tokens[i+1].Is("EXISTS") {
+		return i + 2
+	}
+	return i
+}
+
+// cteNames returns the names of the common table expressions, WITH name AS (SELECT ...)
+func cteNames(tokens []Token) map[string]bool {
+	names := make(map[string]bool)
+	for i := 1; i+3 < len(tokens); i++ {
+		if !isName(tokens[i]) || !tokens[i+1].Is("AS") || !tokens[i+2].IsPunctuation("(") {
+			continue
+		}
+		if !tokens[i-1].Is("WITH") && !tokens[i-1].IsPunctuation(",") {
+			continue
+		}
+		if tokens[i+3].Is("SELECT") || tokens[i+3].Is("WITH") {
+			names[tokens[i].Name()] = true
+		}
+	}
+	return names
+}
@@ -0,0 +1,94 @@
+package sqlparse_test
+
+import (
+	"testing"
+
+	"github.com/rahmatrdn/go-ch-manager/internal/sqlparse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTables(t *testing.T) {
+	testcases := []struct {
+		name      string
+		statement string
+		want      []string
+	}{
+		{name: "Select", statement: "SELECT * FROM events", want: []string{"events"}},
+		{name: "Qualified And Quoted", statement: "SELECT * FROM `my db`.\"my table\"", want: []string{"my db.my table"}},
+		{name: "Comma List With Aliases", statement: "SELECT * FROM a AS x, db.b y FINAL, c WHERE 1", want: []string{"a", "db.b", "c"}},
+		{name: "Joins", statement: "SELECT * FROM a LEFT JOIN db.b USING id GLOBAL ANY INNER JOIN c ON a.id = c.id", want: []string{"a", "db.b", "c"}},
+		{name: "Array Join", statement: "SELECT * FROM a ARRAY JOIN tags LEFT ARRAY JOIN nested", want: []string{"a"}},
+		{name: "Subqueries", statement: "SELECT * FROM (SELECT * FROM a) WHERE id IN (SELECT id FROM b)", want: []string{"a", "b"}},
+		{name: "Table Function", statement: "SELECT * FROM numbers(10) JOIN remote('host', db.t) USING number", want: nil},
+		{name: "Function FROM", statement: "SELECT extract(DAY FROM d), trim(BOTH ' ' FROM s) FROM t", want: []string{"t"}},
+		{name: "Common Table Expressions", statement: "WITH recent AS (SELECT * FROM events), x AS (SELECT 1) SELECT * FROM recent JOIN db.recent USING id", want: []string{"events", "db.recent"}},
+		{name: "Columns Named Like Keywords", statement: "SELECT database, table FROM system.tables WHERE table = 'x'", want: []string{"system.tables"}},
+		{name: "Duplicates", statement: "SELECT * FROM a JOIN a USING id UNION ALL SELECT * FROM a", want: []string{"a"}},
+		{name: "Insert Values", statement: "INSERT INTO db.events (id, name) VALUES (1, 'a')", want: []string{"db.events"}},
+		{name: "Insert Table Select", statement: "INSERT INTO TABLE events SELECT * FROM staging", want: []string{"events", "staging"}},
+		{name: "Insert Function", statement: "INSERT INTO FUNCTION s3('url') SELECT * FROM events", want: []string{"events"}},
+		{name: "Insert From Infile", statement: "INSERT INTO events FROM INFILE 'data.csv' FORMAT CSV", want: []string{"events"}},
+		{name: "Into Outfile", statement: "SELECT * FROM events INTO OUTFILE 'out.csv'", want: []string{"events"}},
+		{name: "Create Table", statement: "CREATE TABLE IF NOT EXISTS db.t ON CLUSTER main (id UInt64) ENGINE = MergeTree ORDER BY id", want: []string{"db.t"}},
+		{name: "Create Table As", statement: "CREATE TABLE t AS db.src ENGINE = Memory", want: []string{"t", "db.src"}},
+		{name: "Create Table As Function", statement: "CREATE TABLE t AS remote('host', db, src)", want: []string{"t"}},
+		{name: "Create Table As Select", statement: "CREATE TABLE t ENGINE = Memory AS SELECT * FROM src", want: []string{"t", "src"}},
+		{name: "Materialized View To", statement: "CREATE MATERIALIZED VIEW db.mv TO db.target AS SELECT * FROM db.source", want: []string{"db.mv", "db.target", "db.source"}},
+		{name: "Drop Temporary", statement: "DROP TEMPORARY TABLE IF EXISTS tmp", want: []string{"tmp"}},
+		{name: "Alter Move Partition", statement: "ALTER TABLE a MOVE PARTITION 1 TO TABLE b", want: []string{"a", "b"}},
+		{name: "Alter Attach Partition From", statement: "ALTER TABLE a ATTACH PARTITION 1 FROM b", want: []string{"a", "b"}},
+		{name: "Alter Delete With Subquery", statement: "ALTER TABLE a DELETE WHERE id IN (SELECT id FROM b)", want: []string{"a", "b"}},
+		{name: "Truncate Short", statement: "TRUNCATE db.t", want: []string{"db.t"}},
+		{name: "Rename", statement: "RENAME TABLE a TO b, db.c TO db.d", want: []string{"a", "b", "db.c", "db.d"}},
+		{name: "Exchange", statement: "EXCHANGE TABLES a AND b", want: []string{"a", "b"}},
+		{name: "Dictionary", statement: "SYSTEM RELOAD DICTIONARY db.dict", want: []string{"db.dict"}},
+		{name: "Lightweight Delete", statement: "DELETE FROM db.events WHERE database = 'x'", want: []string{"db.events"}},
+		{name: "Lightweight Update", statement: "UPDATE events SET x = 1 WHERE id IN (SELECT id FROM other)", want: []string{"events", "other"}},
+		{name: "Describe", statement: "DESCRIBE TABLE db.events", want: []string{"db.events"}},
+		{name: "Describe Short", statement: "DESC events", want: []string{"events"}},
+		{name: "Describe Table Function", statement: "DESC s3('url')", want: nil},
+		{name: "Exists", statement: "EXISTS db.events", want: []string{"db.events"}},
+		{name: "Explain", statement: "EXPLAIN SYNTAX SELECT * FROM events", want: []string{"events"}},
+		{name: "Show Create", statement: "SHOW CREATE TABLE db.events", want: []string{"db.events"}},
+		{name: "Show Columns", statement: "SHOW COLUMNS FROM events", want: []string{"events"}},
+		{name: "Create Database", statement: "CREATE DATABASE IF NOT EXISTS analytics ENGINE = Atomic", want: []string{"analytics"}},
+		{name: "Rename Database", statement: "RENAME DATABASE a TO b", want: []string{"a", "b"}},
+		{name: "Use", statement: "USE analytics", want: []string{"analytics"}},
+		{name: "Show Tables From", statement: "SHOW TABLES FROM analytics LIKE 'e%'", want: []string{"analytics"}},
+		{name: "Show Temporary Tables", statement: "SHOW TEMPORARY TABLES LIKE 'x'", want: nil},
+		{name: "Grant Database", statement: "GRANT SELECT ON analytics.* TO analyst", want: []string{"analytics"}},
+		{name: "Grant Table On Cluster", statement: "GRANT ON CLUSTER main SELECT(id, name) ON db.events TO analyst", want: []string{"db.events"}},
+		{name: "Grant Everything", statement: "GRANT ALL ON *.* TO admin", want: nil},
+		{name: "Revoke", statement: "REVOKE INSERT ON db.events FROM analyst", want: []string{"db.events"}},
+		{name: "Keywords In Literals And Comments", statement: "SELECT 'FROM x' -- FROM y\nFROM /* JOIN z */ t", want: []string{"t"}},
+		{name: "No Tables", statement: "SELECT 1", want: nil},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, ref := range sqlparse.Analyze(tc.statement).Tables {
+				got = append(got, ref.String())
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestDatabases(t *testing.T) {
+	testcases := []struct {
+		name      string
+		statement string
+		want      []string
+	}{
+		{name: "Qualified Tables", statement: "SELECT * FROM a.x JOIN b.y USING id JOIN a.z USING id", want: []string{"a", "b"}},
+		{name: "Database Only", statement: "DROP DATABASE analytics", want: []string{"analytics"}},
+		{name: "Current Database", statement: "SELECT * FROM events", want: nil},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, sqlparse.Analyze(tc.statement).Databases())
+		})
+	}
+}
@@ -0,0 +1,216 @@
+// Package sqlparse understands enough ClickHouse SQL to split scripts into statements, tell what kind of
+// statement each one is and which databases and tables it references. It works on tokens rather than a
+// grammar, so it accepts any input and never fails; what it can't recognise is left to the server.
+package sqlparse
+
+import "strings"
+
+type TokenKind int
+
+const (
+	Whitespace TokenKind = iota
+	// Comment is a -- line comment, a "# " or "#!" line comment or a /* block comment */, block comments nest
+	// like in ClickHouse
+	Comment
+	// Word is a keyword or a bare identifier
+	Word
+	// QuotedIdentifier is an identifier in double quotes or backticks
+	QuotedIdentifier
+	// String is a literal in single quotes or a $tag$heredoc$tag$
+	String
+	Number
+	// Punctuation is an operator, a parenthesis, a comma, a semicolon ...
+	Punctuation
+)
+
+// Token is a piece of SQL. Text is the source text, quotes included, and Pos its byte offset in the input.
+type Token struct {
+	Kind TokenKind
+	Text string
+	Pos  int
+}
+
+// Significant reports whether the token is neither whitespace nor a comment
+func (t Token) Significant() bool {
+	return t.Kind != Whitespace && t.Kind != Comment
+}
+
+// Is reports whether the token is the keyword, ignoring case
+func (t Token) Is(keyword string) bool {
+	return t.Kind == Word && strings.EqualFold(t.Text, keyword)
+}
+
+// IsPunctuation reports whether the token is the operator or punctuation mark p
+func (t Token) IsPunctuation(p string) bool {
+	return t.Kind == Punctuation && t.Text == p
+}
+
+// Name returns the identifier of a Word or QuotedIdentifier without its quotes and escapes
+func (t Token) Name() string {
+	if t.Kind != QuotedIdentifier || len(t.Text) < 2 {
+		return t.Text
+	}
+	quote := t.Text[0]
+	inner := t.Text[1:]
+	if inner[len(inner)-1] == quote {
+		inner = inner[:len(inner)-1]
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(inner); i++ {
+		switch {
+		case inner[i] == '\\' && i+1 < len(inner):
+			i++
+		case inner[i] == quote && i+1 < len(inner) && inner[i+1] == quote:
+			i++
+		}
+		b.WriteByte(inner[i])
+	}
+	return b.String()
+}
+
+// multiCharOperators are read as one Punctuation token
+var multiCharOperators = []string{"->", "::", "<=", ">=", "!=", "<>", "==", "||"}
+
+// Tokenize splits the SQL into tokens. Unterminated literals and comments run to the end of the input.
+func Tokenize(sql string) []Token {
+	var tokens []Token
+	for i := 0; i < len(sql); {
+		kind, end := scanToken(sql, i)
+		tokens = append(tokens, Token{Kind: kind, Text: sql[i:end], Pos: i})
+		i = end
+	}
+	return tokens
+}
+
+// scanToken returns the kind of the token starting at start and the offset right after it
+func scanToken(sql string, start int) (TokenKind, int) {
+	c := sql[start]
+	switch {
+	case isSpace(c):
+		end := start
+		for end < len(sql) && isSpace(sql[end]) {
+			end++
+		}
+		return Whitespace, end
+	case strings.HasPrefix(sql[start:], "--"), strings.HasPrefix(sql[start:], "# "), strings.HasPrefix(sql[start:], "#!"):
+		if idx := strings.IndexByte(sql[start:], '\n'); idx >= 0 {
+			return Comment, start + idx
+		}
+		return Comment, len(sql)
+	case strings.HasPrefix(sql[start:], "/*"):
+		return Comment, skipBlockComment(sql, start)
+	case c == '\'':
+		return String, skipQuoted(sql, start)
+	case c == '"' || c == '`':
+		return QuotedIdentifier, skipQuoted(sql, start)
+	case c == '$':
+		if end, ok := skipHeredoc(sql, start); ok {
+			return String, end
+		}
+	case IsIdentifierChar(c, true):
+		end := start
+		for end < len(sql) && IsIdentifierChar(sql[end], false) {
+			end++
+		}
+		return Word, end
+	case c >= '0' && c <= '9' || c == '.' && start+1 < len(sql) && sql[start+1] >= '0' && sql[start+1] <= '9':
+		return Number, skipNumber(sql, start)
+	}
+
+	for _, op := range multiCharOperators {
+		if strings.HasPrefix(sql[start:], op) {
+			return Punctuation, start + len(op)
+		}
+	}
+	return Punctuation, start + 1
+}
+
+// IsIdentifierChar reports whether c can be part of a bare identifier, digits only after the first character
+func IsIdentifierChar(c byte, first bool) bool {
+	if c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' {
+		return true
+	}
+	return !first && c >= '0' && c <= '9'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+// skipQuoted returns the offset after the quote closing the literal opened at start. Backslash escapes
+// and doubled quotes stay inside the literal.
+func skipQuoted(sql string, start int) int {
+	quote := sql[start]
+	for i := start + 1; i < len(sql); i++ {
+		switch sql[i] {
+		case '\\':
+			i++
+		case quote:
+			if i+1 < len(sql) && sql[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(sql)
+}
+
+// skipBlockComment returns the offset after the */ closing the comment opened at start
+func skipBlockComment(sql string, start int) int {
+	depth := 0
+	for i := start; i < len(sql)-1; i++ {
+		switch {
+		case sql[i] == '/' && sql[i+1] == '*':
+			depth++
+			i++
+		case sql[i] == '*' && sql[i+1] == '/':
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(sql)
+}
+
+// skipHeredoc returns the offset after a $tag$...$tag$ literal starting at start, false when start does not
+// open one
+func skipHeredoc(sql string, start int) (int, bool) {
+	end := start + 1
+	for end < len(sql) && IsIdentifierChar(sql[end], end == start+1) {
+		end++
+	}
+	if end >= len(sql) || sql[end] != '$' {
+		return 0, false
+	}
+
+	tag := sql[start : end+1]
+	if idx := strings.Index(sql[end+1:], tag); idx >= 0 {
+		return end + 1 + idx + len(tag), true
+	}
+	return len(sql), true
+}
+
+// skipNumber returns the offset after the number starting at start: decimals, exponents, hex and binary
+func skipNumber(sql string, start int) int {
+	end := start
+	for end < len(sql) {
+		c := sql[end]
+		switch {
+		case c >= '0' && c <= '9' || c == '.' || c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			end++
+		case (c == '+' || c == '-') && (sql[end-1] == 'e' || sql[end-1] == 'E') && !isHex(sql[start:end]):
+			end++
+		default:
+			return end
+		}
+	}
+	return end
+}
+
+func isHex(number string) bool {
+	return len(number) > 1 && number[0] == '0' && (number[1] == 'x' || number[1] == 'X')
+}
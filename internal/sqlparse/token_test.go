@@ -0,0 +1,115 @@
+package sqlparse_test
+
+import (
+	"testing"
+
+	"github.com/rahmatrdn/go-ch-manager/internal/sqlparse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenize(t *testing.T) {
+	type tok struct {
+		Kind sqlparse.TokenKind
+		Text string
+	}
+
+	testcases := []struct {
+		name string
+		sql  string
+		want []tok
+	}{
+		{
+			name: "Words Numbers And Punctuation",
+			sql:  "SELECT a1, 2.5e-3 FROM t;",
+			want: []tok{
+				{sqlparse.Word, "SELECT"}, {sqlparse.Whitespace, " "}, {sqlparse.Word, "a1"}, {sqlparse.Punctuation, ","},
+				{sqlparse.Whitespace, " "}, {sqlparse.Number, "2.5e-3"}, {sqlparse.Whitespace, " "}, {sqlparse.Word, "FROM"},
+				{sqlparse.Whitespace, " "}, {sqlparse.Word, "t"}, {sqlparse.Punctuation, ";"},
+			},
+		},
+		{
+			name: "Hex Number Is Not An Exponent",
+			sql:  "0x1e-1",
+			want: []tok{{sqlparse.Number, "0x1e"}, {sqlparse.Punctuation, "-"}, {sqlparse.Number, "1"}},
+		},
+		{
+			name: "Quoted Literals With Escapes",
+			sql:  `'it\'s' 'a''b' "col""x" ` + "`tab`",
+			want: []tok{
+				{sqlparse.String, `'it\'s'`}, {sqlparse.Whitespace, " "}, {sqlparse.String, "'a''b'"}, {sqlparse.Whitespace, " "},
+				{sqlparse.QuotedIdentifier, `"col""x"`}, {sqlparse.Whitespace, " "}, {sqlparse.QuotedIdentifier, "`tab`"},
+			},
+		},
+		{
+			name: "Comments Nest",
+			sql:  "/* a /* b */ c */x -- end",
+			want: []tok{{sqlparse.Comment, "/* a /* b */ c */"}, {sqlparse.Word, "x"}, {sqlparse.Whitespace, " "}, {sqlparse.Comment, "-- end"}},
+		},
+		{
+			name: "Hash Line Comments",
+			sql:  "# it's\n#!x\n#y",
+			want: []tok{
+				{sqlparse.Comment, "# it's"}, {sqlparse.Whitespace, "\n"}, {sqlparse.Comment, "#!x"}, {sqlparse.Whitespace, "\n"},
+				{sqlparse.Punctuation, "#"}, {sqlparse.Word, "y"},
+			},
+		},
+		{
+			name: "Heredoc",
+			sql:  "$body$ it's; $x$ $body$",
+			want: []tok{{sqlparse.String, "$body$ it's; $x$ $body$"}},
+		},
+		{
+			name: "Multi Character Operators",
+			sql:  "a::UInt8->b!=c<=d||e",
+			want: []tok{
+				{sqlparse.Word, "a"}, {sqlparse.Punctuation, "::"}, {sqlparse.Word, "UInt8"}, {sqlparse.Punctuation, "->"},
+				{sqlparse.Word, "b"}, {sqlparse.Punctuation, "!="}, {sqlparse.Word, "c"}, {sqlparse.Punctuation, "<="},
+				{sqlparse.Word, "d"}, {sqlparse.Punctuation, "||"}, {sqlparse.Word, "e"},
+			},
+		},
+		{
+			name: "Unterminated Literal Runs To The End",
+			sql:  "SELECT 'open",
+			want: []tok{{sqlparse.Word, "SELECT"}, {sqlparse.Whitespace, " "}, {sqlparse.String, "'open"}},
+		},
+		{
+			name: "Empty",
+			sql:  "",
+			want: nil,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []tok
+			pos := 0
+			for _, token := range sqlparse.Tokenize(tc.sql) {
+				assert.Equal(t, pos, token.Pos)
+				pos += len(token.Text)
+				got = append(got, tok{token.Kind, token.Text})
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestTokenName(t *testing.T) {
+	testcases := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{name: "Bare", sql: "events", want: "events"},
+		{name: "Backticks", sql: "`my table`", want: "my table"},
+		{name: "Doubled Quote", sql: `"a""b"`, want: `a"b`},
+		{name: "Backslash Escape", sql: "`a\\`b`", want: "a`b"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokens := sqlparse.Tokenize(tc.sql)
+			assert.Len(t, tokens, 1)
+			assert.Equal(t, tc.want, tokens[0].Name())
+		})
+	}
+}
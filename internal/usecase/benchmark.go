@@ -0,0 +1,362 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/helper"
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/clickhouse"
+)
+
+// normalizeCompareOptions applies defaults and guards against unreasonable iteration counts
+func normalizeCompareOptions(opts entity.CompareOptions) (entity.CompareOptions, error) {
+	if opts.Iterations <= 0 {
+		opts.Iterations = entity.DefaultCompareIterations
+	}
+	if opts.Warmup < 0 {
+		opts.Warmup = 0
+	}
+
+	if opts.Iterations > entity.MaxCompareIterations {
+		return opts, fmt.Errorf("iterations must not exceed %d", entity.MaxCompareIterations)
+	}
+	if opts.Warmup > entity.MaxCompareWarmup {
+		return opts, fmt.Errorf("warmup must not exceed %d", entity.MaxCompareWarmup)
+	}
+	if opts.ResultCheck != "" && opts.ResultCheck != entity.ResultCheckFull && opts.ResultCheck != entity.ResultCheckHash {
+		return opts, fmt.Errorf("unknown result check mode %q", opts.ResultCheck)
+	}
+	if opts.CacheMode != "" && opts.CacheMode != entity.CacheModeCold && opts.CacheMode != entity.CacheModeColdWarm {
+		return opts, fmt.Errorf("unknown cache mode %q", opts.CacheMode)
+	}
+
+	return opts, nil
+}
+
+// normalizeVariants trims the variants, fills in missing labels, normalizes settings
+// and rejects empty queries or duplicate labels
+func normalizeVariants(variants []entity.QueryVariant) ([]entity.QueryVariant, error) {
+	if len(variants) < entity.MinCompareVariants {
+		return nil, fmt.Errorf("at least %d variants are required", entity.MinCompareVariants)
+	}
+	if len(variants) > entity.MaxCompareVariants {
+		return nil, fmt.Errorf("variants must not exceed %d", entity.MaxCompareVariants)
+	}
+
+	result := make([]entity.QueryVariant, len(variants))
+	seen := make(map[string]bool, len(variants))
+	for i, v := range variants {
+		v.Label = strings.TrimSpace(v.Label)
+		if v.Label == "" {
+			v.Label = fmt.Sprintf("Query %d", i+1)
+		}
+		if seen[v.Label] {
+			return nil, fmt.Errorf("duplicate variant label %q", v.Label)
+		}
+		seen[v.Label] = true
+
+		if strings.TrimSpace(v.Query) == "" {
+			return nil, fmt.Errorf("query of variant %q is empty", v.Label)
+		}
+
+		settings, err := clickhouse.NormalizeSettings(v.Settings)
+		if err != nil {
+			return nil, fmt.Errorf("variant %q: %w", v.Label, err)
+		}
+		v.Settings = settings
+
+		params, err := BindQueryParameters(v.Query, v.Parameters)
+		if err != nil {
+			return nil, fmt.Errorf("variant %q: %w", v.Label, err)
+		}
+		v.Parameters = params
+		result[i] = v
+	}
+
+	return result, nil
+}
+
+// checkCacheDrop refuses to drop caches on read-only connections, which the server rejects, and on PRODUCTION
+// connections unless the caller confirmed it. It returns the warning to report when it was confirmed.
+func checkCacheDrop(conn *entity.CHConnection, opts entity.CompareOptions) ([]string, error) {
+	if opts.CacheMode == "" {
+		return nil, nil
+	}
+	if conn.IsReadOnly() {
+		return nil, fmt.Errorf("connection %s is read-only: ClickHouse refuses to drop caches in readonly mode, run cold comparisons on a writable connection", conn.Name)
+	}
+	if conn.Label != entity.LabelProduction {
+		return nil, nil
+	}
+	if !opts.ConfirmCacheDrop {
+		return nil, fmt.Errorf("connection %s is labelled PRODUCTION: dropping caches slows down every query on that server, confirm the cache drop to continue", conn.Name)
+	}
+
+	return []string{fmt.Sprintf("caches were dropped on PRODUCTION connection %s", conn.Name)}, nil
+}
+
+// runBenchmark runs the warmup iterations (discarded) followed by the measured iterations,
+// applying the variant's settings and parameters to every execution and dropping caches according to the cache mode
+func runBenchmark(ctx context.Context, chClient clickhouse.ClickHouseClient, conn *entity.CHConnection, variant entity.QueryVariant, opts entity.CompareOptions) (*entity.QueryBenchmark, error) {
+	ctx = clickhouse.WithQueryParameters(clickhouse.WithQuerySettings(ctx, variant.Settings), variant.Parameters)
+	query := variant.Query
+
+	for i := 0; i < opts.Warmup; i++ {
+		if _, err := chClient.ExecuteQueryWithStats(ctx, conn, query); err != nil {
+			return nil, err
+		}
+	}
+
+	runs := make([]*entity.QueryStats, 0, opts.Iterations)
+	var coldRuns []*entity.QueryStats
+	for i := 0; i < opts.Iterations; i++ {
+		if opts.CacheMode != "" {
+			if err := chClient.DropCaches(ctx, conn); err != nil {
+				return nil, fmt.Errorf("drop caches: %w", err)
+			}
+		}
+
+		// In cold_warm mode the first run after the drop is the cold sample, the next one the warm sample
+		if opts.CacheMode == entity.CacheModeColdWarm {
+			stats, err := chClient.ExecuteQueryWithStats(ctx, conn, query)
+			if err != nil {
+				return nil, err
+			}
+			coldRuns = append(coldRuns, stats)
+		}
+
+		stats, err := chClient.ExecuteQueryWithStats(ctx, conn, query)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, stats)
+	}
+
+	bench := summarizeRuns(runs)
+	if len(coldRuns) > 0 {
+		bench.Cold = summarizeRuns(coldRuns)
+	}
+
+	return bench, nil
+}
+
+// summarizeRuns summarizes the iterations that have query_log stats, the zeros of the others would drag the
+// summaries down. When none has them, the client side durations are all there is.
+func summarizeRuns(runs []*entity.QueryStats) *entity.QueryBenchmark {
+	logged := loggedRuns(runs)
+
+	duration := make([]float64, len(logged))
+	rowsRead := make([]float64, len(logged))
+	bytesRead := make([]float64, len(logged))
+	memory := make([]float64, len(logged))
+
+	for i, r := range logged {
+		duration[i] = float64(r.ExecutionTimeMs)
+		rowsRead[i] = float64(r.RowsRead)
+		bytesRead[i] = float64(r.BytesRead)
+		memory[i] = float64(r.MemoryPeak)
+	}
+
+	return &entity.QueryBenchmark{
+		Iterations:   runs,
+		Duration:     helper.Summarize(duration),
+		RowsRead:     helper.Summarize(rowsRead),
+		BytesRead:    helper.Summarize(bytesRead),
+		Memory:       helper.Summarize(memory),
+		UnloggedRuns: len(runs) - countLogged(runs),
+	}
+}
+
+// loggedRuns returns the runs with query_log stats, or all of them when none has any
+func loggedRuns(runs []*entity.QueryStats) []*entity.QueryStats {
+	if countLogged(runs) == 0 {
+		return runs
+	}
+	logged := make([]*entity.QueryStats, 0, len(runs))
+	for _, r := range runs {
+		if !r.Unlogged {
+			logged = append(logged, r)
+		}
+	}
+	return logged
+}
+
+// countLogged counts the runs with query_log stats
+func countLogged(runs []*entity.QueryStats) int {
+	n := 0
+	for _, r := range runs {
+		if !r.Unlogged {
+			n++
+		}
+	}
+	return n
+}
+
+// meanStats builds a QueryStats holding the mean of every metric across the benchmark iterations
+func meanStats(b *entity.QueryBenchmark) *entity.QueryStats {
+	runs := loggedRuns(b.Iterations)
+	if len(runs) == 0 {
+		return &entity.QueryStats{}
+	}
+
+	var parts, marks float64
+	events := make(map[string]float64)
+	for _, r := range runs {
+		parts += float64(r.PartsRead)
+		marks += float64(r.MarksRead)
+		for name, value := range r.ProfileEvents {
+			events[name] += float64(value)
+		}
+	}
+	n := float64(len(runs))
+
+	var profileEvents map[string]uint64
+	if len(events) > 0 {
+		profileEvents = make(map[string]uint64, len(events))
+		for name, total := range events {
+			profileEvents[name] = uint64(total/n + 0.5)
+		}
+	}
+
+	return &entity.QueryStats{
+		ExecutionTimeMs: int64(b.Duration.Mean + 0.5),
+		RowsRead:        uint64(b.RowsRead.Mean + 0.5),
+		BytesRead:       uint64(b.BytesRead.Mean + 0.5),
+		MemoryPeak:      uint64(b.Memory.Mean + 0.5),
+		PartsRead:       uint64(parts/n + 0.5),
+		MarksRead:       uint64(marks/n + 0.5),
+		ProfileEvents:   profileEvents,
+	}
+}
+
+// DiffProfileEvents lists the ProfileEvents counters that differ between two runs,
+// ordered by the largest relative change first. Counters missing on one side count as zero.
+func DiffProfileEvents(before, after map[string]uint64) []entity.ProfileEventDiff {
+	names := make(map[string]struct{}, len(before)+len(after))
+	for name := range before {
+		names[name] = struct{}{}
+	}
+	for name := range after {
+		names[name] = struct{}{}
+	}
+
+	diffs := make([]entity.ProfileEventDiff, 0)
+	for name := range names {
+		if before[name] == after[name] {
+			continue
+		}
+		v1, v2 := float64(before[name]), float64(after[name])
+		diffs = append(diffs, entity.ProfileEventDiff{
+			Event:     name,
+			Value1:    v1,
+			Value2:    v2,
+			ChangePct: changePct(v1, v2),
+		})
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		ci, cj := math.Abs(diffs[i].ChangePct), math.Abs(diffs[j].ChangePct)
+		if ci != cj {
+			return ci > cj
+		}
+		return diffs[i].Event < diffs[j].Event
+	})
+
+	return diffs
+}
+
+// changePct returns the relative change from before to after in percent
+func changePct(before, after float64) float64 {
+	if before == 0 {
+		if after == 0 {
+			return 0
+		}
+		return 100
+	}
+	return (after - before) / before * 100
+}
+
+// diffBenchmarks compares the mean metrics of two benchmarks and flags significant changes
+func diffBenchmarks(version1, version2 string, b1, b2 *entity.QueryBenchmark) entity.CompareDifferences {
+	diff := entity.CompareDifferences{
+		ServerVersionDiffers: version1 != version2,
+		DurationChangePct:    changePct(b1.Duration.Mean, b2.Duration.Mean),
+		RowsReadChangePct:    changePct(b1.RowsRead.Mean, b2.RowsRead.Mean),
+		MemoryChangePct:      changePct(b1.Memory.Mean, b2.Memory.Mean),
+		ProfileEvents:        DiffProfileEvents(meanStats(b1).ProfileEvents, meanStats(b2).ProfileEvents),
+		Flags:                []string{},
+	}
+
+	if diff.ServerVersionDiffers {
+		diff.Flags = append(diff.Flags, fmt.Sprintf("server version differs: %s vs %s", version1, version2))
+	}
+
+	metrics := []struct {
+		name string
+		pct  float64
+	}{
+		{"duration", diff.DurationChangePct},
+		{"rows read", diff.RowsReadChangePct},
+		{"memory", diff.MemoryChangePct},
+	}
+	for _, m := range metrics {
+		if m.pct >= entity.CrossCompareThresholdPct || m.pct <= -entity.CrossCompareThresholdPct {
+			diff.Flags = append(diff.Flags, fmt.Sprintf("%s changed by %+.1f%%", m.name, m.pct))
+		}
+	}
+
+	return diff
+}
+
+// RankVariants orders the variants by the mean of every rank metric (lower is better),
+// stores each variant's position in its Ranks and returns the labels per metric, best first.
+// Variants without a benchmark are left out.
+func RankVariants(variants []*entity.VariantResult) map[string][]string {
+	metrics := []struct {
+		name  string
+		value func(b *entity.QueryBenchmark) float64
+	}{
+		{entity.RankMetricDuration, func(b *entity.QueryBenchmark) float64 { return b.Duration.Mean }},
+		{entity.RankMetricRowsRead, func(b *entity.QueryBenchmark) float64 { return b.RowsRead.Mean }},
+		{entity.RankMetricBytesRead, func(b *entity.QueryBenchmark) float64 { return b.BytesRead.Mean }},
+		{entity.RankMetricMemory, func(b *entity.QueryBenchmark) float64 { return b.Memory.Mean }},
+	}
+
+	candidates := make([]*entity.VariantResult, 0, len(variants))
+	for _, v := range variants {
+		if v.Benchmark == nil {
+			continue
+		}
+		if v.Ranks == nil {
+			v.Ranks = make(map[string]int, len(metrics))
+		}
+		candidates = append(candidates, v)
+	}
+
+	rankings := make(map[string][]string, len(metrics))
+	for _, m := range metrics {
+		// Start from the input order every time so ties keep the order the variants were given in
+		ranked := append([]*entity.VariantResult(nil), candidates...)
+		sort.SliceStable(ranked, func(i, j int) bool {
+			return m.value(ranked[i].Benchmark) < m.value(ranked[j].Benchmark)
+		})
+
+		labels := make([]string, len(ranked))
+		for i, v := range ranked {
+			// Equal values share the same rank
+			rank := i + 1
+			if i > 0 && m.value(v.Benchmark) == m.value(ranked[i-1].Benchmark) {
+				rank = ranked[i-1].Ranks[m.name]
+			}
+			v.Ranks[m.name] = rank
+			labels[i] = v.Label
+		}
+		rankings[m.name] = labels
+	}
+
+	return rankings
+}
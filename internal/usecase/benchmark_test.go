@@ -0,0 +1,207 @@
+package usecase_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/sqlite"
+	"github.com/rahmatrdn/go-ch-manager/internal/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gormsqlite "gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestRankVariants(t *testing.T) {
+	variant := func(label string, duration, rows, bytes, memory float64) *entity.VariantResult {
+		return &entity.VariantResult{
+			Label: label,
+			Benchmark: &entity.QueryBenchmark{
+				Duration:  entity.MetricSummary{Mean: duration},
+				RowsRead:  entity.MetricSummary{Mean: rows},
+				BytesRead: entity.MetricSummary{Mean: bytes},
+				Memory:    entity.MetricSummary{Mean: memory},
+			},
+		}
+	}
+
+	testcases := []struct {
+		name         string
+		variants     []*entity.VariantResult
+		wantRankings map[string][]string
+		wantRanks    map[string]map[string]int
+	}{
+		{
+			name: "Ranks Each Metric Independently",
+			variants: []*entity.VariantResult{
+				variant("final", 300, 100, 1000, 50),
+				variant("argmax", 100, 200, 500, 10),
+				variant("prewhere", 200, 50, 2000, 30),
+			},
+			wantRankings: map[string][]string{
+				entity.RankMetricDuration:  {"argmax", "prewhere", "final"},
+				entity.RankMetricRowsRead:  {"prewhere", "final", "argmax"},
+				entity.RankMetricBytesRead: {"argmax", "final", "prewhere"},
+				entity.RankMetricMemory:    {"argmax", "prewhere", "final"},
+			},
+			wantRanks: map[string]map[string]int{
+				"final":    {entity.RankMetricDuration: 3, entity.RankMetricRowsRead: 2, entity.RankMetricBytesRead: 2, entity.RankMetricMemory: 3},
+				"argmax":   {entity.RankMetricDuration: 1, entity.RankMetricRowsRead: 3, entity.RankMetricBytesRead: 1, entity.RankMetricMemory: 1},
+				"prewhere": {entity.RankMetricDuration: 2, entity.RankMetricRowsRead: 1, entity.RankMetricBytesRead: 3, entity.RankMetricMemory: 2},
+			},
+		},
+		{
+			name: "Ties Share A Rank And Keep Input Order",
+			variants: []*entity.VariantResult{
+				variant("a", 10, 5, 5, 5),
+				variant("b", 10, 5, 5, 5),
+				variant("c", 5, 5, 5, 5),
+			},
+			wantRankings: map[string][]string{
+				entity.RankMetricDuration:  {"c", "a", "b"},
+				entity.RankMetricRowsRead:  {"a", "b", "c"},
+				entity.RankMetricBytesRead: {"a", "b", "c"},
+				entity.RankMetricMemory:    {"a", "b", "c"},
+			},
+			wantRanks: map[string]map[string]int{
+				"a": {entity.RankMetricDuration: 2, entity.RankMetricRowsRead: 1, entity.RankMetricBytesRead: 1, entity.RankMetricMemory: 1},
+				"b": {entity.RankMetricDuration: 2, entity.RankMetricRowsRead: 1, entity.RankMetricBytesRead: 1, entity.RankMetricMemory: 1},
+				"c": {entity.RankMetricDuration: 1, entity.RankMetricRowsRead: 1, entity.RankMetricBytesRead: 1, entity.RankMetricMemory: 1},
+			},
+		},
+		{
+			name: "Skips Variants Without Benchmark",
+			variants: []*entity.VariantResult{
+				{Label: "failed"},
+				variant("ok", 1, 1, 1, 1),
+			},
+			wantRankings: map[string][]string{
+				entity.RankMetricDuration:  {"ok"},
+				entity.RankMetricRowsRead:  {"ok"},
+				entity.RankMetricBytesRead: {"ok"},
+				entity.RankMetricMemory:    {"ok"},
+			},
+			wantRanks: map[string]map[string]int{
+				"failed": nil,
+				"ok":     {entity.RankMetricDuration: 1, entity.RankMetricRowsRead: 1, entity.RankMetricBytesRead: 1, entity.RankMetricMemory: 1},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			rankings := usecase.RankVariants(tc.variants)
+
+			assert.Equal(t, tc.wantRankings, rankings)
+			for _, v := range tc.variants {
+				assert.Equal(t, tc.wantRanks[v.Label], v.Ranks, v.Label)
+			}
+		})
+	}
+}
+
+func TestDiffProfileEvents(t *testing.T) {
+	testcases := []struct {
+		name   string
+		before map[string]uint64
+		after  map[string]uint64
+		want   []entity.ProfileEventDiff
+	}{
+		{
+			name:   "Both Empty",
+			before: nil,
+			after:  nil,
+			want:   []entity.ProfileEventDiff{},
+		},
+		{
+			name:   "Equal Counters Are Skipped",
+			before: map[string]uint64{"SelectedMarks": 10, "MarkCacheHits": 5},
+			after:  map[string]uint64{"SelectedMarks": 10, "MarkCacheHits": 5},
+			want:   []entity.ProfileEventDiff{},
+		},
+		{
+			name:   "Ordered By Largest Relative Change",
+			before: map[string]uint64{"SelectedMarks": 100, "OSReadBytes": 1000, "UserTimeMicroseconds": 200},
+			after:  map[string]uint64{"SelectedMarks": 50, "OSReadBytes": 1100, "UserTimeMicroseconds": 600},
+			want: []entity.ProfileEventDiff{
+				{Event: "UserTimeMicroseconds", Value1: 200, Value2: 600, ChangePct: 200},
+				{Event: "SelectedMarks", Value1: 100, Value2: 50, ChangePct: -50},
+				{Event: "OSReadBytes", Value1: 1000, Value2: 1100, ChangePct: 10},
+			},
+		},
+		{
+			name:   "Missing Counters Count As Zero",
+			before: map[string]uint64{"MarkCacheMisses": 4},
+			after:  map[string]uint64{"MarkCacheHits": 4},
+			want: []entity.ProfileEventDiff{
+				{Event: "MarkCacheHits", Value1: 0, Value2: 4, ChangePct: 100},
+				{Event: "MarkCacheMisses", Value1: 4, Value2: 0, ChangePct: -100},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, usecase.DiffProfileEvents(tc.before, tc.after))
+		})
+	}
+}
+
+func TestCompareQueriesCacheModes(t *testing.T) {
+	testcases := []struct {
+		name     string
+		readOnly int
+		label    string
+		opts     entity.CompareOptions
+		wantErr  string
+	}{
+		{name: "Warm On Read-Only", readOnly: entity.ReadOnlyStrict},
+		{name: "Cold On Read-Only", readOnly: entity.ReadOnlyAllowSettings, opts: entity.CompareOptions{CacheMode: entity.CacheModeCold}, wantErr: "ClickHouse refuses to drop caches in readonly mode"},
+		{name: "Cold Warm On Read-Only", readOnly: entity.ReadOnlyStrict, opts: entity.CompareOptions{CacheMode: entity.CacheModeColdWarm}, wantErr: "ClickHouse refuses to drop caches in readonly mode"},
+		{name: "Cold On Production Unconfirmed", label: entity.LabelProduction, opts: entity.CompareOptions{CacheMode: entity.CacheModeCold}, wantErr: "confirm the cache drop"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			db, err := gorm.Open(gormsqlite.Open(filepath.Join(t.TempDir(), "compare.db")), &gorm.Config{})
+			require.NoError(t, err)
+			require.NoError(t, db.AutoMigrate(&entity.CHConnection{}))
+			connectionRepo := sqlite.NewConnectionRepository(db)
+			conn := &entity.CHConnection{Name: "analytics", Host: "localhost", Port: 9000, ReadOnly: tc.readOnly, Label: tc.label}
+			require.NoError(t, connectionRepo.Create(ctx, conn))
+
+			client := &statsClient{stats: map[string]*entity.QueryStats{
+				"SELECT 1": {ExecutionTimeMs: 10},
+				"SELECT 2": {ExecutionTimeMs: 20},
+			}}
+			u := usecase.NewConnectionUsecase(connectionRepo, nil, nil, client, nil)
+
+			variants := []entity.QueryVariant{{Query: "SELECT 1"}, {Query: "SELECT 2"}}
+			result, err := u.CompareQueries(ctx, conn.ID, variants, tc.opts)
+			if tc.wantErr != "" {
+				assert.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, result.Variants, 2)
+		})
+	}
+}
+
+func TestCompareConnectionsSameConnection(t *testing.T) {
+	ctx := context.Background()
+	db, err := gorm.Open(gormsqlite.Open(filepath.Join(t.TempDir(), "compare.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&entity.CHConnection{}))
+	connectionRepo := sqlite.NewConnectionRepository(db)
+	conn := &entity.CHConnection{Name: "analytics", Host: "localhost", Port: 9000}
+	require.NoError(t, connectionRepo.Create(ctx, conn))
+
+	u := usecase.NewConnectionUsecase(connectionRepo, nil, nil, &statsClient{}, nil)
+
+	_, err = u.CompareConnections(ctx, conn.ID, conn.ID, "SELECT 1", entity.CompareOptions{})
+	assert.ErrorContains(t, err, "two different connections")
+}
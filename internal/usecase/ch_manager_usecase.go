@@ -2,8 +2,13 @@ package usecase
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"time"
+	// Timezones are picked by users, they must not depend on the host's zoneinfo
+	_ "time/tzdata"
 
+	"github.com/google/uuid"
 	"github.com/rahmatrdn/go-ch-manager/entity"
 	"github.com/rahmatrdn/go-ch-manager/internal/repository/clickhouse"
 	"github.com/rahmatrdn/go-ch-manager/internal/repository/sqlite"
@@ -14,18 +19,30 @@ type ConnectionUsecase struct {
 	historyRepo sqlite.QueryHistoryRepository
 	favRepo     sqlite.FavoriteRepository
 	chClient    clickhouse.ClickHouseClient
+	cursors     *cursorRegistry
+	sessions    *sessionRegistry
+	guard       *StatementGuard
+	// historyMu serializes history writes, so a repeated run is folded into the entry it repeats
+	historyMu sync.Mutex
 }
 
-func NewConnectionUsecase(repo sqlite.ConnectionRepository, historyRepo sqlite.QueryHistoryRepository, favRepo sqlite.FavoriteRepository, chClient clickhouse.ClickHouseClient) *ConnectionUsecase {
+func NewConnectionUsecase(repo sqlite.ConnectionRepository, historyRepo sqlite.QueryHistoryRepository, favRepo sqlite.FavoriteRepository, chClient clickhouse.ClickHouseClient, guard *StatementGuard) *ConnectionUsecase {
 	return &ConnectionUsecase{
 		repo:        repo,
 		historyRepo: historyRepo,
 		favRepo:     favRepo,
 		chClient:    chClient,
+		cursors:     newCursorRegistry(),
+		sessions:    newSessionRegistry(),
+		guard:       guard,
 	}
 }
 
 func (u *ConnectionUsecase) CreateConnection(ctx context.Context, conn *entity.CHConnection) error {
+	if err := validateReadOnly(conn); err != nil {
+		return err
+	}
+
 	conn.CreatedAt = time.Now()
 	conn.UpdatedAt = time.Now()
 	// Optionally test connection before saving?
@@ -44,6 +61,10 @@ func (u *ConnectionUsecase) CreateConnection(ctx context.Context, conn *entity.C
 }
 
 func (u *ConnectionUsecase) UpdateConnection(ctx context.Context, id int64, conn *entity.CHConnection) error {
+	if err := validateReadOnly(conn); err != nil {
+		return err
+	}
+
 	existing, err := u.repo.FindByID(ctx, id)
 	if err != nil {
 		return err
@@ -71,6 +92,15 @@ func (u *ConnectionUsecase) UpdateConnection(ctx context.Context, id int64, conn
 	return u.repo.Update(ctx, conn)
 }
 
+// validateReadOnly accepts the read-only modes ClickHouse knows
+func validateReadOnly(conn *entity.CHConnection) error {
+	switch conn.ReadOnly {
+	case entity.ReadOnlyOff, entity.ReadOnlyStrict, entity.ReadOnlyAllowSettings:
+		return nil
+	}
+	return fmt.Errorf("read_only must be %d, %d or %d", entity.ReadOnlyOff, entity.ReadOnlyStrict, entity.ReadOnlyAllowSettings)
+}
+
 func (u *ConnectionUsecase) GetAllConnections(ctx context.Context) ([]*entity.CHConnection, error) {
 	return u.repo.FindAll(ctx)
 }
@@ -159,11 +189,19 @@ func (u *ConnectionUsecase) GetSchema(ctx context.Context, id int64, table strin
 	return schema, createSQL, nil
 }
 
-func (u *ConnectionUsecase) GetQueryHistory(ctx context.Context, connectionID int64) ([]*entity.QueryHistory, error) {
-	return u.historyRepo.FindByConnectionID(ctx, connectionID, 50)
-}
+// CompareQueries benchmarks every variant on the same connection and ranks them.
+// The first variant is the reference the others are compared against.
+func (u *ConnectionUsecase) CompareQueries(ctx context.Context, id int64, variants []entity.QueryVariant, opts entity.CompareOptions) (*entity.CompareResult, error) {
+	opts, err := normalizeCompareOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	variants, err = normalizeVariants(variants)
+	if err != nil {
+		return nil, err
+	}
 
-func (u *ConnectionUsecase) CompareQueries(ctx context.Context, id int64, query1, query2 string) (*entity.CompareResult, error) {
 	conn, err := u.repo.FindByID(ctx, id)
 	if err != nil {
 		return nil, err
@@ -172,23 +210,171 @@ func (u *ConnectionUsecase) CompareQueries(ctx context.Context, id int64, query1
 		return nil, nil // Or error not found
 	}
 
-	stats1, err := u.chClient.ExecuteQueryWithStats(ctx, conn, query1)
+	warnings, err := checkCacheDrop(conn, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	stats2, err := u.chClient.ExecuteQueryWithStats(ctx, conn, query2)
+	// Comparisons run every query several times, guarded statements are never confirmed here
+	for _, v := range variants {
+		if err := u.guard.Check(conn, SplitStatements(v.Query), ""); err != nil {
+			return nil, fmt.Errorf("%s: %w", v.Label, err)
+		}
+	}
+
+	results := make([]*entity.VariantResult, 0, len(variants))
+	for i, v := range variants {
+		bench, err := runBenchmark(ctx, u.chClient, conn, v, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", v.Label, err)
+		}
+
+		result := &entity.VariantResult{
+			Label:     v.Label,
+			Query:     v.Query,
+			Settings:  v.Settings,
+			Stats:     meanStats(bench),
+			Benchmark: bench,
+		}
+		if bench.Cold != nil {
+			result.ColdStats = meanStats(bench.Cold)
+		}
+		if i > 0 {
+			result.ProfileEventDiffs = DiffProfileEvents(results[0].Stats.ProfileEvents, result.Stats.ProfileEvents)
+			result.ResultDiff, err = u.compareResults(ctx, conn, variants[0], conn, v, opts.ResultCheck)
+			if err != nil {
+				return nil, err
+			}
+		}
+		results = append(results, result)
+	}
+
+	return &entity.CompareResult{
+		Query1Stats:     results[0].Stats,
+		Query2Stats:     results[1].Stats,
+		Query1Benchmark: results[0].Benchmark,
+		Query2Benchmark: results[1].Benchmark,
+		ResultDiff:      results[1].ResultDiff,
+		Variants:        results,
+		Rankings:        RankVariants(results),
+		Iterations:      opts.Iterations,
+		Warmup:          opts.Warmup,
+		CacheMode:       opts.CacheMode,
+		Warnings:        warnings,
+	}, nil
+}
+
+// CompareConnections runs the same query against two connections (e.g. staging and production)
+func (u *ConnectionUsecase) CompareConnections(ctx context.Context, id1, id2 int64, query string, opts entity.CompareOptions) (*entity.CrossConnectionCompareResult, error) {
+	if id1 == id2 {
+		return nil, fmt.Errorf("pick two different connections to compare")
+	}
+
+	opts, err := normalizeCompareOptions(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	return &entity.CompareResult{
-		Query1Stats: stats1,
-		Query2Stats: stats2,
+	conn1, err := u.findConnection(ctx, id1)
+	if err != nil {
+		return nil, err
+	}
+	conn2, err := u.findConnection(ctx, id2)
+	if err != nil {
+		return nil, err
+	}
+
+	var warnings []string
+	for _, conn := range []*entity.CHConnection{conn1, conn2} {
+		if err := u.guard.Check(conn, SplitStatements(query), ""); err != nil {
+			return nil, err
+		}
+		w, err := checkCacheDrop(conn, opts)
+		if err != nil {
+			return nil, err
+		}
+		warnings = append(warnings, w...)
+	}
+
+	variant := entity.QueryVariant{Query: query}
+	bench1, err := runBenchmark(ctx, u.chClient, conn1, variant, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", conn1.Name, err)
+	}
+
+	bench2, err := runBenchmark(ctx, u.chClient, conn2, variant, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", conn2.Name, err)
+	}
+
+	resultDiff, err := u.compareResults(ctx, conn1, variant, conn2, variant, opts.ResultCheck)
+	if err != nil {
+		return nil, err
+	}
+
+	summary1 := u.connectionSummary(ctx, conn1)
+	summary2 := u.connectionSummary(ctx, conn2)
+
+	return &entity.CrossConnectionCompareResult{
+		Query:                query,
+		Connection1:          summary1,
+		Connection2:          summary2,
+		Connection1Stats:     meanStats(bench1),
+		Connection2Stats:     meanStats(bench2),
+		Connection1Benchmark: bench1,
+		Connection2Benchmark: bench2,
+		Differences:          diffBenchmarks(summary1.ServerVersion, summary2.ServerVersion, bench1, bench2),
+		ResultDiff:           resultDiff,
+		Iterations:           opts.Iterations,
+		Warmup:               opts.Warmup,
+		CacheMode:            opts.CacheMode,
+		Warnings:             warnings,
 	}, nil
 }
 
-func (u *ConnectionUsecase) ExecuteQuery(ctx context.Context, id int64, query string) (*entity.QueryResult, error) {
+// findConnection loads a connection and turns a missing record into an error
+func (u *ConnectionUsecase) findConnection(ctx context.Context, id int64) (*entity.CHConnection, error) {
+	conn, err := u.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if conn == nil {
+		return nil, fmt.Errorf("connection %d not found", id)
+	}
+	return conn, nil
+}
+
+// connectionSummary prefers the live server version and falls back to the one stored on save
+func (u *ConnectionUsecase) connectionSummary(ctx context.Context, conn *entity.CHConnection) entity.ConnectionSummary {
+	version, err := u.chClient.GetServerInfo(ctx, conn)
+	if err != nil || version == "" {
+		version = conn.ServerInfo
+	}
+
+	return entity.ConnectionSummary{
+		ID:            conn.ID,
+		Name:          conn.Name,
+		Label:         conn.Label,
+		ServerVersion: version,
+	}
+}
+
+// ValidateQueryID accepts the IDs the console generates: letters, digits, dashes and underscores
+func ValidateQueryID(queryID string) error {
+	if queryID == "" || len(queryID) > entity.MaxQueryIDLength {
+		return fmt.Errorf("query id must be between 1 and %d characters", entity.MaxQueryIDLength)
+	}
+	for _, r := range queryID {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return fmt.Errorf("query id contains invalid character %q", r)
+		}
+	}
+	return nil
+}
+
+// ExecuteQuery runs a console query. With opts.PageSize set only the first page is returned and the
+// remaining rows are fetched with FetchQueryPage; otherwise all rows up to the connection's caps are returned.
+func (u *ConnectionUsecase) ExecuteQuery(ctx context.Context, id int64, query string, opts entity.QueryOptions) (*entity.QueryResult, error) {
 	conn, err := u.repo.FindByID(ctx, id)
 	if err != nil {
 		return nil, err
@@ -197,26 +383,202 @@ func (u *ConnectionUsecase) ExecuteQuery(ctx context.Context, id int64, query st
 		return nil, nil // Or return not found error
 	}
 
-	result, err := u.chClient.ExecuteQueryWithResults(ctx, conn, query)
+	if err := u.guard.Check(conn, SplitStatements(query), opts.Confirm); err != nil {
+		return nil, err
+	}
+
+	if opts.PageSize < 0 || opts.PageSize > entity.MaxResultPageSize {
+		return nil, fmt.Errorf("page size must be between 0 and %d", entity.MaxResultPageSize)
+	}
+
+	loc, err := ResolveTimezone(opts.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := BindQueryParameters(query, opts.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	ctx = clickhouse.WithQueryParameters(ctx, params)
+
+	ctx, settings, err := withQuerySettings(ctx, opts.Settings)
 	if err != nil {
 		return nil, err
 	}
 
-	// Save to history (Async or Sync? Sync for now to simple)
-	go func() {
-		// Create a new context for the background task to avoid cancellation if the request context is cancelled
-		bgCtx := context.Background()
-		history := &entity.QueryHistory{
-			ConnectionID: id,
-			Query:        query,
+	ctx, err = u.sessionContext(ctx, id, opts.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.QueryID != "" {
+		if err := ValidateQueryID(opts.QueryID); err != nil {
+			return nil, err
 		}
-		_ = u.historyRepo.Create(bgCtx, history)
-		_ = u.historyRepo.Prune(bgCtx, id, 50)
-	}()
+		ctx = clickhouse.WithQueryID(ctx, opts.QueryID)
+	}
+
+	run := u.startHistory(ctx, conn, query, params, settings)
+	var result *entity.QueryResult
+	if opts.PageSize > 0 {
+		// Paged results need a known ID to address their cursor
+		queryID := opts.QueryID
+		if queryID == "" {
+			queryID = uuid.New().String()
+		}
+		result, err = u.executePaged(ctx, conn, query, queryID, opts.PageSize, loc)
+	} else {
+		result, err = u.chClient.ExecuteQueryWithResults(ctx, conn, query)
+		if err == nil {
+			clickhouse.EncodeResult(result, loc)
+		}
+	}
+	if err != nil {
+		run.finish(nil, err)
+		return nil, err
+	}
 
+	result.Settings = settings
+	run.finish(result.Stats, nil)
 	return result, nil
 }
 
+// StreamQuery runs a console query and hands its rows to emit one at a time, framed by a meta and an end event.
+// The connection's caps still apply. An error of emit, e.g. a closed client, stops and kills the query.
+func (u *ConnectionUsecase) StreamQuery(ctx context.Context, id int64, query string, opts entity.QueryOptions, emit func(entity.QueryStreamEvent) error) (err error) {
+	conn, err := u.repo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if conn == nil {
+		return fmt.Errorf("connection not found")
+	}
+
+	if err := u.guard.Check(conn, SplitStatements(query), opts.Confirm); err != nil {
+		return err
+	}
+
+	if opts.QueryID != "" {
+		if err := ValidateQueryID(opts.QueryID); err != nil {
+			return err
+		}
+		ctx = clickhouse.WithQueryID(ctx, opts.QueryID)
+	}
+
+	loc, err := ResolveTimezone(opts.Timezone)
+	if err != nil {
+		return err
+	}
+
+	params, err := BindQueryParameters(query, opts.Parameters)
+	if err != nil {
+		return err
+	}
+	ctx = clickhouse.WithQueryParameters(ctx, params)
+
+	ctx, settings, err := withQuerySettings(ctx, opts.Settings)
+	if err != nil {
+		return err
+	}
+
+	ctx, err = u.sessionContext(ctx, id, opts.SessionID)
+	if err != nil {
+		return err
+	}
+
+	var stats *entity.QueryStats
+	run := u.startHistory(ctx, conn, query, params, settings)
+	defer func() { run.finish(stats, err) }()
+
+	reader, err := u.chClient.OpenQuery(ctx, conn, query)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	columns, types := reader.Columns(), reader.ColumnTypes()
+	if err := emit(entity.QueryStreamEvent{
+		Type:        entity.QueryStreamEventMeta,
+		QueryID:     reader.QueryID(),
+		Columns:     columns,
+		ColumnTypes: types,
+		Settings:    settings,
+	}); err != nil {
+		return err
+	}
+
+	maxRows, maxBytes := conn.ResultLimits()
+	end := entity.QueryStreamEvent{Type: entity.QueryStreamEventEnd, QueryID: reader.QueryID()}
+	for {
+		page := &entity.QueryResult{}
+		if err := clickhouse.ReadRows(reader, page, 1, maxRows, maxBytes); err != nil {
+			return err
+		}
+		for _, row := range page.Rows {
+			clickhouse.EncodeRow(columns, types, row, loc)
+			if err := emit(entity.QueryStreamEvent{Type: entity.QueryStreamEventRow, Row: row}); err != nil {
+				return err
+			}
+			end.RowCount++
+		}
+		if page.Truncated {
+			end.Truncated, end.TruncatedReason = true, page.TruncatedReason
+		}
+		if !page.HasMore {
+			break
+		}
+	}
+
+	reader.Close()
+	stats = reader.Stats(ctx)
+	end.Stats = stats
+	return emit(end)
+}
+
+// ResolveTimezone loads the location results are rendered in, an empty name keeps each column's own timezone
+func ResolveTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return nil, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", name)
+	}
+	return loc, nil
+}
+
+// withQuerySettings validates the per-query settings of a console request and applies them to its statements
+func withQuerySettings(ctx context.Context, settings entity.QuerySettings) (context.Context, entity.QuerySettings, error) {
+	settings, err := clickhouse.NormalizeSettings(settings)
+	if err != nil {
+		return nil, nil, err
+	}
+	return clickhouse.WithQuerySettings(ctx, settings), settings, nil
+}
+
+// CancelQuery kills a running query of the connection
+func (u *ConnectionUsecase) CancelQuery(ctx context.Context, id int64, queryID string) error {
+	if err := ValidateQueryID(queryID); err != nil {
+		return err
+	}
+
+	conn, err := u.repo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if conn == nil {
+		return fmt.Errorf("connection not found")
+	}
+
+	// A paged result still holds its query open, closing the cursor stops it as well
+	if cursor := u.cursors.get(queryID); cursor != nil && cursor.connectionID == id {
+		u.cursors.close(queryID)
+	}
+
+	return u.chClient.KillQuery(ctx, conn, queryID)
+}
+
 func (u *ConnectionUsecase) GetConfigurationData(ctx context.Context, id int64) (*entity.ConfigurationData, error) {
 	conn, err := u.repo.FindByID(ctx, id)
 	if err != nil {
@@ -264,6 +626,16 @@ func (u *ConnectionUsecase) GetConfigurationData(ctx context.Context, id int64)
 }
 
 func (u *ConnectionUsecase) SaveFavoriteComparison(ctx context.Context, fav *entity.FavoriteComparison) error {
+	variants, err := normalizeVariants(fav.GetVariants())
+	if err != nil {
+		return err
+	}
+
+	// Keep the legacy columns filled for readers that only know two queries
+	fav.Variants = variants
+	fav.Query1 = variants[0].Query
+	fav.Query2 = variants[1].Query
+
 	return u.favRepo.Create(ctx, fav)
 }
 
@@ -0,0 +1,66 @@
+package usecase_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/usecase"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateQueryID(t *testing.T) {
+	testcases := []struct {
+		name    string
+		queryID string
+		wantErr bool
+	}{
+		{name: "UUID", queryID: "3f2b6c1e-8a4d-4c1b-9b7e-2d5f0a9c8e71"},
+		{name: "Underscores", queryID: "console_42"},
+		{name: "Empty", queryID: "", wantErr: true},
+		{name: "Too Long", queryID: strings.Repeat("a", entity.MaxQueryIDLength+1), wantErr: true},
+		{name: "Quote", queryID: "x' OR 1=1", wantErr: true},
+		{name: "Slash", queryID: "a/b", wantErr: true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := usecase.ValidateQueryID(tc.queryID)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestResolveTimezone(t *testing.T) {
+	testcases := []struct {
+		name     string
+		timezone string
+		want     string
+		wantErr  bool
+	}{
+		{name: "Column Timezone", timezone: "", want: ""},
+		{name: "UTC", timezone: "UTC", want: "UTC"},
+		{name: "IANA Name", timezone: "Asia/Jakarta", want: "Asia/Jakarta"},
+		{name: "Unknown", timezone: "Mars/Olympus", wantErr: true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			loc, err := usecase.ResolveTimezone(tc.timezone)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			if tc.want == "" {
+				assert.Nil(t, loc)
+				return
+			}
+			assert.Equal(t, tc.want, loc.String())
+		})
+	}
+}
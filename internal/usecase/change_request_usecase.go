@@ -0,0 +1,280 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/sqlite"
+)
+
+type ChangeRequestUsecase interface {
+	GetChangeRequests(ctx context.Context, connectionID int64, filter entity.ChangeRequestFilter) ([]*entity.ChangeRequest, error)
+	GetChangeRequest(ctx context.Context, connectionID, id int64) (*entity.ChangeRequest, error)
+	CreateChangeRequest(ctx context.Context, connectionID int64, request *entity.ChangeRequest) error
+	ApproveChangeRequest(ctx context.Context, connectionID, id int64, review entity.ChangeRequestReview) (*entity.ChangeRequest, error)
+	RejectChangeRequest(ctx context.Context, connectionID, id int64, review entity.ChangeRequestReview) (*entity.ChangeRequest, error)
+	CancelChangeRequest(ctx context.Context, connectionID, id int64, review entity.ChangeRequestReview) (*entity.ChangeRequest, error)
+	ExecuteChangeRequest(ctx context.Context, connectionID, id int64) (*entity.ChangeRequest, error)
+}
+
+type changeRequestUsecase struct {
+	changeRequestRepo sqlite.ChangeRequestRepository
+	connectionRepo    sqlite.ConnectionRepository
+	connectionUsecase *ConnectionUsecase
+	guard             *StatementGuard
+}
+
+func NewChangeRequestUsecase(changeRequestRepo sqlite.ChangeRequestRepository, connectionRepo sqlite.ConnectionRepository, connectionUsecase *ConnectionUsecase, guard *StatementGuard) ChangeRequestUsecase {
+	return &changeRequestUsecase{
+		changeRequestRepo: changeRequestRepo,
+		connectionRepo:    connectionRepo,
+		connectionUsecase: connectionUsecase,
+		guard:             guard,
+	}
+}
+
+type approvedChangeKey struct{}
+
+// withApprovedChange marks the statements run with the context as those of an approved change request
+func withApprovedChange(ctx context.Context, id int64) context.Context {
+	return context.WithValue(ctx, approvedChangeKey{}, id)
+}
+
+// approvedChangeFromContext returns the ID stored by withApprovedChange, 0 outside a change request
+func approvedChangeFromContext(ctx context.Context) int64 {
+	id, _ := ctx.Value(approvedChangeKey{}).(int64)
+	return id
+}
+
+func (u *changeRequestUsecase) GetChangeRequests(ctx context.Context, connectionID int64, filter entity.ChangeRequestFilter) ([]*entity.ChangeRequest, error) {
+	filter.Status = strings.TrimSpace(filter.Status)
+	return u.changeRequestRepo.FindByConnection(ctx, connectionID, filter)
+}
+
+func (u *changeRequestUsecase) GetChangeRequest(ctx context.Context, connectionID, id int64) (*entity.ChangeRequest, error) {
+	request, err := u.changeRequestRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if request == nil || request.ConnectionID != connectionID {
+		return nil, fmt.Errorf("change request not found")
+	}
+	return request, nil
+}
+
+// CreateChangeRequest submits the statements for review. Statements the guard blocks are refused up front,
+// an approval would not let them run.
+func (u *changeRequestUsecase) CreateChangeRequest(ctx context.Context, connectionID int64, request *entity.ChangeRequest) error {
+	user, err := changeRequestUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	conn, err := u.connectionRepo.FindByID(ctx, connectionID)
+	if err != nil {
+		return err
+	}
+	if conn == nil {
+		return fmt.Errorf("connection not found")
+	}
+
+	request.Query = strings.TrimSpace(request.Query)
+	request.Justification = strings.TrimSpace(request.Justification)
+	if request.Justification == "" {
+		return fmt.Errorf("justification is required")
+	}
+	statements, err := changeRequestStatements(request.Query)
+	if err != nil {
+		return err
+	}
+	if err := u.guard.CheckApproved(conn, statements); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	*request = entity.ChangeRequest{
+		ConnectionID:  connectionID,
+		Query:         request.Query,
+		Kinds:         GuardedKinds(statements),
+		Justification: request.Justification,
+		Status:        entity.ChangeRequestPending,
+		CreatedBy:     user,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	logChange(request, user, "created", request.Justification)
+
+	return u.changeRequestRepo.Create(ctx, request)
+}
+
+// ApproveChangeRequest approves a pending request, the approver must not be its author
+func (u *changeRequestUsecase) ApproveChangeRequest(ctx context.Context, connectionID, id int64, review entity.ChangeRequestReview) (*entity.ChangeRequest, error) {
+	return u.review(ctx, connectionID, id, review, entity.ChangeRequestApproved)
+}
+
+// RejectChangeRequest rejects a pending request, its author withdraws it with CancelChangeRequest instead
+func (u *changeRequestUsecase) RejectChangeRequest(ctx context.Context, connectionID, id int64, review entity.ChangeRequestReview) (*entity.ChangeRequest, error) {
+	return u.review(ctx, connectionID, id, review, entity.ChangeRequestRejected)
+}
+
+func (u *changeRequestUsecase) review(ctx context.Context, connectionID, id int64, review entity.ChangeRequestReview, status string) (*entity.ChangeRequest, error) {
+	user, err := changeRequestUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	request, err := u.GetChangeRequest(ctx, connectionID, id)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != entity.ChangeRequestPending {
+		return nil, fmt.Errorf("change request %d is %s, only pending requests can be reviewed", id, request.Status)
+	}
+	if strings.EqualFold(user, request.CreatedBy) {
+		return nil, fmt.Errorf("change request %d must be reviewed by a user other than its author", id)
+	}
+
+	now := time.Now()
+	request.Status = status
+	request.ReviewedBy = user
+	request.ReviewComment = strings.TrimSpace(review.Comment)
+	request.ReviewedAt = &now
+	logChange(request, user, status, request.ReviewComment)
+
+	return request, u.transition(ctx, request, entity.ChangeRequestPending)
+}
+
+// CancelChangeRequest withdraws a request that has not run yet, only its author can cancel it
+func (u *changeRequestUsecase) CancelChangeRequest(ctx context.Context, connectionID, id int64, review entity.ChangeRequestReview) (*entity.ChangeRequest, error) {
+	user, err := changeRequestUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	request, err := u.GetChangeRequest(ctx, connectionID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(user, request.CreatedBy) {
+		return nil, fmt.Errorf("change request %d can only be cancelled by its author", id)
+	}
+	if request.Status != entity.ChangeRequestPending && request.Status != entity.ChangeRequestApproved {
+		return nil, fmt.Errorf("change request %d is %s and can no longer be cancelled", id, request.Status)
+	}
+
+	from := request.Status
+	request.Status = entity.ChangeRequestCancelled
+	logChange(request, user, entity.ChangeRequestCancelled, strings.TrimSpace(review.Comment))
+
+	return request, u.transition(ctx, request, from)
+}
+
+// ExecuteChangeRequest runs an approved request once. Its statements are checked again first: the approval
+// must still come from another user and the guard policy of the connection may have blocked them since.
+func (u *changeRequestUsecase) ExecuteChangeRequest(ctx context.Context, connectionID, id int64) (*entity.ChangeRequest, error) {
+	user, err := changeRequestUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	request, err := u.GetChangeRequest(ctx, connectionID, id)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != entity.ChangeRequestApproved {
+		return nil, fmt.Errorf("change request %d is %s, only approved requests can be executed", id, request.Status)
+	}
+
+	request.Status = entity.ChangeRequestRunning
+	request.ExecutedBy = user
+	logChange(request, user, "started", "")
+	if err := u.transition(ctx, request, entity.ChangeRequestApproved); err != nil {
+		return nil, err
+	}
+
+	var result *entity.ScriptResult
+	runErr := u.recheck(ctx, request)
+	if runErr == nil {
+		result, runErr = u.connectionUsecase.ExecuteScript(withApprovedChange(ctx, id), connectionID, request.Query, entity.ScriptOptions{})
+	}
+	if runErr == nil && result != nil {
+		runErr = scriptError(result)
+	}
+
+	now := time.Now()
+	request.Result = result
+	request.ExecutedAt = &now
+	if runErr != nil {
+		request.Status = entity.ChangeRequestFailed
+		request.Error = runErr.Error()
+		logChange(request, user, entity.ChangeRequestFailed, request.Error)
+	} else {
+		request.Status = entity.ChangeRequestExecuted
+		logChange(request, user, entity.ChangeRequestExecuted, fmt.Sprintf("%d statements in %d ms", result.Succeeded, result.DurationMs))
+	}
+
+	// The outcome is recorded even when the request that started it was cancelled
+	return request, u.transition(context.WithoutCancel(ctx), request, entity.ChangeRequestRunning)
+}
+
+// recheck verifies an approved request right before it runs
+func (u *changeRequestUsecase) recheck(ctx context.Context, request *entity.ChangeRequest) error {
+	if request.ReviewedBy == "" || strings.EqualFold(request.ReviewedBy, request.CreatedBy) {
+		return fmt.Errorf("change request %d has no approval from a second user", request.ID)
+	}
+
+	conn, err := u.connectionRepo.FindByID(ctx, request.ConnectionID)
+	if err != nil {
+		return err
+	}
+	if conn == nil {
+		return fmt.Errorf("connection not found")
+	}
+
+	statements, err := changeRequestStatements(request.Query)
+	if err != nil {
+		return err
+	}
+	return u.guard.CheckApproved(conn, statements)
+}
+
+func (u *changeRequestUsecase) transition(ctx context.Context, request *entity.ChangeRequest, from string) error {
+	request.UpdatedAt = time.Now()
+	ok, err := u.changeRequestRepo.Transition(ctx, request, from)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("change request %d was changed by someone else, reload it", request.ID)
+	}
+	return nil
+}
+
+// changeRequestUser returns the user of the request, four eyes need to know whose they are
+func changeRequestUser(ctx context.Context) (string, error) {
+	user := UserFromContext(ctx)
+	if user == "" {
+		return "", fmt.Errorf("change requests need an authenticated user")
+	}
+	return user, nil
+}
+
+func changeRequestStatements(query string) ([]string, error) {
+	statements := SplitStatements(query)
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("query has no statements")
+	}
+	if len(statements) > entity.MaxScriptStatements {
+		return nil, fmt.Errorf("query has %d statements, at most %d are allowed", len(statements), entity.MaxScriptStatements)
+	}
+	return statements, nil
+}
+
+func logChange(request *entity.ChangeRequest, user, action, message string) {
+	request.Log = append(request.Log, entity.ChangeRequestLogEntry{
+		At:      time.Now(),
+		User:    user,
+		Action:  action,
+		Message: message,
+	})
+}
@@ -0,0 +1,129 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/clickhouse"
+)
+
+type consoleSession struct {
+	connectionID int64
+	timer        *time.Timer
+}
+
+// sessionRegistry tracks the open console sessions and closes the idle ones
+type sessionRegistry struct {
+	mu       sync.Mutex
+	sessions map[string]*consoleSession
+}
+
+func newSessionRegistry() *sessionRegistry {
+	return &sessionRegistry{sessions: make(map[string]*consoleSession)}
+}
+
+func (r *sessionRegistry) add(id string, session *consoleSession, onExpire func()) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.sessions) >= entity.MaxConsoleSessions {
+		return fmt.Errorf("too many open console sessions, close an unused one first")
+	}
+
+	session.timer = time.AfterFunc(entity.ConsoleSessionIdleTimeout, onExpire)
+	r.sessions[id] = session
+	return nil
+}
+
+// touch returns the session of the connection and restarts its idle timer
+func (r *sessionRegistry) touch(connectionID int64, id string) (*consoleSession, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[id]
+	if !ok || session.connectionID != connectionID {
+		return nil, fmt.Errorf("console session not found, it may have expired")
+	}
+	session.timer.Reset(entity.ConsoleSessionIdleTimeout)
+	return session, nil
+}
+
+// remove forgets the session, it reports whether the session existed
+func (r *sessionRegistry) remove(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[id]
+	if ok {
+		session.timer.Stop()
+		delete(r.sessions, id)
+	}
+	return ok
+}
+
+// CreateSession opens a console session on the connection. Statements run in it share SET, USE and
+// temporary tables until the session is closed or stays idle for ConsoleSessionIdleTimeout.
+func (u *ConnectionUsecase) CreateSession(ctx context.Context, id int64) (*entity.ConsoleSession, error) {
+	conn, err := u.findConnection(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionID := uuid.New().String()
+	if err := u.chClient.OpenSession(ctx, conn, sessionID); err != nil {
+		return nil, err
+	}
+
+	err = u.sessions.add(sessionID, &consoleSession{connectionID: id}, func() {
+		u.closeSession(sessionID)
+	})
+	if err != nil {
+		_ = u.chClient.CloseSession(sessionID)
+		return nil, err
+	}
+
+	return u.chClient.GetSessionState(ctx, sessionID)
+}
+
+// GetSession returns the current database and changed settings of a console session
+func (u *ConnectionUsecase) GetSession(ctx context.Context, id int64, sessionID string) (*entity.ConsoleSession, error) {
+	if _, err := u.sessions.touch(id, sessionID); err != nil {
+		return nil, err
+	}
+	return u.chClient.GetSessionState(ctx, sessionID)
+}
+
+// CloseSession ends a console session, dropping its temporary tables
+func (u *ConnectionUsecase) CloseSession(ctx context.Context, id int64, sessionID string) error {
+	if _, err := u.sessions.touch(id, sessionID); err != nil {
+		return err
+	}
+	u.closeSession(sessionID)
+	return nil
+}
+
+func (u *ConnectionUsecase) closeSession(sessionID string) {
+	if !u.sessions.remove(sessionID) {
+		return
+	}
+	u.cursors.closeSession(sessionID)
+	_ = u.chClient.CloseSession(sessionID)
+}
+
+// sessionContext runs the statements of ctx in the console session, if any. The session has a single
+// connection, so the unfinished paged results of its previous statements are closed first.
+func (u *ConnectionUsecase) sessionContext(ctx context.Context, id int64, sessionID string) (context.Context, error) {
+	if sessionID == "" {
+		return ctx, nil
+	}
+	if _, err := u.sessions.touch(id, sessionID); err != nil {
+		return nil, err
+	}
+
+	u.cursors.closeSession(sessionID)
+	return clickhouse.WithSession(ctx, sessionID), nil
+}
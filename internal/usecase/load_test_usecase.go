@@ -0,0 +1,454 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/helper"
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/clickhouse"
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/sqlite"
+)
+
+type LoadTestUsecase interface {
+	StartLoadTest(ctx context.Context, connectionID int64, config entity.LoadTestConfig) (*entity.LoadTestReport, error)
+	GetLoadTest(ctx context.Context, testID string) (*entity.LoadTestReport, error)
+	GetLoadTests(ctx context.Context, connectionID int64) ([]*entity.LoadTestReport, error)
+	CancelLoadTest(ctx context.Context, testID string) error
+}
+
+// loadTest is the in-memory state of a running or finished load test
+type loadTest struct {
+	mu             sync.Mutex
+	report         entity.LoadTestReport
+	latencies      []float64
+	queryLatencies [][]float64
+	queryErrors    []int64
+	serverStats    map[int]*entity.LoadTestServerStats
+	errorSamples   []string
+	cancelled      bool
+	cancel         context.CancelFunc
+
+	// summarizing lets one caller at a time compute the snapshot, the others wait for its result
+	summarizing sync.Mutex
+	cached      *entity.LoadTestReport
+	cachedAt    time.Time
+}
+
+// loadTestSnapshotMaxAge is how long a snapshot of a running test is reused, so every progress stream
+// shares the percentiles computed once per tick
+const loadTestSnapshotMaxAge = 400 * time.Millisecond
+
+type loadTestUsecase struct {
+	connectionRepo sqlite.ConnectionRepository
+	chClient       clickhouse.ClickHouseClient
+
+	mu    sync.Mutex
+	tests map[string]*loadTest
+	order []string
+}
+
+func NewLoadTestUsecase(connectionRepo sqlite.ConnectionRepository, chClient clickhouse.ClickHouseClient) LoadTestUsecase {
+	return &loadTestUsecase{
+		connectionRepo: connectionRepo,
+		chClient:       chClient,
+		tests:          make(map[string]*loadTest),
+	}
+}
+
+// NormalizeLoadTestConfig validates a load test configuration and fills in the defaults
+func NormalizeLoadTestConfig(config entity.LoadTestConfig) (entity.LoadTestConfig, error) {
+	if len(config.Queries) == 0 {
+		return config, fmt.Errorf("at least one query is required")
+	}
+	if len(config.Queries) > entity.MaxLoadTestQueries {
+		return config, fmt.Errorf("at most %d queries are allowed", entity.MaxLoadTestQueries)
+	}
+
+	queries := make([]entity.LoadTestQuery, len(config.Queries))
+	for i, q := range config.Queries {
+		q.Query = strings.TrimSpace(q.Query)
+		if q.Query == "" {
+			return config, fmt.Errorf("query %d is empty", i+1)
+		}
+		if q.Label = strings.TrimSpace(q.Label); q.Label == "" {
+			q.Label = fmt.Sprintf("Query %d", i+1)
+		}
+		if err := RequireSelect(q.Query); err != nil {
+			return config, fmt.Errorf("%s: %w", q.Label, err)
+		}
+		if q.Weight < 0 {
+			return config, fmt.Errorf("weight of %s must not be negative", q.Label)
+		}
+		if q.Weight == 0 {
+			q.Weight = 1
+		}
+		queries[i] = q
+	}
+	config.Queries = queries
+
+	if config.Concurrency <= 0 {
+		config.Concurrency = entity.DefaultLoadTestConcurrency
+	}
+	if config.Concurrency > entity.MaxLoadTestConcurrency {
+		return config, fmt.Errorf("concurrency must be at most %d", entity.MaxLoadTestConcurrency)
+	}
+
+	if config.Iterations < 0 || config.Iterations > entity.MaxLoadTestIterations {
+		return config, fmt.Errorf("iterations must be between 0 and %d", entity.MaxLoadTestIterations)
+	}
+	if config.DurationSeconds < 0 || config.DurationSeconds > entity.MaxLoadTestDurationSeconds {
+		return config, fmt.Errorf("duration must be between 0 and %d seconds", entity.MaxLoadTestDurationSeconds)
+	}
+	// Without an iteration budget the duration is the only stop condition
+	if config.DurationSeconds == 0 && config.Iterations == 0 {
+		config.DurationSeconds = entity.DefaultLoadTestDurationSeconds
+	}
+	if config.DurationSeconds == 0 {
+		config.DurationSeconds = entity.MaxLoadTestDurationSeconds
+	}
+
+	return config, nil
+}
+
+// PickWeighted maps n, a number in [0, sum of weights), to the index of the weight range it falls in
+func PickWeighted(weights []int, n int) int {
+	for i, w := range weights {
+		if n < w {
+			return i
+		}
+		n -= w
+	}
+	return len(weights) - 1
+}
+
+func (u *loadTestUsecase) StartLoadTest(ctx context.Context, connectionID int64, config entity.LoadTestConfig) (*entity.LoadTestReport, error) {
+	config, err := NormalizeLoadTestConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := u.connectionRepo.FindByID(ctx, connectionID)
+	if err != nil {
+		return nil, err
+	}
+	if conn == nil {
+		return nil, fmt.Errorf("connection not found")
+	}
+
+	u.mu.Lock()
+	for _, t := range u.tests {
+		if t.report.ConnectionID == connectionID && t.status() == entity.LoadTestStatusRunning {
+			u.mu.Unlock()
+			return nil, fmt.Errorf("a load test is already running on this connection")
+		}
+	}
+
+	// The request context ends with the HTTP call, the test keeps running in the background
+	runCtx, cancel := context.WithCancel(context.Background())
+	test := &loadTest{
+		report: entity.LoadTestReport{
+			ID:           strings.ReplaceAll(uuid.New().String(), "-", "")[:12],
+			ConnectionID: connectionID,
+			Status:       entity.LoadTestStatusRunning,
+			Config:       config,
+			StartedAt:    time.Now(),
+		},
+		queryLatencies: make([][]float64, len(config.Queries)),
+		queryErrors:    make([]int64, len(config.Queries)),
+		cancel:         cancel,
+	}
+	u.tests[test.report.ID] = test
+	u.order = append(u.order, test.report.ID)
+	u.evict()
+	u.mu.Unlock()
+
+	go u.run(runCtx, conn, test)
+
+	return test.snapshot(), nil
+}
+
+// evict drops the oldest finished tests beyond the history limit. Callers must hold u.mu.
+func (u *loadTestUsecase) evict() {
+	for len(u.order) > entity.MaxLoadTestHistory {
+		evicted := false
+		for i, id := range u.order {
+			if u.tests[id].status() != entity.LoadTestStatusRunning {
+				delete(u.tests, id)
+				u.order = append(u.order[:i], u.order[i+1:]...)
+				evicted = true
+				break
+			}
+		}
+		if !evicted {
+			return
+		}
+	}
+}
+
+func (u *loadTestUsecase) run(ctx context.Context, conn *entity.CHConnection, test *loadTest) {
+	funcName := "LoadTestUsecase.run"
+	defer test.cancel()
+
+	config := test.report.Config
+	ctx, cancelDeadline := context.WithTimeout(ctx, time.Duration(config.DurationSeconds)*time.Second)
+	defer cancelDeadline()
+
+	weights := make([]int, len(config.Queries))
+	totalWeight := 0
+	for i, q := range config.Queries {
+		weights[i] = q.Weight
+		totalWeight += q.Weight
+	}
+
+	// Every worker shares the runner, the pooled connection is checked once instead of before each query
+	runner, err := u.chClient.OpenLoadRunner(conn)
+	if err != nil {
+		test.fail(err)
+		return
+	}
+
+	queryIDPrefix := "lt-" + test.report.ID + "-"
+	var issued int64
+
+	var wg sync.WaitGroup
+	for w := 0; w < config.Concurrency; w++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
+
+			for ctx.Err() == nil {
+				if config.Iterations > 0 && atomic.AddInt64(&issued, 1) > int64(config.Iterations) {
+					return
+				}
+
+				idx := PickWeighted(weights, rnd.Intn(totalWeight))
+				queryID := fmt.Sprintf("%s%d-%s", queryIDPrefix, idx, uuid.New().String())
+
+				elapsed, err := runner.Run(ctx, config.Queries[idx].Query, queryID)
+
+				// Queries interrupted by the deadline or a cancellation are neither a result nor an error
+				if err != nil && ctx.Err() != nil {
+					return
+				}
+				test.record(idx, float64(elapsed.Microseconds())/1000, err)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	status := entity.LoadTestStatusCompleted
+	test.mu.Lock()
+	if test.cancelled {
+		status = entity.LoadTestStatusCancelled
+	}
+	test.mu.Unlock()
+
+	// The run context is already done here, the server stats get a fresh one
+	statsCtx, cancelStats := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelStats()
+
+	serverStats, err := u.chClient.GetLoadTestServerStats(statsCtx, conn, queryIDPrefix, test.report.StartedAt)
+	if err != nil {
+		helper.LogError("get load test server stats", funcName, err, entity.CaptureFields{
+			"load_test_id": test.report.ID,
+		}, "")
+	}
+
+	test.finish(status, serverStats, err)
+}
+
+func (u *loadTestUsecase) GetLoadTest(ctx context.Context, testID string) (*entity.LoadTestReport, error) {
+	u.mu.Lock()
+	test, ok := u.tests[testID]
+	u.mu.Unlock()
+
+	if !ok {
+		return nil, nil
+	}
+	return test.snapshot(), nil
+}
+
+func (u *loadTestUsecase) GetLoadTests(ctx context.Context, connectionID int64) ([]*entity.LoadTestReport, error) {
+	u.mu.Lock()
+	var tests []*loadTest
+	for i := len(u.order) - 1; i >= 0; i-- {
+		test := u.tests[u.order[i]]
+		if test.report.ConnectionID == connectionID {
+			tests = append(tests, test)
+		}
+	}
+	u.mu.Unlock()
+
+	reports := make([]*entity.LoadTestReport, 0, len(tests))
+	for _, test := range tests {
+		reports = append(reports, test.snapshot())
+	}
+	return reports, nil
+}
+
+func (u *loadTestUsecase) CancelLoadTest(ctx context.Context, testID string) error {
+	u.mu.Lock()
+	test, ok := u.tests[testID]
+	u.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("load test not found")
+	}
+
+	test.mu.Lock()
+	if test.report.Status != entity.LoadTestStatusRunning {
+		test.mu.Unlock()
+		return fmt.Errorf("load test is not running")
+	}
+	// The status stays running until the server stats of the executed queries are collected
+	test.cancelled = true
+	test.mu.Unlock()
+
+	test.cancel()
+	return nil
+}
+
+func (t *loadTest) status() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.report.Status
+}
+
+func (t *loadTest) record(idx int, latencyMs float64, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err != nil {
+		t.report.Errors++
+		if len(t.errorSamples) < entity.MaxLoadTestErrorSamples {
+			t.errorSamples = append(t.errorSamples, fmt.Sprintf("%s: %s", t.report.Config.Queries[idx].Label, err.Error()))
+		}
+		t.queryErrors[idx]++
+		return
+	}
+
+	t.latencies = append(t.latencies, latencyMs)
+	t.queryLatencies[idx] = append(t.queryLatencies[idx], latencyMs)
+}
+
+// fail ends a test that could not start
+func (t *loadTest) fail(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	t.report.Status = entity.LoadTestStatusFailed
+	t.report.FinishedAt = &now
+	t.report.Error = err.Error()
+	t.cached = nil
+}
+
+func (t *loadTest) finish(status string, serverStats map[int]*entity.LoadTestServerStats, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	t.report.Status = status
+	t.report.FinishedAt = &now
+	t.cached = nil
+
+	if err != nil {
+		t.report.Error = fmt.Sprintf("server stats unavailable: %s", err.Error())
+		return
+	}
+
+	t.serverStats = serverStats
+
+	total := &entity.LoadTestServerStats{}
+	var totalMemory float64
+	for _, stats := range serverStats {
+
+		total.QueryCount += stats.QueryCount
+		total.CPUTimeMs += stats.CPUTimeMs
+		total.TotalReadBytes += stats.TotalReadBytes
+		total.TotalReadRows += stats.TotalReadRows
+		totalMemory += stats.AvgMemory * float64(stats.QueryCount)
+		if stats.PeakMemory > total.PeakMemory {
+			total.PeakMemory = stats.PeakMemory
+		}
+	}
+	if total.QueryCount > 0 {
+		total.AvgCPUTimeMs = total.CPUTimeMs / float64(total.QueryCount)
+		total.AvgMemory = totalMemory / float64(total.QueryCount)
+	}
+	t.report.Server = total
+}
+
+// snapshot copies the report and computes the latency percentiles and throughput so far. The latencies are
+// copied under the lock and sorted outside it, a running test reuses its snapshot for loadTestSnapshotMaxAge
+// and a finished one keeps it.
+func (t *loadTest) snapshot() *entity.LoadTestReport {
+	t.summarizing.Lock()
+	defer t.summarizing.Unlock()
+
+	t.mu.Lock()
+	if t.cached != nil && (t.cached.Status != entity.LoadTestStatusRunning || time.Since(t.cachedAt) < loadTestSnapshotMaxAge) {
+		cached := t.cached
+		t.mu.Unlock()
+		return cached
+	}
+
+	report := t.report
+	latencies := append([]float64(nil), t.latencies...)
+	queryLatencies := make([][]float64, len(t.queryLatencies))
+	for i, values := range t.queryLatencies {
+		queryLatencies[i] = append([]float64(nil), values...)
+	}
+	queryErrors := append([]int64(nil), t.queryErrors...)
+	serverStats := t.serverStats
+	report.ErrorSamples = append([]string{}, t.errorSamples...)
+	t.mu.Unlock()
+
+	end := time.Now()
+	if report.FinishedAt != nil {
+		end = *report.FinishedAt
+	}
+	elapsed := end.Sub(report.StartedAt)
+	report.ElapsedMs = elapsed.Milliseconds()
+	report.Completed = int64(len(latencies))
+	report.LatencyMs = helper.Summarize(latencies)
+
+	seconds := elapsed.Seconds()
+	if seconds > 0 {
+		report.QPS = float64(report.Completed) / seconds
+	}
+
+	report.Queries = make([]*entity.LoadTestQueryReport, len(report.Config.Queries))
+	for i, q := range report.Config.Queries {
+		qr := &entity.LoadTestQueryReport{
+			Label:     q.Label,
+			Query:     q.Query,
+			Weight:    q.Weight,
+			Completed: int64(len(queryLatencies[i])),
+			Errors:    queryErrors[i],
+			LatencyMs: helper.Summarize(queryLatencies[i]),
+			Server:    serverStats[i],
+		}
+		if seconds > 0 {
+			qr.QPS = float64(qr.Completed) / seconds
+		}
+		report.Queries[i] = qr
+	}
+
+	t.mu.Lock()
+	// A test that finished meanwhile is summarized again on the next call
+	if t.report.Status == report.Status {
+		t.cached, t.cachedAt = &report, time.Now()
+	}
+	t.mu.Unlock()
+
+	return &report
+}
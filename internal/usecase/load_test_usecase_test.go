@@ -0,0 +1,118 @@
+package usecase_test
+
+import (
+	"testing"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/usecase"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeLoadTestConfig(t *testing.T) {
+	testcases := []struct {
+		name    string
+		config  entity.LoadTestConfig
+		want    entity.LoadTestConfig
+		wantErr bool
+	}{
+		{
+			name: "Fills Defaults",
+			config: entity.LoadTestConfig{
+				Queries: []entity.LoadTestQuery{{Query: " SELECT 1 "}},
+			},
+			want: entity.LoadTestConfig{
+				Queries:         []entity.LoadTestQuery{{Label: "Query 1", Query: "SELECT 1", Weight: 1}},
+				Concurrency:     entity.DefaultLoadTestConcurrency,
+				DurationSeconds: entity.DefaultLoadTestDurationSeconds,
+			},
+		},
+		{
+			name: "Iterations Without Duration Use The Maximum Duration",
+			config: entity.LoadTestConfig{
+				Queries:     []entity.LoadTestQuery{{Label: "point", Query: "SELECT 1", Weight: 3}},
+				Concurrency: 8,
+				Iterations:  500,
+			},
+			want: entity.LoadTestConfig{
+				Queries:         []entity.LoadTestQuery{{Label: "point", Query: "SELECT 1", Weight: 3}},
+				Concurrency:     8,
+				DurationSeconds: entity.MaxLoadTestDurationSeconds,
+				Iterations:      500,
+			},
+		},
+		{
+			name:    "No Queries",
+			config:  entity.LoadTestConfig{},
+			wantErr: true,
+		},
+		{
+			name: "Empty Query",
+			config: entity.LoadTestConfig{
+				Queries: []entity.LoadTestQuery{{Query: "  "}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Write Query",
+			config: entity.LoadTestConfig{
+				Queries: []entity.LoadTestQuery{{Query: "SELECT 1"}, {Query: "ALTER TABLE events DELETE WHERE 1"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Negative Weight",
+			config: entity.LoadTestConfig{
+				Queries: []entity.LoadTestQuery{{Query: "SELECT 1", Weight: -1}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Concurrency Too High",
+			config: entity.LoadTestConfig{
+				Queries:     []entity.LoadTestQuery{{Query: "SELECT 1"}},
+				Concurrency: entity.MaxLoadTestConcurrency + 1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "Duration Too Long",
+			config: entity.LoadTestConfig{
+				Queries:         []entity.LoadTestQuery{{Query: "SELECT 1"}},
+				DurationSeconds: entity.MaxLoadTestDurationSeconds + 1,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := usecase.NormalizeLoadTestConfig(tc.config)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestPickWeighted(t *testing.T) {
+	weights := []int{1, 3, 2}
+
+	testcases := []struct {
+		n    int
+		want int
+	}{
+		{n: 0, want: 0},
+		{n: 1, want: 1},
+		{n: 3, want: 1},
+		{n: 4, want: 2},
+		{n: 5, want: 2},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.want, usecase.PickWeighted(weights, tc.n), "n=%d", tc.n)
+	}
+}
@@ -0,0 +1,165 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/clickhouse"
+	"github.com/rahmatrdn/go-ch-manager/internal/sqlparse"
+)
+
+var (
+	// exceptionCodePattern finds the code of a server exception, "code: 62, message: ..." from the native
+	// protocol and "Code: 62. DB::Exception: ..." from the HTTP interface
+	exceptionCodePattern = regexp.MustCompile(`(?i)\bcode: (\d+)`)
+	// syntaxPositionPattern finds the 1-based byte offset of a syntax error in the statement the server parsed
+	syntaxPositionPattern = regexp.MustCompile(`failed at position (\d+)`)
+)
+
+// FormatQuery formats each statement of the query with the server's formatQuery, or formatQuerySingleLine.
+// Statements the server cannot parse are returned as errors with their position and the query is then not
+// formatted. Nothing is run, comments are dropped by the server.
+func (u *ConnectionUsecase) FormatQuery(ctx context.Context, id int64, req entity.FormatRequest) (*entity.FormatResult, error) {
+	conn, statements, offsets, err := u.checkedStatements(ctx, id, req.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &entity.FormatResult{Errors: []entity.QuerySyntaxError{}}
+	formatted := make([]string, 0, len(statements))
+	for i, statement := range statements {
+		text, err := u.chClient.FormatQuery(ctx, conn, statement, req.SingleLine)
+		if err != nil {
+			syntaxErr, ok := LocateQueryError(err, req.Query, offsets[i], 0)
+			if !ok {
+				return nil, err
+			}
+			syntaxErr.Statement = i + 1
+			result.Errors = append(result.Errors, syntaxErr)
+			continue
+		}
+		formatted = append(formatted, strings.TrimSpace(text))
+	}
+
+	if len(result.Errors) == 0 {
+		result.Query = joinFormatted(formatted, req.SingleLine)
+	}
+	return result, nil
+}
+
+// ValidateQuery checks each statement of the query with EXPLAIN AST, or EXPLAIN SYNTAX in syntax mode, without
+// running it. EXPLAIN SYNTAX rejects anything but SELECT, so other statements are checked with EXPLAIN AST in
+// either mode. Errors carry the line and column of the exception in the submitted text.
+func (u *ConnectionUsecase) ValidateQuery(ctx context.Context, id int64, req entity.ValidateRequest) (*entity.ValidateResult, error) {
+	mode := strings.ToLower(strings.TrimSpace(req.Mode))
+	if mode == "" {
+		mode = entity.ValidateModeAST
+	}
+	if mode != entity.ValidateModeAST && mode != entity.ValidateModeSyntax {
+		return nil, fmt.Errorf("unknown validation mode %q, use %s or %s", req.Mode, entity.ValidateModeAST, entity.ValidateModeSyntax)
+	}
+
+	conn, statements, offsets, err := u.checkedStatements(ctx, id, req.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &entity.ValidateResult{
+		Mode:       mode,
+		Statements: make([]entity.ValidatedStatement, 0, len(statements)),
+		Errors:     []entity.QuerySyntaxError{},
+	}
+	for i, statement := range statements {
+		statementMode := mode
+		if sqlparse.Classify(statement) != sqlparse.KindSelect {
+			statementMode = entity.ValidateModeAST
+		}
+		kind := strings.ToUpper(statementMode)
+		prefix := len(clickhouse.ExplainStatement(kind, ""))
+
+		explain, err := u.chClient.ExplainQuery(ctx, conn, kind, statement)
+		if err != nil {
+			syntaxErr, ok := LocateQueryError(err, req.Query, offsets[i], prefix)
+			if !ok {
+				return nil, err
+			}
+			syntaxErr.Statement = i + 1
+			result.Errors = append(result.Errors, syntaxErr)
+			continue
+		}
+		result.Statements = append(result.Statements, entity.ValidatedStatement{Index: i + 1, Statement: statement, Mode: statementMode, Explain: explain})
+	}
+
+	result.Valid = len(result.Errors) == 0
+	return result, nil
+}
+
+// checkedStatements loads the connection and splits the query, offsets are where each statement starts in it
+func (u *ConnectionUsecase) checkedStatements(ctx context.Context, id int64, query string) (*entity.CHConnection, []string, []int, error) {
+	conn, err := u.findConnection(ctx, id)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	statements := SplitStatements(query)
+	if len(statements) == 0 {
+		return nil, nil, nil, fmt.Errorf("query has no statements")
+	}
+	if len(statements) > entity.MaxScriptStatements {
+		return nil, nil, nil, fmt.Errorf("query has %d statements, at most %d are allowed", len(statements), entity.MaxScriptStatements)
+	}
+
+	offsets := make([]int, len(statements))
+	from := 0
+	for i, statement := range statements {
+		offsets[i] = from + strings.Index(query[from:], statement)
+		from = offsets[i] + len(statement)
+	}
+	return conn, statements, offsets, nil
+}
+
+// LocateQueryError turns the server exception of a statement starting at offset in the query into a syntax
+// error. prefix is the length of what was sent before the statement, like EXPLAIN AST. It is false when err is
+// not a server exception, e.g. a network error.
+func LocateQueryError(err error, query string, offset, prefix int) (entity.QuerySyntaxError, bool) {
+	message := err.Error()
+	match := exceptionCodePattern.FindStringSubmatch(message)
+	if match == nil {
+		return entity.QuerySyntaxError{}, false
+	}
+
+	code, _ := strconv.Atoi(match[1])
+	syntaxErr := entity.QuerySyntaxError{Code: code, Message: message}
+	if match := syntaxPositionPattern.FindStringSubmatch(message); match != nil {
+		position, _ := strconv.Atoi(match[1])
+		// A position inside the prefix points at nothing the user wrote
+		if at := offset + position - 1 - prefix; position > prefix && at <= len(query) {
+			syntaxErr.Line, syntaxErr.Column = textPosition(query, at)
+		}
+	}
+	return syntaxErr, true
+}
+
+// textPosition returns the 1-based line and column, in characters, of a byte offset in text
+func textPosition(text string, offset int) (int, int) {
+	before := text[:offset]
+	lineStart := strings.LastIndexByte(before, '\n') + 1
+	return strings.Count(before, "\n") + 1, utf8.RuneCountInString(before[lineStart:]) + 1
+}
+
+// joinFormatted joins formatted statements into a script, a single statement keeps no semicolon
+func joinFormatted(statements []string, singleLine bool) string {
+	if len(statements) == 1 {
+		return statements[0]
+	}
+	separator := ";\n\n"
+	if singleLine {
+		separator = ";\n"
+	}
+	return strings.Join(statements, separator) + ";"
+}
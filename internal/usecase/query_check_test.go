@@ -0,0 +1,265 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/clickhouse"
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/sqlite"
+	"github.com/rahmatrdn/go-ch-manager/internal/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gormsqlite "gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestLocateQueryError(t *testing.T) {
+	query := "SELECT 1;\nSELECT *\nFORM t;\nSELECT 'é' FRM t"
+
+	testcases := []struct {
+		name    string
+		err     error
+		offset  int
+		prefix  int
+		want    entity.QuerySyntaxError
+		wantErr bool
+	}{
+		{
+			name:   "Native Exception In Second Statement",
+			err:    errors.New("code: 62, message: Syntax error: failed at position 10 ('FORM') (line 2, col 1): FORM t. Expected one of: ..."),
+			offset: 10,
+			want:   entity.QuerySyntaxError{Line: 3, Column: 1, Code: 62},
+		},
+		{
+			name:   "HTTP Exception After EXPLAIN AST",
+			err:    errors.New("sendQuery: [HTTP 400] response body: \"Code: 62. DB::Exception: Syntax error: failed at position 22 ('FORM'): FORM t. (SYNTAX_ERROR)\""),
+			offset: 10,
+			prefix: len("EXPLAIN AST "),
+			want:   entity.QuerySyntaxError{Line: 3, Column: 1, Code: 62},
+		},
+		{
+			name:   "Column Counts Characters",
+			err:    errors.New("code: 62, message: Syntax error: failed at position 13 ('FRM')"),
+			offset: 27,
+			want:   entity.QuerySyntaxError{Line: 4, Column: 12, Code: 62},
+		},
+		{
+			name:   "Position Inside Prefix",
+			err:    errors.New("code: 62, message: Syntax error: failed at position 3"),
+			offset: 0,
+			prefix: len("EXPLAIN AST "),
+			want:   entity.QuerySyntaxError{Code: 62},
+		},
+		{
+			name:   "No Position",
+			err:    errors.New("code: 60, message: Unknown table expression identifier 't'"),
+			offset: 0,
+			want:   entity.QuerySyntaxError{Code: 60},
+		},
+		{
+			name:    "Not A Server Exception",
+			err:     errors.New("dial tcp 127.0.0.1:9000: connect: connection refused"),
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := usecase.LocateQueryError(tc.err, query, tc.offset, tc.prefix)
+			if tc.wantErr {
+				assert.False(t, ok)
+				return
+			}
+			assert.True(t, ok)
+			tc.want.Message = tc.err.Error()
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+// checkClient parses like the server as far as the tests need: FORM is a syntax error at its position in what
+// was sent, EXPLAIN SYNTAX only takes SELECT and "unreachable" fails like a network error
+type checkClient struct {
+	clickhouse.ClickHouseClient
+	explained []string
+}
+
+func (c *checkClient) FormatQuery(ctx context.Context, conn *entity.CHConnection, query string, singleLine bool) (string, error) {
+	if err := c.parse(query); err != nil {
+		return "", err
+	}
+	return strings.Join(strings.Fields(query), " ") + "\n", nil
+}
+
+func (c *checkClient) ExplainQuery(ctx context.Context, conn *entity.CHConnection, kind string, query string) (string, error) {
+	c.explained = append(c.explained, clickhouse.ExplainStatement(kind, query))
+	if kind == "SYNTAX" && !strings.HasPrefix(query, "SELECT") {
+		return "", errors.New("code: 62, message: Syntax error: failed at position 16: Expected SELECT query")
+	}
+	if err := c.parse(clickhouse.ExplainStatement(kind, query)); err != nil {
+		return "", err
+	}
+	return kind + " of " + query, nil
+}
+
+func (c *checkClient) parse(sent string) error {
+	if strings.Contains(sent, "unreachable") {
+		return errors.New("dial tcp 127.0.0.1:9000: connect: connection refused")
+	}
+	if at := strings.Index(sent, "FORM"); at >= 0 {
+		return fmt.Errorf("code: 62, message: Syntax error: failed at position %d ('FORM')", at+1)
+	}
+	return nil
+}
+
+func newCheckUsecase(t *testing.T) (*usecase.ConnectionUsecase, *checkClient, int64) {
+	db, err := gorm.Open(gormsqlite.Open(filepath.Join(t.TempDir(), "check.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&entity.CHConnection{}))
+
+	connectionRepo := sqlite.NewConnectionRepository(db)
+	conn := &entity.CHConnection{Name: "local", Host: "localhost", Port: 9000}
+	require.NoError(t, connectionRepo.Create(context.Background(), conn))
+
+	client := &checkClient{}
+	return usecase.NewConnectionUsecase(connectionRepo, nil, nil, client, nil), client, conn.ID
+}
+
+func TestFormatQuery(t *testing.T) {
+	testcases := []struct {
+		name       string
+		req        entity.FormatRequest
+		want       string
+		wantErrors []entity.QuerySyntaxError
+		wantErr    string
+	}{
+		{
+			name: "Single Statement",
+			req:  entity.FormatRequest{Query: "SELECT   1\n FROM t"},
+			want: "SELECT 1 FROM t",
+		},
+		{
+			name: "Script",
+			req:  entity.FormatRequest{Query: "SELECT 1; SELECT 2"},
+			want: "SELECT 1;\n\nSELECT 2;",
+		},
+		{
+			name: "Single Line Script",
+			req:  entity.FormatRequest{Query: "SELECT 1; SELECT 2", SingleLine: true},
+			want: "SELECT 1;\nSELECT 2;",
+		},
+		{
+			name:       "Syntax Error Leaves The Query Unformatted",
+			req:        entity.FormatRequest{Query: "SELECT 1;\nSELECT *\nFORM t"},
+			wantErrors: []entity.QuerySyntaxError{{Statement: 2, Line: 3, Column: 1, Code: 62}},
+		},
+		{
+			name:    "No Statements",
+			req:     entity.FormatRequest{Query: " ; "},
+			wantErr: "query has no statements",
+		},
+		{
+			name:    "Not A Server Exception",
+			req:     entity.FormatRequest{Query: "SELECT unreachable"},
+			wantErr: "connection refused",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			u, _, id := newCheckUsecase(t)
+
+			result, err := u.FormatQuery(context.Background(), id, tc.req)
+			if tc.wantErr != "" {
+				assert.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, result.Query)
+			require.Len(t, result.Errors, len(tc.wantErrors))
+			for i, want := range tc.wantErrors {
+				want.Message = result.Errors[i].Message
+				assert.Equal(t, want, result.Errors[i])
+			}
+		})
+	}
+
+	t.Run("Unknown Connection", func(t *testing.T) {
+		u, _, id := newCheckUsecase(t)
+		_, err := u.FormatQuery(context.Background(), id+1, entity.FormatRequest{Query: "SELECT 1"})
+		assert.EqualError(t, err, fmt.Sprintf("connection %d not found", id+1))
+	})
+}
+
+func TestValidateQuery(t *testing.T) {
+	testcases := []struct {
+		name          string
+		req           entity.ValidateRequest
+		wantExplained []string
+		wantModes     []string
+		wantErrors    []entity.QuerySyntaxError
+		wantErr       string
+	}{
+		{
+			name:          "AST By Default",
+			req:           entity.ValidateRequest{Query: "SELECT 1; INSERT INTO t VALUES (1)"},
+			wantExplained: []string{"EXPLAIN AST SELECT 1", "EXPLAIN AST INSERT INTO t VALUES (1)"},
+			wantModes:     []string{entity.ValidateModeAST, entity.ValidateModeAST},
+		},
+		{
+			name:          "Syntax Mode Only Rewrites SELECT",
+			req:           entity.ValidateRequest{Query: "SELECT 1;\nCREATE TABLE t (id UInt64) ENGINE = Memory", Mode: "Syntax"},
+			wantExplained: []string{"EXPLAIN SYNTAX SELECT 1", "EXPLAIN AST CREATE TABLE t (id UInt64) ENGINE = Memory"},
+			wantModes:     []string{entity.ValidateModeSyntax, entity.ValidateModeAST},
+		},
+		{
+			name:          "Syntax Error Position Skips The EXPLAIN Prefix",
+			req:           entity.ValidateRequest{Query: "SELECT 1;\nSELECT *\nFORM t", Mode: entity.ValidateModeSyntax},
+			wantExplained: []string{"EXPLAIN SYNTAX SELECT 1", "EXPLAIN SYNTAX SELECT *\nFORM t"},
+			wantModes:     []string{entity.ValidateModeSyntax},
+			wantErrors:    []entity.QuerySyntaxError{{Statement: 2, Line: 3, Column: 1, Code: 62}},
+		},
+		{
+			name:    "Unknown Mode",
+			req:     entity.ValidateRequest{Query: "SELECT 1", Mode: "pipeline"},
+			wantErr: `unknown validation mode "pipeline"`,
+		},
+		{
+			name:    "Not A Server Exception",
+			req:     entity.ValidateRequest{Query: "SELECT unreachable"},
+			wantErr: "connection refused",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			u, client, id := newCheckUsecase(t)
+
+			result, err := u.ValidateQuery(context.Background(), id, tc.req)
+			if tc.wantErr != "" {
+				assert.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantExplained, client.explained)
+			assert.Equal(t, len(tc.wantErrors) == 0, result.Valid)
+
+			modes := make([]string, len(result.Statements))
+			for i, statement := range result.Statements {
+				modes[i] = statement.Mode
+			}
+			assert.Equal(t, tc.wantModes, modes)
+
+			require.Len(t, result.Errors, len(tc.wantErrors))
+			for i, want := range tc.wantErrors {
+				want.Message = result.Errors[i].Message
+				assert.Equal(t, want, result.Errors[i])
+			}
+		})
+	}
+}
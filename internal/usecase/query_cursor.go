@@ -0,0 +1,196 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/clickhouse"
+)
+
+const (
+	// cursorIdleTimeout closes cursors the console stopped fetching from
+	cursorIdleTimeout = 5 * time.Minute
+	// maxOpenCursors bounds the pooled ClickHouse connections held by unfinished paged results
+	maxOpenCursors = 16
+)
+
+// queryCursor is a paged console result whose query is still running on the server
+type queryCursor struct {
+	mu           sync.Mutex
+	connectionID int64
+	// sessionID is the console session the query runs in, whose only connection the cursor holds
+	sessionID string
+	reader    *clickhouse.ResultReader
+	location  *time.Location
+	cancel    context.CancelFunc
+	timer     *time.Timer
+}
+
+type cursorRegistry struct {
+	mu      sync.Mutex
+	cursors map[string]*queryCursor
+}
+
+func newCursorRegistry() *cursorRegistry {
+	return &cursorRegistry{cursors: make(map[string]*queryCursor)}
+}
+
+func (r *cursorRegistry) add(id string, cursor *queryCursor) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.cursors) >= maxOpenCursors {
+		return fmt.Errorf("too many open result cursors, fetch or close the pending results first")
+	}
+	if _, exists := r.cursors[id]; exists {
+		return fmt.Errorf("query id %s is already in use", id)
+	}
+
+	cursor.timer = time.AfterFunc(cursorIdleTimeout, func() { r.close(id) })
+	r.cursors[id] = cursor
+	return nil
+}
+
+func (r *cursorRegistry) get(id string) *queryCursor {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cursors[id]
+}
+
+// close stops the query of the cursor and forgets it, it reports whether the cursor existed
+func (r *cursorRegistry) close(id string) bool {
+	r.mu.Lock()
+	cursor, ok := r.cursors[id]
+	delete(r.cursors, id)
+	r.mu.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	cursor.timer.Stop()
+	cursor.mu.Lock()
+	defer cursor.mu.Unlock()
+	cursor.reader.Close()
+	cursor.cancel()
+	return true
+}
+
+// closeSession closes the cursors of a console session, which would otherwise keep its connection busy
+func (r *cursorRegistry) closeSession(sessionID string) {
+	r.mu.Lock()
+	var ids []string
+	for id, cursor := range r.cursors {
+		if cursor.sessionID == sessionID {
+			ids = append(ids, id)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, id := range ids {
+		r.close(id)
+	}
+}
+
+// executePaged starts a query whose rows are fetched page by page. The query outlives the request,
+// so it runs on its own context and is only tied to the request while the first page is read.
+func (u *ConnectionUsecase) executePaged(ctx context.Context, conn *entity.CHConnection, query, queryID string, pageSize int, loc *time.Location) (*entity.QueryResult, error) {
+	queryCtx, cancel := context.WithCancel(context.Background())
+	queryCtx = clickhouse.WithQuerySettings(clickhouse.WithQueryID(queryCtx, queryID), clickhouse.QuerySettingsFromContext(ctx))
+	queryCtx = clickhouse.WithQueryParameters(queryCtx, clickhouse.QueryParametersFromContext(ctx))
+	queryCtx = clickhouse.WithSession(queryCtx, clickhouse.SessionFromContext(ctx))
+
+	stopFollowing := context.AfterFunc(ctx, cancel)
+	defer stopFollowing()
+
+	reader, err := u.chClient.OpenQuery(queryCtx, conn, query)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	cursor := &queryCursor{
+		connectionID: conn.ID,
+		sessionID:    clickhouse.SessionFromContext(ctx),
+		reader:       reader,
+		location:     loc,
+		cancel:       cancel,
+	}
+	if err := u.cursors.add(queryID, cursor); err != nil {
+		reader.Close()
+		cancel()
+		return nil, err
+	}
+
+	return u.readPage(ctx, conn, queryID, cursor, pageSize)
+}
+
+// FetchQueryPage returns the next page of a paged console result
+func (u *ConnectionUsecase) FetchQueryPage(ctx context.Context, id int64, cursorID string, pageSize int) (*entity.QueryResult, error) {
+	if pageSize <= 0 {
+		pageSize = entity.DefaultResultPageSize
+	}
+	if pageSize > entity.MaxResultPageSize {
+		return nil, fmt.Errorf("page size must be at most %d", entity.MaxResultPageSize)
+	}
+
+	cursor := u.cursors.get(cursorID)
+	if cursor == nil || cursor.connectionID != id {
+		return nil, fmt.Errorf("result cursor not found, it may have expired")
+	}
+
+	conn, err := u.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if conn == nil {
+		return nil, fmt.Errorf("connection not found")
+	}
+
+	return u.readPage(ctx, conn, cursorID, cursor, pageSize)
+}
+
+func (u *ConnectionUsecase) readPage(ctx context.Context, conn *entity.CHConnection, cursorID string, cursor *queryCursor, pageSize int) (*entity.QueryResult, error) {
+	cursor.mu.Lock()
+	reader := cursor.reader
+	result := &entity.QueryResult{
+		QueryID:     reader.QueryID(),
+		Columns:     reader.Columns(),
+		ColumnTypes: reader.ColumnTypes(),
+		Rows:        make([]map[string]interface{}, 0, pageSize),
+	}
+
+	maxRows, maxBytes := conn.ResultLimits()
+	err := clickhouse.ReadRows(reader, result, pageSize, maxRows, maxBytes)
+	cursor.mu.Unlock()
+	clickhouse.EncodeResult(result, cursor.location)
+
+	if err != nil {
+		u.cursors.close(cursorID)
+		return nil, err
+	}
+
+	if result.HasMore {
+		cursor.timer.Reset(cursorIdleTimeout)
+		result.CursorID = cursorID
+		return result, nil
+	}
+
+	u.cursors.close(cursorID)
+	result.Stats = reader.Stats(ctx)
+	return result, nil
+}
+
+// CloseQueryCursor drops a paged result that is no longer needed and stops its query
+func (u *ConnectionUsecase) CloseQueryCursor(ctx context.Context, id int64, cursorID string) error {
+	cursor := u.cursors.get(cursorID)
+	if cursor == nil || cursor.connectionID != id {
+		return fmt.Errorf("result cursor not found, it may have expired")
+	}
+
+	u.cursors.close(cursorID)
+	return nil
+}
@@ -0,0 +1,144 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/clickhouse"
+)
+
+// maxExportFileNameLength keeps download names within common file system limits
+const maxExportFileNameLength = 200
+
+// ExportQuery re-runs a console query and returns its result encoded in the requested format.
+// The query runs for as long as the stream is read, ctx must stay alive until it is closed.
+func (u *ConnectionUsecase) ExportQuery(ctx context.Context, id int64, req entity.ExportRequest) (io.ReadCloser, entity.ExportFormat, error) {
+	format, ok := entity.FindExportFormat(req.Format)
+	if !ok {
+		return nil, format, fmt.Errorf("unknown export format %q", req.Format)
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		return nil, format, fmt.Errorf("query is required")
+	}
+
+	conn, err := u.findConnection(ctx, id)
+	if err != nil {
+		return nil, format, err
+	}
+	if err := u.guard.Check(conn, SplitStatements(req.Query), req.Confirm); err != nil {
+		return nil, format, err
+	}
+
+	if req.QueryID != "" {
+		if err := ValidateQueryID(req.QueryID); err != nil {
+			return nil, format, err
+		}
+		ctx = clickhouse.WithQueryID(ctx, req.QueryID)
+	}
+
+	loc, err := ResolveTimezone(req.Timezone)
+	if err != nil {
+		return nil, format, err
+	}
+	ctx = clickhouse.WithTimezone(ctx, loc)
+
+	params, err := BindQueryParameters(req.Query, req.Parameters)
+	if err != nil {
+		return nil, format, err
+	}
+	ctx = clickhouse.WithQueryParameters(ctx, params)
+
+	ctx, _, err = withQuerySettings(ctx, req.Settings)
+	if err != nil {
+		return nil, format, err
+	}
+
+	ctx, err = u.sessionContext(ctx, id, req.SessionID)
+	if err != nil {
+		return nil, format, err
+	}
+
+	stream, err := u.chClient.OpenExport(ctx, conn, req.Query, format)
+	if err != nil {
+		return nil, format, err
+	}
+
+	if format.Name == entity.ExportFormatXLSX {
+		// A workbook over the row limit fails at the last row, it is written out first so the failure
+		// is an error response rather than a truncated download
+		stream, err = spoolExport(stream)
+		if err != nil {
+			return nil, format, err
+		}
+	}
+
+	return stream, format, nil
+}
+
+// spoolExport writes the whole stream to a temporary file and returns the file, which is removed on close
+func spoolExport(stream io.ReadCloser) (io.ReadCloser, error) {
+	defer stream.Close()
+
+	file, err := os.CreateTemp("", "ch-manager-export-*")
+	if err != nil {
+		return nil, err
+	}
+	spooled := &spooledExport{File: file}
+
+	if _, err := io.Copy(file, stream); err != nil {
+		spooled.Close()
+		return nil, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		spooled.Close()
+		return nil, err
+	}
+	return spooled, nil
+}
+
+// spooledExport is an export read back from its temporary file
+type spooledExport struct {
+	*os.File
+}
+
+func (s *spooledExport) Close() error {
+	err := s.File.Close()
+	_ = os.Remove(s.Name())
+	return err
+}
+
+// ExportFileName returns the download name of an export. The requested name is kept apart from characters
+// that are unsafe in file names; without one the name is derived from the time of the export.
+func ExportFileName(name string, format entity.ExportFormat, now time.Time) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r == '/' || r == '\\' || r == ':' || r == '*' || r == '?' || r == '"' || r == '<' || r == '>' || r == '|':
+			return '_'
+		case unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(name))
+
+	ext := "." + format.Extension
+	if strings.EqualFold(path.Ext(name), ext) {
+		name = name[:len(name)-len(ext)]
+	}
+	name = strings.Trim(name, " .")
+
+	if name == "" {
+		name = "query-result-" + now.Format("20060102-150405")
+	}
+	if runes := []rune(name); len(runes) > maxExportFileNameLength {
+		name = string(runes[:maxExportFileNameLength])
+	}
+
+	return name + ext
+}
@@ -0,0 +1,40 @@
+package usecase_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/usecase"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportFileName(t *testing.T) {
+	csv, _ := entity.FindExportFormat(entity.ExportFormatCSV)
+	xlsx, _ := entity.FindExportFormat(entity.ExportFormatXLSX)
+	now := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	testcases := []struct {
+		name     string
+		fileName string
+		format   entity.ExportFormat
+		want     string
+	}{
+		{name: "Generated", fileName: "", format: csv, want: "query-result-20260304-050607.csv"},
+		{name: "Kept", fileName: "daily revenue", format: csv, want: "daily revenue.csv"},
+		{name: "Extension Not Doubled", fileName: "report.XLSX", format: xlsx, want: "report.xlsx"},
+		{name: "Other Extension Kept", fileName: "report.v2", format: csv, want: "report.v2.csv"},
+		{name: "Unicode Kept", fileName: "penjualan_ñ", format: csv, want: "penjualan_ñ.csv"},
+		{name: "Path Separators Replaced", fileName: "../etc/passwd", format: csv, want: "_etc_passwd.csv"},
+		{name: "Control Characters Dropped", fileName: "a\r\nb", format: csv, want: "ab.csv"},
+		{name: "Only Dots", fileName: "..", format: csv, want: "query-result-20260304-050607.csv"},
+		{name: "Too Long", fileName: strings.Repeat("x", 300), format: csv, want: strings.Repeat("x", 200) + ".csv"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, usecase.ExportFileName(tc.fileName, tc.format, now))
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+)
+
+type userKey struct{}
+
+// WithUser returns a context recording who makes the request, e.g. the user reported by an authenticating proxy
+func WithUser(ctx context.Context, user string) context.Context {
+	if user == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFromContext returns the user stored by WithUser
+func UserFromContext(ctx context.Context) string {
+	user, _ := ctx.Value(userKey{}).(string)
+	return user
+}
+
+// historyRun is a console run that is recorded in the history once its outcome is known
+type historyRun struct {
+	u     *ConnectionUsecase
+	conn  *entity.CHConnection
+	entry *entity.QueryHistory
+	start time.Time
+}
+
+func (u *ConnectionUsecase) startHistory(ctx context.Context, conn *entity.CHConnection, query string, params entity.QueryParameters, settings entity.QuerySettings) *historyRun {
+	return &historyRun{
+		u:    u,
+		conn: conn,
+		entry: &entity.QueryHistory{
+			ConnectionID: conn.ID,
+			Query:        query,
+			Parameters:   params,
+			Settings:     settings,
+			Database:     conn.Database,
+			User:         UserFromContext(ctx),
+		},
+		start: time.Now(),
+	}
+}
+
+// finish records the run with the server stats, when known, or the time measured by the manager
+func (r *historyRun) finish(stats *entity.QueryStats, err error) {
+	entry := r.entry
+	entry.DurationMs = time.Since(r.start).Milliseconds()
+	if stats != nil {
+		if stats.ExecutionTimeMs > 0 {
+			entry.DurationMs = stats.ExecutionTimeMs
+		}
+		entry.RowsRead = stats.RowsRead
+		entry.BytesRead = stats.BytesRead
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	// Recorded in the background, the request context may already be cancelled
+	go r.u.saveHistory(context.Background(), r.conn, entry)
+}
+
+// saveHistory adds the run to the history, or folds it into the latest entry when that ran the same query,
+// and prunes the history to the connection's retention
+func (u *ConnectionUsecase) saveHistory(ctx context.Context, conn *entity.CHConnection, entry *entity.QueryHistory) {
+	u.historyMu.Lock()
+	defer u.historyMu.Unlock()
+
+	entry.RunCount = 1
+	latest, err := u.historyRepo.FindLatest(ctx, conn.ID)
+	if err == nil && latest != nil && latest.SameRun(entry) {
+		latest.RunCount++
+		latest.DurationMs = entry.DurationMs
+		latest.RowsRead = entry.RowsRead
+		latest.BytesRead = entry.BytesRead
+		latest.Error = entry.Error
+		latest.CreatedAt = time.Now()
+		_ = u.historyRepo.Update(ctx, latest)
+	} else {
+		_ = u.historyRepo.Create(ctx, entry)
+	}
+
+	_ = u.historyRepo.Prune(ctx, conn.ID, conn.HistoryLimit())
+}
+
+// GetQueryHistory returns the newest history entries of the connection that match the filter
+func (u *ConnectionUsecase) GetQueryHistory(ctx context.Context, connectionID int64, filter entity.HistoryFilter) ([]*entity.QueryHistory, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = entity.DefaultHistoryPageSize
+	}
+	if filter.Limit > entity.MaxHistoryPageSize {
+		return nil, fmt.Errorf("limit must be at most %d", entity.MaxHistoryPageSize)
+	}
+
+	return u.historyRepo.FindByConnectionID(ctx, connectionID, filter)
+}
+
+// PinQueryHistory pins or unpins a history entry, pinned entries are kept regardless of the retention
+func (u *ConnectionUsecase) PinQueryHistory(ctx context.Context, connectionID, historyID int64, pinned bool) error {
+	entry, err := u.historyRepo.FindByID(ctx, historyID)
+	if err != nil {
+		return err
+	}
+	if entry == nil || entry.ConnectionID != connectionID {
+		return fmt.Errorf("history entry %d not found", historyID)
+	}
+
+	return u.historyRepo.SetPinned(ctx, historyID, pinned)
+}
@@ -0,0 +1,109 @@
+package usecase
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/sqlparse"
+)
+
+// DetectQueryParameters returns the {name:Type} placeholders of a query in order of first appearance.
+// Placeholders inside string literals, quoted identifiers and comments are ignored.
+func DetectQueryParameters(query string) []entity.QueryParameter {
+	var params []entity.QueryParameter
+	seen := make(map[string]bool)
+
+	tokens := sqlparse.Tokenize(query)
+	for i := 0; i < len(tokens); i++ {
+		if !tokens[i].IsPunctuation("{") {
+			continue
+		}
+		param, end, ok := parsePlaceholder(query, tokens, i)
+		if !ok {
+			continue
+		}
+		if !seen[param.Name] {
+			seen[param.Name] = true
+			params = append(params, param)
+		}
+		i = end
+	}
+
+	return params
+}
+
+// BindQueryParameters checks that every placeholder of the query has a value and returns only those values
+func BindQueryParameters(query string, values entity.QueryParameters) (entity.QueryParameters, error) {
+	placeholders := DetectQueryParameters(query)
+	if len(placeholders) == 0 {
+		return nil, nil
+	}
+
+	bound := make(entity.QueryParameters, len(placeholders))
+	var missing []string
+	for _, p := range placeholders {
+		value, ok := values[p.Name]
+		if !ok {
+			missing = append(missing, p.Name)
+			continue
+		}
+		bound[p.Name] = value
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("missing value for query parameter(s): %s", strings.Join(missing, ", "))
+	}
+	return bound, nil
+}
+
+// parsePlaceholder reads "{name:Type}" from the token of the opening brace and returns the token of the
+// closing one
+func parsePlaceholder(query string, tokens []sqlparse.Token, start int) (entity.QueryParameter, int, bool) {
+	i := skipInlineSpace(tokens, start+1)
+	if i >= len(tokens) || tokens[i].Kind != sqlparse.Word {
+		return entity.QueryParameter{}, 0, false
+	}
+	name := tokens[i].Text
+
+	i = skipInlineSpace(tokens, i+1)
+	if i >= len(tokens) || !tokens[i].IsPunctuation(":") {
+		return entity.QueryParameter{}, 0, false
+	}
+
+	// The type may nest parentheses and quotes, e.g. {m:Map(String, Enum8('a' = 1))}
+	typeStart, depth := tokens[i].Pos+1, 0
+	for i++; i < len(tokens); i++ {
+		token := tokens[i]
+		switch {
+		case token.IsPunctuation("("):
+			depth++
+		case token.IsPunctuation(")"):
+			depth--
+		case token.IsPunctuation("{"), token.IsPunctuation(";"), token.Kind == sqlparse.Comment,
+			token.Kind == sqlparse.Whitespace && strings.Contains(token.Text, "\n"):
+			return entity.QueryParameter{}, 0, false
+		case token.IsPunctuation("}"):
+			if depth != 0 {
+				return entity.QueryParameter{}, 0, false
+			}
+			chType := strings.TrimSpace(query[typeStart:token.Pos])
+			if chType == "" {
+				return entity.QueryParameter{}, 0, false
+			}
+			return entity.QueryParameter{Name: name, Type: chType}, i, true
+		}
+	}
+
+	return entity.QueryParameter{}, 0, false
+}
+
+// skipInlineSpace returns the index of the first token at or after i that is not whitespace on the same line
+func skipInlineSpace(tokens []sqlparse.Token, i int) int {
+	for i < len(tokens) && tokens[i].Kind == sqlparse.Whitespace && !strings.Contains(tokens[i].Text, "\n") {
+		i++
+	}
+	return i
+}
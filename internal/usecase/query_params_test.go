@@ -0,0 +1,113 @@
+package usecase_test
+
+import (
+	"testing"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/usecase"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectQueryParameters(t *testing.T) {
+	testcases := []struct {
+		name  string
+		query string
+		want  []entity.QueryParameter
+	}{
+		{
+			name:  "None",
+			query: "SELECT 1",
+			want:  nil,
+		},
+		{
+			name:  "Single",
+			query: "SELECT * FROM events WHERE user_id = {uid:UInt64}",
+			want:  []entity.QueryParameter{{Name: "uid", Type: "UInt64"}},
+		},
+		{
+			name:  "Order Of Appearance And Duplicates",
+			query: "SELECT {b:String}, {a: Date}, {b:String}",
+			want:  []entity.QueryParameter{{Name: "b", Type: "String"}, {Name: "a", Type: "Date"}},
+		},
+		{
+			name:  "Nested Type",
+			query: "SELECT {m:Map(String, Enum8('a}' = 1))}, {d:DateTime64(3, 'UTC')}",
+			want: []entity.QueryParameter{
+				{Name: "m", Type: "Map(String, Enum8('a}' = 1))"},
+				{Name: "d", Type: "DateTime64(3, 'UTC')"},
+			},
+		},
+		{
+			name:  "Ignores Literals And Comments",
+			query: "SELECT '{a:UInt8}', `{b:UInt8}` -- {c:UInt8}\n/* {d:UInt8} */ FROM t WHERE x = {e:UInt8}",
+			want:  []entity.QueryParameter{{Name: "e", Type: "UInt8"}},
+		},
+		{
+			name:  "Ignores Heredocs And Nested Comments",
+			query: "SELECT $x${a:UInt8}$x$ /* /* {b:UInt8} */ {c:UInt8} */ # {d:UInt8}\n, {e:UInt8}",
+			want:  []entity.QueryParameter{{Name: "e", Type: "UInt8"}},
+		},
+		{
+			name:  "Escaped Quote In Literal",
+			query: "SELECT 'it''s {a:UInt8}', 'x\\'{b:UInt8}', {c:UInt8}",
+			want:  []entity.QueryParameter{{Name: "c", Type: "UInt8"}},
+		},
+		{
+			name:  "Not Placeholders",
+			query: "SELECT map('k', 1), {}, {1:UInt8}, {a:}, {a UInt8}",
+			want:  nil,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, usecase.DetectQueryParameters(tc.query))
+		})
+	}
+}
+
+func TestBindQueryParameters(t *testing.T) {
+	testcases := []struct {
+		name    string
+		query   string
+		values  entity.QueryParameters
+		want    entity.QueryParameters
+		wantErr bool
+	}{
+		{
+			name:  "No Placeholders",
+			query: "SELECT 1",
+			want:  nil,
+		},
+		{
+			name:   "Unused Values Dropped",
+			query:  "SELECT {uid:UInt64}",
+			values: entity.QueryParameters{"uid": "42", "other": "x"},
+			want:   entity.QueryParameters{"uid": "42"},
+		},
+		{
+			name:   "Empty Value Allowed",
+			query:  "SELECT {s:String}",
+			values: entity.QueryParameters{"s": ""},
+			want:   entity.QueryParameters{"s": ""},
+		},
+		{
+			name:    "Missing Value",
+			query:   "SELECT {uid:UInt64}, {day:Date}",
+			values:  entity.QueryParameters{"uid": "42"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := usecase.BindQueryParameters(tc.query, tc.values)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/clickhouse"
+	"github.com/rahmatrdn/go-ch-manager/internal/sqlparse"
+)
+
+// SplitStatements splits a script on the semicolons that end its statements. Semicolons inside string
+// literals, quoted identifiers and comments are kept, and chunks holding only whitespace or comments are dropped.
+func SplitStatements(script string) []string {
+	return sqlparse.Split(script)
+}
+
+// LeadingKeyword returns the upper-cased first word of a statement after any comments, or "(" for a
+// parenthesized query. It is empty when the statement holds only whitespace or comments.
+func LeadingKeyword(statement string) string {
+	return sqlparse.LeadingKeyword(statement)
+}
+
+// StatementReturnsRows reports whether a statement produces a result set rather than only a status
+func StatementReturnsRows(statement string) bool {
+	return sqlparse.Analyze(statement).ReturnsRows()
+}
+
+// ExecuteScript runs the statements of a script in order and returns one result block per statement.
+// Unless opts.ContinueOnError is set, the statements after a failure are skipped.
+func (u *ConnectionUsecase) ExecuteScript(ctx context.Context, id int64, script string, opts entity.ScriptOptions) (*entity.ScriptResult, error) {
+	conn, err := u.findConnection(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	statements := SplitStatements(script)
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("script has no statements")
+	}
+	if len(statements) > entity.MaxScriptStatements {
+		return nil, fmt.Errorf("script has %d statements, at most %d are allowed", len(statements), entity.MaxScriptStatements)
+	}
+	// One confirmation covers every guarded statement of the script, an approved change request needs none
+	if approvedChangeFromContext(ctx) != 0 {
+		err = u.guard.CheckApproved(conn, statements)
+	} else {
+		err = u.guard.Check(conn, statements, opts.Confirm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	loc, err := ResolveTimezone(opts.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check every placeholder up front so a missing value does not fail the script halfway
+	params, err := BindQueryParameters(script, opts.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, settings, err := withQuerySettings(ctx, opts.Settings)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err = u.sessionContext(ctx, id, opts.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	run := u.startHistory(ctx, conn, script, params, settings)
+	start := time.Now()
+	result := &entity.ScriptResult{
+		Statements:      make([]entity.ScriptStatementResult, 0, len(statements)),
+		ContinueOnError: opts.ContinueOnError,
+	}
+
+	stop := false
+	for i, statement := range statements {
+		block := entity.ScriptStatementResult{Index: i + 1, Statement: statement}
+
+		if stop || ctx.Err() != nil {
+			block.Status = entity.ScriptStatementSkipped
+			result.Skipped++
+			result.Statements = append(result.Statements, block)
+			continue
+		}
+
+		if err := u.executeScriptStatement(ctx, conn, statement, params, loc, &block); err != nil {
+			block.Status = entity.ScriptStatementError
+			block.Error = err.Error()
+			result.Failed++
+			stop = !opts.ContinueOnError
+		} else {
+			block.Status = entity.ScriptStatementOK
+			result.Succeeded++
+		}
+		result.Statements = append(result.Statements, block)
+	}
+	result.DurationMs = time.Since(start).Milliseconds()
+
+	run.finish(nil, scriptError(result))
+	return result, nil
+}
+
+func (u *ConnectionUsecase) executeScriptStatement(ctx context.Context, conn *entity.CHConnection, statement string, params entity.QueryParameters, loc *time.Location, block *entity.ScriptStatementResult) error {
+	bound, err := BindQueryParameters(statement, params)
+	if err != nil {
+		return err
+	}
+	ctx = clickhouse.WithQueryParameters(ctx, bound)
+
+	if !StatementReturnsRows(statement) {
+		stats, err := u.chClient.ExecuteStatement(ctx, conn, statement)
+		if err != nil {
+			return err
+		}
+		block.Stats = stats
+		return nil
+	}
+
+	rows, err := u.chClient.ExecuteQueryWithResults(ctx, conn, statement)
+	if err != nil {
+		return err
+	}
+	clickhouse.EncodeResult(rows, loc)
+	block.Result = rows
+	block.Stats = rows.Stats
+	return nil
+}
+
+// scriptError describes the first failed statement of a script for its history entry, nil when none failed
+func scriptError(result *entity.ScriptResult) error {
+	for _, block := range result.Statements {
+		if block.Status == entity.ScriptStatementError {
+			return fmt.Errorf("statement %d of %d: %s", block.Index, len(result.Statements), block.Error)
+		}
+	}
+	return nil
+}
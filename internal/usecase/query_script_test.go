@@ -0,0 +1,83 @@
+package usecase_test
+
+import (
+	"testing"
+
+	"github.com/rahmatrdn/go-ch-manager/internal/usecase"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitStatements(t *testing.T) {
+	testcases := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{
+			name:   "Single Without Semicolon",
+			script: "SELECT 1",
+			want:   []string{"SELECT 1"},
+		},
+		{
+			name:   "Several Statements",
+			script: "CREATE TABLE t (x UInt8) ENGINE = Memory;\nINSERT INTO t VALUES (1);\nSELECT * FROM t;",
+			want: []string{
+				"CREATE TABLE t (x UInt8) ENGINE = Memory",
+				"INSERT INTO t VALUES (1)",
+				"SELECT * FROM t",
+			},
+		},
+		{
+			name:   "Semicolons In Literals",
+			script: `SELECT 'a;b', "c;d", ` + "`e;f`" + `; SELECT 'it\'s;', 'x'';'`,
+			want:   []string{`SELECT 'a;b', "c;d", ` + "`e;f`", `SELECT 'it\'s;', 'x'';'`},
+		},
+		{
+			name:   "Semicolons In Comments",
+			script: "SELECT 1 -- first; still a comment\n; /* block; comment */ SELECT 2",
+			want:   []string{"SELECT 1 -- first; still a comment", "/* block; comment */ SELECT 2"},
+		},
+		{
+			name:   "Empty And Comment Only Chunks",
+			script: ";; SELECT 1;\n  ;\n-- trailing comment\n/* done */",
+			want:   []string{"SELECT 1"},
+		},
+		{
+			name:   "Empty Script",
+			script: "   ",
+			want:   nil,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, usecase.SplitStatements(tc.script))
+		})
+	}
+}
+
+func TestStatementReturnsRows(t *testing.T) {
+	testcases := []struct {
+		name      string
+		statement string
+		keyword   string
+		want      bool
+	}{
+		{name: "Select", statement: "select 1", keyword: "SELECT", want: true},
+		{name: "With", statement: "WITH 1 AS x SELECT x", keyword: "WITH", want: true},
+		{name: "Leading Comments", statement: "-- note\n/* more */ SHOW TABLES", keyword: "SHOW", want: true},
+		{name: "Parenthesized", statement: "(SELECT 1) UNION ALL (SELECT 2)", keyword: "(", want: true},
+		{name: "Describe Short", statement: "DESC system.one", keyword: "DESC", want: true},
+		{name: "Insert", statement: "INSERT INTO t SELECT 1", keyword: "INSERT", want: false},
+		{name: "Create", statement: "CREATE TABLE t (x UInt8) ENGINE = Memory", keyword: "CREATE", want: false},
+		{name: "Set", statement: "SET max_threads = 1", keyword: "SET", want: false},
+		{name: "Comment Only", statement: "-- nothing", keyword: "", want: false},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.keyword, usecase.LeadingKeyword(tc.statement))
+			assert.Equal(t, tc.want, usecase.StatementReturnsRows(tc.statement))
+		})
+	}
+}
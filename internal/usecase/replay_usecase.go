@@ -0,0 +1,338 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/helper"
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/clickhouse"
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/sqlite"
+)
+
+// replayRunsLimit is the number of past replays listed per connection
+const replayRunsLimit = 50
+
+type ReplayUsecase interface {
+	StartReplay(ctx context.Context, run *entity.ReplayRun) (*entity.ReplayRun, error)
+	GetReplays(ctx context.Context, sourceConnectionID int64) ([]*entity.ReplayRun, error)
+	GetReplay(ctx context.Context, id int64) (*entity.ReplayRun, error)
+	CancelReplay(ctx context.Context, id int64) error
+	DeleteReplay(ctx context.Context, id int64) error
+}
+
+type replayUsecase struct {
+	replayRepo     sqlite.ReplayRepository
+	connectionRepo sqlite.ConnectionRepository
+	chClient       clickhouse.ClickHouseClient
+
+	mu sync.Mutex
+	// running holds the cancel functions of the replays running in the background, by run ID
+	running map[int64]context.CancelFunc
+}
+
+func NewReplayUsecase(
+	replayRepo sqlite.ReplayRepository,
+	connectionRepo sqlite.ConnectionRepository,
+	chClient clickhouse.ClickHouseClient,
+) ReplayUsecase {
+	return &replayUsecase{
+		replayRepo:     replayRepo,
+		connectionRepo: connectionRepo,
+		chClient:       chClient,
+		running:        make(map[int64]context.CancelFunc),
+	}
+}
+
+// normalizeReplayRun validates the replay options and fills in the defaults
+func normalizeReplayRun(run *entity.ReplayRun) error {
+	if run.WindowHours == 0 {
+		run.WindowHours = entity.DefaultReplayWindowHours
+	}
+	if run.WindowHours < 0 || run.WindowHours > entity.MaxReplayWindowHours {
+		return fmt.Errorf("window must be between 1 and %d hours", entity.MaxReplayWindowHours)
+	}
+
+	if run.MaxFingerprints == 0 {
+		run.MaxFingerprints = entity.DefaultReplayFingerprints
+	}
+	if run.MaxFingerprints < 0 || run.MaxFingerprints > entity.MaxReplayFingerprints {
+		return fmt.Errorf("fingerprints must be between 1 and %d", entity.MaxReplayFingerprints)
+	}
+
+	if run.SamplesPerFingerprint == 0 {
+		run.SamplesPerFingerprint = entity.DefaultReplaySamples
+	}
+	if run.SamplesPerFingerprint < 0 || run.SamplesPerFingerprint > entity.MaxReplaySamples {
+		return fmt.Errorf("samples per fingerprint must be between 1 and %d", entity.MaxReplaySamples)
+	}
+
+	if run.RegressionThresholdPct <= 0 {
+		run.RegressionThresholdPct = entity.DefaultReplayRegressionThresholdPct
+	}
+
+	return nil
+}
+
+// StartReplay saves the run and replays it in the background, the returned run is still running. Its
+// progress and result are read with GetReplay.
+func (u *replayUsecase) StartReplay(ctx context.Context, run *entity.ReplayRun) (*entity.ReplayRun, error) {
+	if err := normalizeReplayRun(run); err != nil {
+		return nil, err
+	}
+
+	source, err := u.connectionRepo.FindByID(ctx, run.SourceConnectionID)
+	if err != nil {
+		return nil, err
+	}
+	if source == nil {
+		return nil, fmt.Errorf("source connection not found")
+	}
+
+	target, err := u.connectionRepo.FindByID(ctx, run.TargetConnectionID)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, fmt.Errorf("target connection not found")
+	}
+
+	run.ID = 0
+	run.Status = entity.ReplayStatusRunning
+	run.StartedAt = time.Now()
+	run.FinishedAt = nil
+	run.Fingerprints = nil
+	run.TotalSamples, run.ReplayedSamples = 0, 0
+	if err := u.replayRepo.Create(ctx, run); err != nil {
+		return nil, err
+	}
+
+	// The request context ends with the HTTP call, the replay keeps running in the background
+	runCtx, cancel := context.WithCancel(context.Background())
+	u.mu.Lock()
+	u.running[run.ID] = cancel
+	u.mu.Unlock()
+
+	started := *run
+	go u.run(runCtx, source, target, run)
+
+	return &started, nil
+}
+
+// run replays the samples and saves the run as it goes, until it is done or cancelled
+func (u *replayUsecase) run(ctx context.Context, source, target *entity.CHConnection, run *entity.ReplayRun) {
+	funcName := "ReplayUsecase.run"
+	defer func() {
+		u.mu.Lock()
+		cancel := u.running[run.ID]
+		delete(u.running, run.ID)
+		u.mu.Unlock()
+		cancel()
+	}()
+
+	err := u.executeReplay(ctx, source, target, run)
+	switch {
+	case ctx.Err() != nil:
+		run.Status = entity.ReplayStatusCancelled
+	case err != nil:
+		run.Status = entity.ReplayStatusFailed
+		run.Error = err.Error()
+	default:
+		run.Status = entity.ReplayStatusCompleted
+	}
+
+	now := time.Now()
+	run.FinishedAt = &now
+	// The run context may be cancelled already, the final state is saved with a fresh one
+	saveCtx, cancelSave := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelSave()
+	if err := u.replayRepo.Update(saveCtx, run); err != nil {
+		helper.LogError("save replay", funcName, err, entity.CaptureFields{
+			"replay_id": helper.ToString(run.ID),
+		}, "")
+	}
+}
+
+func (u *replayUsecase) executeReplay(ctx context.Context, source, target *entity.CHConnection, run *entity.ReplayRun) error {
+	logSamples, err := u.chClient.GetQueryLogSamples(ctx, source, run.WindowHours, run.MaxFingerprints, run.SamplesPerFingerprint)
+	if err != nil {
+		return fmt.Errorf("failed to sample query log: %w", err)
+	}
+
+	for _, logSample := range logSamples {
+		run.TotalSamples += len(logSample.Queries)
+	}
+	if err := u.replayRepo.Update(ctx, run); err != nil {
+		return err
+	}
+
+	for _, logSample := range logSamples {
+		samples := make([]*entity.ReplaySample, 0, len(logSample.Queries))
+		for i, query := range logSample.Queries {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			sample := &entity.ReplaySample{Query: query}
+			if i < len(logSample.DurationsMs) {
+				sample.SourceDurationMs = float64(logSample.DurationsMs[i])
+			}
+			if i < len(logSample.BytesRead) {
+				sample.SourceBytesRead = logSample.BytesRead[i]
+			}
+
+			u.replaySample(ctx, source, target, run.CompareResults, sample)
+			samples = append(samples, sample)
+		}
+
+		result := SummarizeReplaySamples(samples, run.RegressionThresholdPct)
+		result.Fingerprint = logSample.Fingerprint
+		result.Executions = logSample.Executions
+		result.LoggedAvgDuration = logSample.AvgDurationMs
+		run.Fingerprints = append(run.Fingerprints, result)
+		run.ReplayedSamples += len(samples)
+		tallyReplay(run)
+
+		// Saved after every fingerprint so GetReplay shows the progress
+		if err := u.replayRepo.Update(ctx, run); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tallyReplay counts the errors, mismatches and regressions of the fingerprints replayed so far
+func tallyReplay(run *entity.ReplayRun) {
+	run.FingerprintCount = len(run.Fingerprints)
+	run.ErrorCount, run.MismatchCount, run.RegressionCount = 0, 0, 0
+	for _, fp := range run.Fingerprints {
+		run.ErrorCount += fp.Errors
+		run.MismatchCount += fp.HashMismatches
+		if fp.Regressed {
+			run.RegressionCount++
+		}
+	}
+}
+
+// replaySample runs a logged statement on the target and, when asked, compares the result hashes of both sides
+func (u *replayUsecase) replaySample(ctx context.Context, source, target *entity.CHConnection, compareResults bool, sample *entity.ReplaySample) {
+	stats, err := u.chClient.ExecuteQueryWithStats(ctx, target, sample.Query)
+	if err != nil {
+		sample.Error = err.Error()
+		return
+	}
+	sample.TargetDurationMs = float64(stats.ExecutionTimeMs)
+	sample.TargetBytesRead = stats.BytesRead
+
+	if !compareResults {
+		return
+	}
+
+	sourceHash, err := u.chClient.GetResultHash(ctx, source, sample.Query)
+	if err != nil {
+		sample.Error = fmt.Sprintf("source result hash: %s", err.Error())
+		return
+	}
+	targetHash, err := u.chClient.GetResultHash(ctx, target, sample.Query)
+	if err != nil {
+		sample.Error = fmt.Sprintf("target result hash: %s", err.Error())
+		return
+	}
+
+	sample.HashChecked = true
+	sample.HashMismatch = !DiffResultHashes(sourceHash, targetHash).Equal
+}
+
+// SummarizeReplaySamples averages the successful samples of a fingerprint and flags it as regressed when
+// the target is slower or reads more bytes than the source by more than thresholdPct
+func SummarizeReplaySamples(samples []*entity.ReplaySample, thresholdPct float64) *entity.ReplayFingerprintResult {
+	result := &entity.ReplayFingerprintResult{
+		Replayed: len(samples),
+		Samples:  samples,
+	}
+
+	succeeded := 0
+	for _, s := range samples {
+		if s.HashMismatch {
+			result.HashMismatches++
+		}
+		if s.Error != "" {
+			result.Errors++
+			if len(result.ErrorSamples) < entity.MaxReplayErrorSamples {
+				result.ErrorSamples = append(result.ErrorSamples, s.Error)
+			}
+			continue
+		}
+
+		succeeded++
+		result.SourceDurationMs += s.SourceDurationMs
+		result.TargetDurationMs += s.TargetDurationMs
+		result.SourceBytesRead += float64(s.SourceBytesRead)
+		result.TargetBytesRead += float64(s.TargetBytesRead)
+	}
+
+	if succeeded > 0 {
+		result.SourceDurationMs /= float64(succeeded)
+		result.TargetDurationMs /= float64(succeeded)
+		result.SourceBytesRead /= float64(succeeded)
+		result.TargetBytesRead /= float64(succeeded)
+
+		result.DurationChangePct = changePct(result.SourceDurationMs, result.TargetDurationMs)
+		result.BytesReadChangePct = changePct(result.SourceBytesRead, result.TargetBytesRead)
+		result.Regressed = result.DurationChangePct > thresholdPct || result.BytesReadChangePct > thresholdPct
+	}
+
+	return result
+}
+
+func (u *replayUsecase) GetReplays(ctx context.Context, sourceConnectionID int64) ([]*entity.ReplayRun, error) {
+	return u.replayRepo.FindBySourceConnectionID(ctx, sourceConnectionID, replayRunsLimit)
+}
+
+func (u *replayUsecase) GetReplay(ctx context.Context, id int64) (*entity.ReplayRun, error) {
+	run, err := u.replayRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if run == nil {
+		return nil, fmt.Errorf("replay not found")
+	}
+	return run, nil
+}
+
+// CancelReplay stops a running replay, the fingerprints replayed so far are kept. A replay left running by
+// a restart of the manager is only marked as cancelled.
+func (u *replayUsecase) CancelReplay(ctx context.Context, id int64) error {
+	u.mu.Lock()
+	cancel, ok := u.running[id]
+	u.mu.Unlock()
+	if ok {
+		cancel()
+		return nil
+	}
+
+	run, err := u.GetReplay(ctx, id)
+	if err != nil {
+		return err
+	}
+	if run.Status != entity.ReplayStatusRunning {
+		return fmt.Errorf("replay is not running")
+	}
+	now := time.Now()
+	run.Status = entity.ReplayStatusCancelled
+	run.FinishedAt = &now
+	return u.replayRepo.Update(ctx, run)
+}
+
+func (u *replayUsecase) DeleteReplay(ctx context.Context, id int64) error {
+	u.mu.Lock()
+	_, running := u.running[id]
+	u.mu.Unlock()
+	if running {
+		return fmt.Errorf("replay is still running, cancel it first")
+	}
+	return u.replayRepo.Delete(ctx, id)
+}
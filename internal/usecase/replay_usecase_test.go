@@ -0,0 +1,179 @@
+package usecase_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/clickhouse"
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/sqlite"
+	"github.com/rahmatrdn/go-ch-manager/internal/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gormsqlite "gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestSummarizeReplaySamples(t *testing.T) {
+	testcases := []struct {
+		name    string
+		samples []*entity.ReplaySample
+		want    *entity.ReplayFingerprintResult
+	}{
+		{
+			name: "Averages Successful Samples",
+			samples: []*entity.ReplaySample{
+				{SourceDurationMs: 100, TargetDurationMs: 150, SourceBytesRead: 1000, TargetBytesRead: 1000},
+				{SourceDurationMs: 300, TargetDurationMs: 450, SourceBytesRead: 3000, TargetBytesRead: 3000},
+			},
+			want: &entity.ReplayFingerprintResult{
+				Replayed:           2,
+				SourceDurationMs:   200,
+				TargetDurationMs:   300,
+				DurationChangePct:  50,
+				SourceBytesRead:    2000,
+				TargetBytesRead:    2000,
+				BytesReadChangePct: 0,
+				Regressed:          true,
+			},
+		},
+		{
+			name: "Within Threshold",
+			samples: []*entity.ReplaySample{
+				{SourceDurationMs: 100, TargetDurationMs: 110, SourceBytesRead: 1000, TargetBytesRead: 500},
+			},
+			want: &entity.ReplayFingerprintResult{
+				Replayed:           1,
+				SourceDurationMs:   100,
+				TargetDurationMs:   110,
+				DurationChangePct:  10,
+				SourceBytesRead:    1000,
+				TargetBytesRead:    500,
+				BytesReadChangePct: -50,
+			},
+		},
+		{
+			name: "Errors And Mismatches Are Counted",
+			samples: []*entity.ReplaySample{
+				{SourceDurationMs: 100, Error: "Unknown table"},
+				{SourceDurationMs: 100, TargetDurationMs: 100, HashChecked: true, HashMismatch: true},
+			},
+			want: &entity.ReplayFingerprintResult{
+				Replayed:         2,
+				Errors:           1,
+				HashMismatches:   1,
+				SourceDurationMs: 100,
+				TargetDurationMs: 100,
+				ErrorSamples:     []string{"Unknown table"},
+			},
+		},
+		{
+			name: "All Samples Failed",
+			samples: []*entity.ReplaySample{
+				{SourceDurationMs: 100, Error: "timeout"},
+			},
+			want: &entity.ReplayFingerprintResult{
+				Replayed:     1,
+				Errors:       1,
+				ErrorSamples: []string{"timeout"},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.want.Samples = tc.samples
+			assert.Equal(t, tc.want, usecase.SummarizeReplaySamples(tc.samples, entity.DefaultReplayRegressionThresholdPct))
+		})
+	}
+}
+
+// replayClient samples two fingerprints of two queries each. With block set the replayed queries wait
+// for the cancellation of the replay.
+type replayClient struct {
+	clickhouse.ClickHouseClient
+	block bool
+}
+
+func (c *replayClient) GetQueryLogSamples(ctx context.Context, conn *entity.CHConnection, windowHours, fingerprints, samples int) ([]entity.QueryLogSample, error) {
+	return []entity.QueryLogSample{
+		{Fingerprint: "SELECT ?", Queries: []string{"SELECT 1", "SELECT 2"}, DurationsMs: []uint64{10, 10}},
+		{Fingerprint: "SELECT ? + ?", Queries: []string{"SELECT 1 + 1", "SELECT 2 + 2"}, DurationsMs: []uint64{10, 10}},
+	}, nil
+}
+
+func (c *replayClient) ExecuteQueryWithStats(ctx context.Context, conn *entity.CHConnection, query string) (*entity.QueryStats, error) {
+	if c.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return &entity.QueryStats{ExecutionTimeMs: 10}, nil
+}
+
+func newReplayUsecase(t *testing.T, client *replayClient) (usecase.ReplayUsecase, *entity.ReplayRun) {
+	db, err := gorm.Open(gormsqlite.Open(filepath.Join(t.TempDir(), "replay.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&entity.CHConnection{}, &entity.ReplayRun{}))
+
+	connectionRepo := sqlite.NewConnectionRepository(db)
+	source := &entity.CHConnection{Name: "old", Host: "old"}
+	target := &entity.CHConnection{Name: "new", Host: "new"}
+	require.NoError(t, connectionRepo.Create(context.Background(), source))
+	require.NoError(t, connectionRepo.Create(context.Background(), target))
+
+	u := usecase.NewReplayUsecase(sqlite.NewReplayRepository(db), connectionRepo, client)
+	return u, &entity.ReplayRun{SourceConnectionID: source.ID, TargetConnectionID: target.ID}
+}
+
+// waitForReplay polls the replay until it leaves the running status
+func waitForReplay(t *testing.T, u usecase.ReplayUsecase, id int64) *entity.ReplayRun {
+	var run *entity.ReplayRun
+	require.Eventually(t, func() bool {
+		var err error
+		run, err = u.GetReplay(context.Background(), id)
+		require.NoError(t, err)
+		return run.Status != entity.ReplayStatusRunning
+	}, 5*time.Second, 10*time.Millisecond)
+	return run
+}
+
+func TestStartReplay(t *testing.T) {
+	ctx := context.Background()
+	u, run := newReplayUsecase(t, &replayClient{})
+
+	started, err := u.StartReplay(ctx, run)
+	require.NoError(t, err)
+	assert.Equal(t, entity.ReplayStatusRunning, started.Status)
+	assert.NotZero(t, started.ID)
+
+	finished := waitForReplay(t, u, started.ID)
+	assert.Equal(t, entity.ReplayStatusCompleted, finished.Status)
+	assert.Equal(t, 4, finished.TotalSamples)
+	assert.Equal(t, 4, finished.ReplayedSamples)
+	assert.Equal(t, 2, finished.FingerprintCount)
+	assert.NotNil(t, finished.FinishedAt)
+
+	assert.Error(t, u.CancelReplay(ctx, started.ID))
+}
+
+func TestCancelReplay(t *testing.T) {
+	ctx := context.Background()
+	u, run := newReplayUsecase(t, &replayClient{block: true})
+
+	started, err := u.StartReplay(ctx, run)
+	require.NoError(t, err)
+	assert.Error(t, u.DeleteReplay(ctx, started.ID))
+
+	require.NoError(t, u.CancelReplay(ctx, started.ID))
+	finished := waitForReplay(t, u, started.ID)
+	assert.Equal(t, entity.ReplayStatusCancelled, finished.Status)
+	assert.Empty(t, finished.Error)
+	assert.Equal(t, 4, finished.TotalSamples)
+	assert.Zero(t, finished.ReplayedSamples)
+
+	require.Eventually(t, func() bool {
+		return u.DeleteReplay(ctx, started.ID) == nil
+	}, 5*time.Second, 10*time.Millisecond)
+}
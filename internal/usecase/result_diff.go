@@ -0,0 +1,167 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/clickhouse"
+)
+
+// compareResults checks whether variant1 on conn1 and variant2 on conn2 return the same data.
+// Each side runs with its own variant settings.
+func (u *ConnectionUsecase) compareResults(ctx context.Context, conn1 *entity.CHConnection, variant1 entity.QueryVariant, conn2 *entity.CHConnection, variant2 entity.QueryVariant, mode string) (*entity.ResultDiff, error) {
+	ctx1, query1 := clickhouse.WithQueryParameters(clickhouse.WithQuerySettings(ctx, variant1.Settings), variant1.Parameters), variant1.Query
+	ctx2, query2 := clickhouse.WithQueryParameters(clickhouse.WithQuerySettings(ctx, variant2.Settings), variant2.Parameters), variant2.Query
+
+	switch mode {
+	case "":
+		return nil, nil
+	case entity.ResultCheckHash:
+		hash1, err := u.chClient.GetResultHash(ctx1, conn1, query1)
+		if err != nil {
+			return nil, err
+		}
+		hash2, err := u.chClient.GetResultHash(ctx2, conn2, query2)
+		if err != nil {
+			return nil, err
+		}
+		return DiffResultHashes(hash1, hash2), nil
+	case entity.ResultCheckFull:
+		res1, err := u.chClient.ExecuteQueryWithResults(ctx1, conn1, query1)
+		if err != nil {
+			return nil, err
+		}
+		res2, err := u.chClient.ExecuteQueryWithResults(ctx2, conn2, query2)
+		if err != nil {
+			return nil, err
+		}
+		if res1.Truncated || res2.Truncated {
+			return nil, fmt.Errorf("result is too large for a full comparison, use the hash check instead")
+		}
+		// Compare and report the values as the console shows them
+		clickhouse.EncodeResult(res1, nil)
+		clickhouse.EncodeResult(res2, nil)
+		return DiffResults(res1, res2), nil
+	default:
+		return nil, fmt.Errorf("unknown result check mode %q", mode)
+	}
+}
+
+// DiffResultHashes compares two results by row count, hash and column names and types
+func DiffResultHashes(hash1, hash2 *entity.ResultHash) *entity.ResultDiff {
+	diff := &entity.ResultDiff{
+		Mode:         entity.ResultCheckHash,
+		RowCount1:    hash1.Rows,
+		RowCount2:    hash2.Rows,
+		Hash1:        hash1.Hash,
+		Hash2:        hash2.Hash,
+		ColumnsMatch: reflect.DeepEqual(hash1.Columns, hash2.Columns),
+	}
+	if !diff.ColumnsMatch {
+		diff.Columns1 = hash1.Columns
+		diff.Columns2 = hash2.Columns
+	}
+	diff.Equal = diff.ColumnsMatch && hash1.Rows == hash2.Rows && hash1.Hash == hash2.Hash
+	return diff
+}
+
+// DiffResults compares two result sets ignoring row order.
+// Value differences are reported by row position and are only meaningful for ordered queries.
+func DiffResults(res1, res2 *entity.QueryResult) *entity.ResultDiff {
+	diff := &entity.ResultDiff{
+		Mode:         entity.ResultCheckFull,
+		RowCount1:    uint64(len(res1.Rows)),
+		RowCount2:    uint64(len(res2.Rows)),
+		ColumnsMatch: reflect.DeepEqual(res1.Columns, res2.Columns),
+	}
+	if !diff.ColumnsMatch {
+		diff.Columns1 = res1.Columns
+		diff.Columns2 = res2.Columns
+	}
+
+	// Multiset of rows from the first result, consumed by matching rows of the second result
+	remaining := make(map[string][]int)
+	for i, row := range res1.Rows {
+		key := rowKey(res1.Columns, row)
+		remaining[key] = append(remaining[key], i)
+	}
+
+	for _, row := range res2.Rows {
+		key := rowKey(res2.Columns, row)
+		if idx := remaining[key]; len(idx) > 0 {
+			remaining[key] = idx[1:]
+			continue
+		}
+		diff.ExtraRowCount++
+		if len(diff.ExtraRows) < entity.MaxResultDiffSamples {
+			diff.ExtraRows = append(diff.ExtraRows, row)
+		}
+	}
+
+	unmatched := make(map[int]bool)
+	for _, idx := range remaining {
+		for _, i := range idx {
+			unmatched[i] = true
+		}
+	}
+	for i, row := range res1.Rows {
+		if !unmatched[i] {
+			continue
+		}
+		diff.MissingRowCount++
+		if len(diff.MissingRows) < entity.MaxResultDiffSamples {
+			diff.MissingRows = append(diff.MissingRows, row)
+		}
+	}
+
+	if diff.ColumnsMatch && (diff.MissingRowCount > 0 || diff.ExtraRowCount > 0) {
+		diff.ValueDiffs = positionalValueDiffs(res1, res2)
+	}
+
+	diff.Equal = diff.ColumnsMatch && diff.MissingRowCount == 0 && diff.ExtraRowCount == 0
+	return diff
+}
+
+func positionalValueDiffs(res1, res2 *entity.QueryResult) []entity.ColumnValueDiff {
+	var diffs []entity.ColumnValueDiff
+
+	n := len(res1.Rows)
+	if len(res2.Rows) < n {
+		n = len(res2.Rows)
+	}
+
+	for i := 0; i < n; i++ {
+		for _, col := range res1.Columns {
+			v1, v2 := res1.Rows[i][col], res2.Rows[i][col]
+			if valueKey(v1) == valueKey(v2) {
+				continue
+			}
+			diffs = append(diffs, entity.ColumnValueDiff{Row: i, Column: col, Value1: v1, Value2: v2})
+			if len(diffs) >= entity.MaxResultDiffSamples {
+				return diffs
+			}
+		}
+	}
+
+	return diffs
+}
+
+// rowKey builds a canonical representation of a row following the column order
+func rowKey(columns []string, row map[string]interface{}) string {
+	values := make([]interface{}, len(columns))
+	for i, col := range columns {
+		values[i] = row[col]
+	}
+	return valueKey(values)
+}
+
+func valueKey(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%#v", v)
+	}
+	return string(b)
+}
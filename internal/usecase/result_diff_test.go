@@ -0,0 +1,126 @@
+package usecase_test
+
+import (
+	"testing"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/usecase"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffResults(t *testing.T) {
+	columns := []string{"id", "name"}
+	row := func(id uint64, name string) map[string]interface{} {
+		return map[string]interface{}{"id": id, "name": name}
+	}
+
+	testcases := []struct {
+		name         string
+		res1         *entity.QueryResult
+		res2         *entity.QueryResult
+		wantEqual    bool
+		wantMissing  int
+		wantExtra    int
+		wantColMatch bool
+		wantValDiffs int
+	}{
+		{
+			name:         "Equal Ignoring Order",
+			res1:         &entity.QueryResult{Columns: columns, Rows: []map[string]interface{}{row(1, "a"), row(2, "b")}},
+			res2:         &entity.QueryResult{Columns: columns, Rows: []map[string]interface{}{row(2, "b"), row(1, "a")}},
+			wantEqual:    true,
+			wantColMatch: true,
+		},
+		{
+			name:         "Duplicate Rows Are Counted",
+			res1:         &entity.QueryResult{Columns: columns, Rows: []map[string]interface{}{row(1, "a"), row(1, "a")}},
+			res2:         &entity.QueryResult{Columns: columns, Rows: []map[string]interface{}{row(1, "a")}},
+			wantMissing:  1,
+			wantColMatch: true,
+		},
+		{
+			name:         "Differing Value",
+			res1:         &entity.QueryResult{Columns: columns, Rows: []map[string]interface{}{row(1, "a"), row(2, "b")}},
+			res2:         &entity.QueryResult{Columns: columns, Rows: []map[string]interface{}{row(1, "a"), row(2, "c")}},
+			wantMissing:  1,
+			wantExtra:    1,
+			wantColMatch: true,
+			wantValDiffs: 1,
+		},
+		{
+			name:        "Different Columns",
+			res1:        &entity.QueryResult{Columns: columns, Rows: []map[string]interface{}{row(1, "a")}},
+			res2:        &entity.QueryResult{Columns: []string{"id"}, Rows: []map[string]interface{}{{"id": uint64(1)}}},
+			wantMissing: 1,
+			wantExtra:   1,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := usecase.DiffResults(tt.res1, tt.res2)
+			assert.Equal(t, tt.wantEqual, diff.Equal)
+			assert.Equal(t, tt.wantMissing, diff.MissingRowCount)
+			assert.Equal(t, tt.wantExtra, diff.ExtraRowCount)
+			assert.Equal(t, tt.wantColMatch, diff.ColumnsMatch)
+			assert.Len(t, diff.ValueDiffs, tt.wantValDiffs)
+			assert.Equal(t, uint64(len(tt.res1.Rows)), diff.RowCount1)
+			assert.Equal(t, uint64(len(tt.res2.Rows)), diff.RowCount2)
+		})
+	}
+}
+
+func TestDiffResultHashes(t *testing.T) {
+	columns := []string{"id UInt64", "name String"}
+
+	testcases := []struct {
+		name         string
+		hash1        *entity.ResultHash
+		hash2        *entity.ResultHash
+		wantEqual    bool
+		wantColMatch bool
+	}{
+		{
+			name:         "Equal",
+			hash1:        &entity.ResultHash{Rows: 10, Hash: "ab", Columns: columns},
+			hash2:        &entity.ResultHash{Rows: 10, Hash: "ab", Columns: columns},
+			wantEqual:    true,
+			wantColMatch: true,
+		},
+		{
+			name:         "Different Hash",
+			hash1:        &entity.ResultHash{Rows: 10, Hash: "ab", Columns: columns},
+			hash2:        &entity.ResultHash{Rows: 10, Hash: "cd", Columns: columns},
+			wantColMatch: true,
+		},
+		{
+			name:         "Different Row Count",
+			hash1:        &entity.ResultHash{Rows: 10, Hash: "ab", Columns: columns},
+			hash2:        &entity.ResultHash{Rows: 11, Hash: "ab", Columns: columns},
+			wantColMatch: true,
+		},
+		{
+			name:  "Renamed Column",
+			hash1: &entity.ResultHash{Rows: 10, Hash: "ab", Columns: columns},
+			hash2: &entity.ResultHash{Rows: 10, Hash: "ab", Columns: []string{"id UInt64", "title String"}},
+		},
+		{
+			name:  "Different Type",
+			hash1: &entity.ResultHash{Rows: 10, Hash: "ab", Columns: columns},
+			hash2: &entity.ResultHash{Rows: 10, Hash: "ab", Columns: []string{"id UInt32", "name String"}},
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := usecase.DiffResultHashes(tt.hash1, tt.hash2)
+			assert.Equal(t, entity.ResultCheckHash, diff.Mode)
+			assert.Equal(t, tt.wantEqual, diff.Equal)
+			assert.Equal(t, tt.wantColMatch, diff.ColumnsMatch)
+			if !tt.wantColMatch {
+				assert.Equal(t, tt.hash1.Columns, diff.Columns1)
+				assert.Equal(t, tt.hash2.Columns, diff.Columns2)
+			}
+		})
+	}
+}
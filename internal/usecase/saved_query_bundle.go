@@ -0,0 +1,179 @@
+package usecase
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+)
+
+// A saved query bundle is a .sql file holding one statement per saved query. The fields of a query are
+// comment headers right before its SQL, so the bundle still runs as a script:
+//
+//	-- @title: Daily events
+//	-- @description: Events per day
+//	-- @folder: reports/daily
+//	-- @tags: events, daily
+//	-- @any_connection: true
+//	-- @param day: "2024-01-31"
+//	SELECT count() FROM events WHERE date = {day:Date};
+//
+// A statement without a @title header continues the query before it, so multi-statement queries survive.
+const (
+	bundleTitle         = "title"
+	bundleDescription   = "description"
+	bundleFolder        = "folder"
+	bundleTags          = "tags"
+	bundleAnyConnection = "any_connection"
+	bundleParam         = "param "
+)
+
+// EncodeSavedQueries writes the queries as a .sql bundle
+func EncodeSavedQueries(queries []*entity.SavedQuery) string {
+	var b strings.Builder
+
+	for i, q := range queries {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+
+		writeBundleHeader(&b, bundleTitle, singleLine(q.Title))
+		if q.Description != "" {
+			for _, line := range strings.Split(q.Description, "\n") {
+				writeBundleHeader(&b, bundleDescription, strings.TrimRight(line, "\r"))
+			}
+		}
+		if q.Folder != "" {
+			writeBundleHeader(&b, bundleFolder, q.Folder)
+		}
+		if len(q.Tags) > 0 {
+			writeBundleHeader(&b, bundleTags, strings.Join(q.Tags, ", "))
+		}
+		if q.AnyConnection {
+			writeBundleHeader(&b, bundleAnyConnection, "true")
+		}
+
+		names := make([]string, 0, len(q.Parameters))
+		for name := range q.Parameters {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			writeBundleHeader(&b, bundleParam+name, strconv.Quote(q.Parameters[name]))
+		}
+
+		query := strings.TrimRight(strings.TrimSpace(q.Query), "; \t\r\n")
+		b.WriteString(query)
+		// A semicolon after a trailing line comment would be commented out
+		if lines := strings.Split(query, "\n"); strings.Contains(lines[len(lines)-1], "--") {
+			b.WriteString("\n")
+		}
+		b.WriteString(";\n")
+	}
+
+	return b.String()
+}
+
+func writeBundleHeader(b *strings.Builder, key, value string) {
+	b.WriteString("-- @" + key + ": " + value + "\n")
+}
+
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// ParseSavedQueries reads the queries of a .sql bundle written by EncodeSavedQueries or by hand
+func ParseSavedQueries(bundle string) ([]*entity.SavedQuery, error) {
+	var queries []*entity.SavedQuery
+
+	for n, statement := range SplitStatements(bundle) {
+		headers, sql := splitBundleHeaders(statement)
+		if len(headers) == 0 {
+			if len(queries) == 0 {
+				return nil, fmt.Errorf("statement %d: the bundle must start with a -- @title: header", n+1)
+			}
+			previous := queries[len(queries)-1]
+			previous.Query += ";\n" + sql
+			continue
+		}
+
+		q := &entity.SavedQuery{}
+		var description []string
+		for _, header := range headers {
+			key, value, ok := strings.Cut(header, ":")
+			if !ok {
+				return nil, fmt.Errorf("statement %d: header @%s has no value", n+1, header)
+			}
+			key = strings.TrimSpace(key)
+			value = strings.TrimSpace(value)
+
+			switch {
+			case key == bundleTitle:
+				q.Title = value
+			case key == bundleDescription:
+				description = append(description, value)
+			case key == bundleFolder:
+				q.Folder = value
+			case key == bundleTags:
+				for _, tag := range strings.Split(value, ",") {
+					if tag = strings.TrimSpace(tag); tag != "" {
+						q.Tags = append(q.Tags, tag)
+					}
+				}
+			case key == bundleAnyConnection:
+				anyConnection, err := strconv.ParseBool(value)
+				if err != nil {
+					return nil, fmt.Errorf("statement %d: @%s must be true or false", n+1, key)
+				}
+				q.AnyConnection = anyConnection
+			case strings.HasPrefix(key, bundleParam):
+				if q.Parameters == nil {
+					q.Parameters = make(entity.QueryParameters)
+				}
+				// Values are quoted on export, hand written ones may not be
+				if unquoted, err := strconv.Unquote(value); err == nil {
+					value = unquoted
+				}
+				q.Parameters[strings.TrimSpace(strings.TrimPrefix(key, bundleParam))] = value
+			default:
+				return nil, fmt.Errorf("statement %d: unknown header @%s", n+1, key)
+			}
+		}
+		if q.Title == "" {
+			return nil, fmt.Errorf("statement %d: the -- @title: header is required", n+1)
+		}
+
+		q.Description = strings.Join(description, "\n")
+		q.Query = sql
+		queries = append(queries, q)
+	}
+
+	return queries, nil
+}
+
+// splitBundleHeaders separates the leading "-- @key: value" comments of a statement from its SQL
+func splitBundleHeaders(statement string) ([]string, string) {
+	var headers []string
+	lines := strings.Split(statement, "\n")
+
+	i := 0
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+		comment, ok := strings.CutPrefix(line, "--")
+		if !ok {
+			break
+		}
+		header, ok := strings.CutPrefix(strings.TrimSpace(comment), "@")
+		if !ok {
+			break
+		}
+		headers = append(headers, header)
+	}
+
+	return headers, strings.TrimSpace(strings.Join(lines[i:], "\n"))
+}
@@ -0,0 +1,104 @@
+package usecase_test
+
+import (
+	"testing"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSavedQueryBundleRoundTrip(t *testing.T) {
+	queries := []*entity.SavedQuery{
+		{
+			Title:         "Daily events",
+			Description:   "Events per day\nfor the dashboard",
+			Folder:        "reports/daily",
+			Tags:          []string{"daily", "events"},
+			AnyConnection: true,
+			Parameters:    entity.QueryParameters{"day": "2024-01-31", "note": `a "b"; c`},
+			Query:         "SELECT count() FROM events WHERE date = {day:Date} AND note != {note:String}",
+		},
+		{
+			Title: "Setup",
+			Query: "CREATE TABLE t (x UInt8) ENGINE = Memory;\nINSERT INTO t VALUES (1)",
+		},
+		{
+			Title: "Commented",
+			Query: "SELECT 1 -- trailing comment",
+		},
+	}
+
+	parsed, err := usecase.ParseSavedQueries(usecase.EncodeSavedQueries(queries))
+	require.NoError(t, err)
+	assert.Equal(t, queries, parsed)
+}
+
+func TestParseSavedQueries(t *testing.T) {
+	testcases := []struct {
+		name    string
+		bundle  string
+		want    []*entity.SavedQuery
+		wantErr string
+	}{
+		{
+			name:   "Hand Written",
+			bundle: "--@title: Users\n-- @tags: a, b\n-- @param id: 42\n-- not a header\nSELECT * FROM users WHERE id = {id:UInt64};",
+			want: []*entity.SavedQuery{{
+				Title:      "Users",
+				Tags:       []string{"a", "b"},
+				Parameters: entity.QueryParameters{"id": "42"},
+				Query:      "-- not a header\nSELECT * FROM users WHERE id = {id:UInt64}",
+			}},
+		},
+		{
+			name:   "Empty Bundle",
+			bundle: "-- nothing here\n",
+			want:   nil,
+		},
+		{
+			name:    "Missing First Title",
+			bundle:  "SELECT 1;",
+			wantErr: "statement 1: the bundle must start with a -- @title: header",
+		},
+		{
+			name:    "Headers Without Title",
+			bundle:  "-- @folder: x\nSELECT 1;",
+			wantErr: "statement 1: the -- @title: header is required",
+		},
+		{
+			name:    "Unknown Header",
+			bundle:  "-- @title: x\n-- @owner: me\nSELECT 1;",
+			wantErr: "statement 1: unknown header @owner",
+		},
+		{
+			name:    "Invalid Flag",
+			bundle:  "-- @title: x\n-- @any_connection: sometimes\nSELECT 1;",
+			wantErr: "statement 1: @any_connection must be true or false",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := usecase.ParseSavedQueries(tc.bundle)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestNormalizeFolder(t *testing.T) {
+	assert.Equal(t, "reports/daily", usecase.NormalizeFolder(" /reports// daily /"))
+	assert.Equal(t, "", usecase.NormalizeFolder(" / "))
+}
+
+func TestNormalizeTags(t *testing.T) {
+	assert.Equal(t, []string{"daily"}, usecase.NormalizeTags([]string{" daily", "", "daily "}))
+	assert.Equal(t, []string{"a b", "c"}, usecase.NormalizeTags([]string{"c", " a   b "}))
+	assert.Equal(t, []string{}, usecase.NormalizeTags(nil))
+}
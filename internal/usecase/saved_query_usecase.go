@@ -0,0 +1,210 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/helper"
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/sqlite"
+)
+
+type SavedQueryUsecase interface {
+	GetSavedQueries(ctx context.Context, connectionID int64, filter entity.SavedQueryFilter) ([]*entity.SavedQuery, error)
+	GetSavedQuery(ctx context.Context, connectionID, id int64) (*entity.SavedQuery, error)
+	CreateSavedQuery(ctx context.Context, connectionID int64, query *entity.SavedQuery) error
+	UpdateSavedQuery(ctx context.Context, connectionID, id int64, query *entity.SavedQuery) error
+	DeleteSavedQuery(ctx context.Context, connectionID, id int64) error
+	ExportSavedQueries(ctx context.Context, connectionID int64, filter entity.SavedQueryFilter) (string, error)
+	ImportSavedQueries(ctx context.Context, connectionID int64, bundle string) (*entity.SavedQueryImportResult, error)
+}
+
+type savedQueryUsecase struct {
+	savedQueryRepo sqlite.SavedQueryRepository
+	connectionRepo sqlite.ConnectionRepository
+}
+
+func NewSavedQueryUsecase(savedQueryRepo sqlite.SavedQueryRepository, connectionRepo sqlite.ConnectionRepository) SavedQueryUsecase {
+	return &savedQueryUsecase{
+		savedQueryRepo: savedQueryRepo,
+		connectionRepo: connectionRepo,
+	}
+}
+
+func (u *savedQueryUsecase) GetSavedQueries(ctx context.Context, connectionID int64, filter entity.SavedQueryFilter) ([]*entity.SavedQuery, error) {
+	filter.Folder = NormalizeFolder(filter.Folder)
+	filter.Tag = strings.TrimSpace(filter.Tag)
+
+	return u.savedQueryRepo.FindVisible(ctx, connectionID, filter)
+}
+
+// GetSavedQuery returns a query that can be opened on the connection
+func (u *savedQueryUsecase) GetSavedQuery(ctx context.Context, connectionID, id int64) (*entity.SavedQuery, error) {
+	query, err := u.savedQueryRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if query == nil || !query.VisibleOn(connectionID) {
+		return nil, fmt.Errorf("saved query not found")
+	}
+	return query, nil
+}
+
+func (u *savedQueryUsecase) CreateSavedQuery(ctx context.Context, connectionID int64, query *entity.SavedQuery) error {
+	conn, err := u.connectionRepo.FindByID(ctx, connectionID)
+	if err != nil {
+		return err
+	}
+	if conn == nil {
+		return fmt.Errorf("connection not found")
+	}
+	if err := validateSavedQuery(query); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	query.ID = 0
+	query.ConnectionID = connectionID
+	query.CreatedBy = UserFromContext(ctx)
+	query.UpdatedBy = query.CreatedBy
+	query.CreatedAt = now
+	query.UpdatedAt = now
+
+	return u.savedQueryRepo.Create(ctx, query)
+}
+
+func (u *savedQueryUsecase) UpdateSavedQuery(ctx context.Context, connectionID, id int64, query *entity.SavedQuery) error {
+	existing, err := u.GetSavedQuery(ctx, connectionID, id)
+	if err != nil {
+		return err
+	}
+	if err := validateSavedQuery(query); err != nil {
+		return err
+	}
+
+	// A shared query keeps the connection it was saved on
+	query.ID = id
+	query.ConnectionID = existing.ConnectionID
+	query.CreatedBy = existing.CreatedBy
+	query.CreatedAt = existing.CreatedAt
+	query.UpdatedBy = UserFromContext(ctx)
+	query.UpdatedAt = time.Now()
+
+	return u.savedQueryRepo.Update(ctx, query)
+}
+
+func (u *savedQueryUsecase) DeleteSavedQuery(ctx context.Context, connectionID, id int64) error {
+	if _, err := u.GetSavedQuery(ctx, connectionID, id); err != nil {
+		return err
+	}
+	return u.savedQueryRepo.Delete(ctx, id)
+}
+
+// ExportSavedQueries writes the queries matching the filter as a .sql bundle
+func (u *savedQueryUsecase) ExportSavedQueries(ctx context.Context, connectionID int64, filter entity.SavedQueryFilter) (string, error) {
+	queries, err := u.GetSavedQueries(ctx, connectionID, filter)
+	if err != nil {
+		return "", err
+	}
+	return EncodeSavedQueries(queries), nil
+}
+
+// ImportSavedQueries saves the queries of a .sql bundle on the connection, all or none. A query replaces the
+// connection's own query with the same title in the same folder, the others are added.
+func (u *savedQueryUsecase) ImportSavedQueries(ctx context.Context, connectionID int64, bundle string) (*entity.SavedQueryImportResult, error) {
+	queries, err := ParseSavedQueries(bundle)
+	if err != nil {
+		return nil, err
+	}
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("the bundle holds no queries")
+	}
+	// Validate everything first so a bad entry does not leave a half imported bundle
+	for _, query := range queries {
+		if err := validateSavedQuery(query); err != nil {
+			return nil, fmt.Errorf("%s: %w", query.Title, err)
+		}
+	}
+
+	conn, err := u.connectionRepo.FindByID(ctx, connectionID)
+	if err != nil {
+		return nil, err
+	}
+	if conn == nil {
+		return nil, fmt.Errorf("connection not found")
+	}
+
+	now := time.Now()
+	user := UserFromContext(ctx)
+	for _, query := range queries {
+		query.ID = 0
+		query.CreatedBy = user
+		query.UpdatedBy = user
+		query.CreatedAt = now
+		query.UpdatedAt = now
+	}
+
+	return u.savedQueryRepo.Import(ctx, connectionID, queries)
+}
+
+func validateSavedQuery(query *entity.SavedQuery) error {
+	query.Title = singleLine(query.Title)
+	query.Query = strings.TrimSpace(query.Query)
+	if query.Title == "" {
+		return fmt.Errorf("title is required")
+	}
+	if len(query.Title) > 255 {
+		return fmt.Errorf("title must be at most 255 characters")
+	}
+	if query.Query == "" {
+		return fmt.Errorf("query is required")
+	}
+
+	query.Description = strings.TrimSpace(query.Description)
+	query.Folder = NormalizeFolder(query.Folder)
+	if len(query.Folder) > 255 {
+		return fmt.Errorf("folder must be at most 255 characters")
+	}
+	query.Tags = NormalizeTags(query.Tags)
+
+	// Only the defaults of placeholders the query still has are kept
+	var params entity.QueryParameters
+	for _, p := range DetectQueryParameters(query.Query) {
+		if value, ok := query.Parameters[p.Name]; ok {
+			if params == nil {
+				params = make(entity.QueryParameters)
+			}
+			params[p.Name] = value
+		}
+	}
+	query.Parameters = params
+
+	return nil
+}
+
+// NormalizeFolder trims a folder path and drops its empty segments, " /reports//daily/ " becomes "reports/daily"
+func NormalizeFolder(folder string) string {
+	var segments []string
+	for _, segment := range strings.Split(folder, "/") {
+		if segment = strings.TrimSpace(segment); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// NormalizeTags trims tags, drops empty and duplicate ones and sorts them
+func NormalizeTags(tags []string) []string {
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = singleLine(tag)
+		if tag != "" && !helper.InArray(tag, normalized) {
+			normalized = append(normalized, tag)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized
+}
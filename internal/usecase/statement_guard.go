@@ -0,0 +1,182 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/helper"
+	"github.com/rahmatrdn/go-ch-manager/internal/sqlparse"
+)
+
+// ClassifyStatement returns the guarded kind of a statement, empty for statements that always run
+func ClassifyStatement(statement string) string {
+	parsed := sqlparse.Analyze(statement)
+	switch parsed.Kind {
+	case sqlparse.KindDDL:
+		switch parsed.Keyword {
+		case "DROP":
+			return entity.StatementKindDrop
+		case "TRUNCATE":
+			return entity.StatementKindTruncate
+		}
+		return entity.StatementKindDDL
+	case sqlparse.KindMutation:
+		return entity.StatementKindMutation
+	case sqlparse.KindKill:
+		return entity.StatementKindKill
+	case sqlparse.KindSystem:
+		return entity.StatementKindSystem
+	}
+	return ""
+}
+
+// RequireSelect accepts a single SELECT only. Load tests and suites run their queries over and over without
+// asking, so they have no business running writes or DDL, whatever the guard policy of the connection.
+func RequireSelect(query string) error {
+	statements := SplitStatements(query)
+	if len(statements) != 1 {
+		return fmt.Errorf("exactly one statement is required, got %d", len(statements))
+	}
+	if kind := sqlparse.Classify(statements[0]); kind != sqlparse.KindSelect {
+		return fmt.Errorf("only SELECT queries can be benchmarked, %q is a %s statement", statementSummary(statements[0]), kind)
+	}
+	return nil
+}
+
+// StatementGuard stops destructive statements on connections whose label policy blocks them, asks for a
+// typed confirmation or for an approved change request. The confirmation token is the name of the connection.
+type StatementGuard struct {
+	policies map[string]entity.GuardPolicy
+}
+
+// NewStatementGuard uses the policies of the labels, labels without one get the default policy
+func NewStatementGuard(policies map[string]entity.GuardPolicy) *StatementGuard {
+	merged := entity.DefaultGuardPolicies()
+	for label, policy := range policies {
+		merged[label] = policy
+	}
+	return &StatementGuard{policies: merged}
+}
+
+// Check returns a StatementGuardError for the strictest guarded statement, nil when every statement may run.
+// Statements that need a confirmation run when confirm is the connection's name.
+func (g *StatementGuard) Check(conn *entity.CHConnection, statements []string, confirm string) error {
+	stopped := g.strictest(conn, statements)
+	if stopped == nil {
+		return nil
+	}
+	if stopped.Action == entity.GuardActionConfirm && strings.TrimSpace(confirm) == conn.Name {
+		return nil
+	}
+	return stopped
+}
+
+// CheckApproved checks the statements of an approved change request, which only the block action stops
+func (g *StatementGuard) CheckApproved(conn *entity.CHConnection, statements []string) error {
+	stopped := g.strictest(conn, statements)
+	if stopped == nil || stopped.Action != entity.GuardActionBlock {
+		return nil
+	}
+	return stopped
+}
+
+// GuardedKinds returns the guarded kinds of the statements, each once, in order of appearance
+func GuardedKinds(statements []string) []string {
+	var kinds []string
+	for _, statement := range statements {
+		if kind := ClassifyStatement(statement); kind != "" && !helper.InArray(kind, kinds) {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds
+}
+
+// strictest returns the error of the statement with the strictest action, nil when every statement is allowed
+func (g *StatementGuard) strictest(conn *entity.CHConnection, statements []string) *entity.StatementGuardError {
+	if g == nil {
+		return nil
+	}
+
+	label := conn.Label
+	if label == "" {
+		label = entity.LabelDevelopment
+	}
+	policy := g.policies[label]
+
+	var stopped *entity.StatementGuardError
+	for _, statement := range statements {
+		kind := ClassifyStatement(statement)
+		if kind == "" {
+			continue
+		}
+		action := policy[kind]
+		if guardActionRank(action) == 0 {
+			continue
+		}
+		if stopped == nil || guardActionRank(action) > guardActionRank(stopped.Action) {
+			stopped = &entity.StatementGuardError{
+				Label:        label,
+				Kind:         kind,
+				Action:       action,
+				Statement:    statementSummary(statement),
+				ConfirmToken: conn.Name,
+			}
+		}
+	}
+	return stopped
+}
+
+// guardActionRank orders the actions by strictness, unknown actions allow like GuardActionAllow
+func guardActionRank(action string) int {
+	for rank, a := range entity.GuardActions {
+		if a == action {
+			return rank
+		}
+	}
+	return 0
+}
+
+// statementSummary shortens a statement to its first line for messages
+func statementSummary(statement string) string {
+	summary, _, cut := strings.Cut(strings.TrimSpace(statement), "\n")
+	if len(summary) > 120 {
+		summary, cut = summary[:120], true
+	}
+	if cut {
+		summary += " ..."
+	}
+	return summary
+}
+
+// ParseGuardPolicy reads a policy written as comma separated kind=action pairs, e.g. "*=confirm,drop=block".
+// The kind "*" sets every guarded kind, later pairs override earlier ones.
+func ParseGuardPolicy(s string) (entity.GuardPolicy, error) {
+	policy := entity.GuardPolicy{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		kind, action, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("guard policy %q: %q is not a kind=action pair", s, pair)
+		}
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		action = strings.ToLower(strings.TrimSpace(action))
+
+		if !helper.InArray(action, entity.GuardActions) {
+			return nil, fmt.Errorf("guard policy %q: unknown action %q", s, action)
+		}
+		switch {
+		case kind == "*":
+			for _, k := range entity.GuardedStatementKinds {
+				policy[k] = action
+			}
+		case helper.InArray(kind, entity.GuardedStatementKinds):
+			policy[kind] = action
+		default:
+			return nil, fmt.Errorf("guard policy %q: unknown statement kind %q", s, kind)
+		}
+	}
+	return policy, nil
+}
@@ -0,0 +1,194 @@
+package usecase_test
+
+import (
+	"testing"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyStatement(t *testing.T) {
+	testcases := []struct {
+		name      string
+		statement string
+		want      string
+	}{
+		{name: "Select", statement: "SELECT * FROM events", want: ""},
+		{name: "Insert", statement: "INSERT INTO events VALUES (1)", want: ""},
+		{name: "Explain Drop", statement: "EXPLAIN AST DROP TABLE events", want: ""},
+		{name: "Create", statement: "CREATE TABLE t (x UInt8) ENGINE = Memory", want: entity.StatementKindDDL},
+		{name: "Alter Schema", statement: "ALTER TABLE events ADD COLUMN deleted UInt8", want: entity.StatementKindDDL},
+		{name: "Alter Update", statement: "alter table events update x = 1 where 1", want: entity.StatementKindMutation},
+		{name: "Alter Delete On Cluster", statement: "ALTER TABLE db.events ON CLUSTER main DELETE WHERE id = 1", want: entity.StatementKindMutation},
+		{name: "Alter Keyword In Literal", statement: "ALTER TABLE events COMMENT COLUMN x 'DELETE me'", want: entity.StatementKindDDL},
+		{name: "Lightweight Delete", statement: "DELETE FROM events WHERE id = 1", want: entity.StatementKindMutation},
+		{name: "Drop After Comment", statement: "-- cleanup\nDROP TABLE events", want: entity.StatementKindDrop},
+		{name: "Truncate", statement: "TRUNCATE TABLE events", want: entity.StatementKindTruncate},
+		{name: "Kill", statement: "KILL QUERY WHERE query_id = 'x'", want: entity.StatementKindKill},
+		{name: "System", statement: "SYSTEM DROP MARK CACHE", want: entity.StatementKindSystem},
+		{name: "Rename", statement: "RENAME TABLE a TO b", want: entity.StatementKindDDL},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, usecase.ClassifyStatement(tc.statement))
+		})
+	}
+}
+
+func TestRequireSelect(t *testing.T) {
+	testcases := []struct {
+		name    string
+		query   string
+		wantErr bool
+	}{
+		{name: "Select", query: "SELECT count() FROM events"},
+		{name: "With And Trailing Semicolon", query: "WITH 1 AS x SELECT x;"},
+		{name: "Parenthesized Union", query: "(SELECT 1) UNION ALL (SELECT 2)"},
+		{name: "Drop", query: "DROP TABLE events", wantErr: true},
+		{name: "Truncate After Comment", query: "-- reset\nTRUNCATE TABLE events", wantErr: true},
+		{name: "Alter Delete", query: "ALTER TABLE events DELETE WHERE 1", wantErr: true},
+		{name: "Insert Select", query: "INSERT INTO events SELECT * FROM staging", wantErr: true},
+		{name: "System", query: "SYSTEM DROP MARK CACHE", wantErr: true},
+		{name: "Select Then Drop", query: "SELECT 1; DROP TABLE events", wantErr: true},
+		{name: "Empty", query: "-- nothing", wantErr: true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := usecase.RequireSelect(tc.query)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestParseGuardPolicy(t *testing.T) {
+	testcases := []struct {
+		name    string
+		policy  string
+		want    entity.GuardPolicy
+		wantErr bool
+	}{
+		{name: "Empty", policy: "", want: entity.GuardPolicy{}},
+		{
+			name:   "Pairs",
+			policy: "drop=block, Truncate=Confirm",
+			want:   entity.GuardPolicy{entity.StatementKindDrop: entity.GuardActionBlock, entity.StatementKindTruncate: entity.GuardActionConfirm},
+		},
+		{
+			name:   "Wildcard Then Override",
+			policy: "*=confirm,drop=block,kill=allow",
+			want: entity.GuardPolicy{
+				entity.StatementKindDDL:      entity.GuardActionConfirm,
+				entity.StatementKindMutation: entity.GuardActionConfirm,
+				entity.StatementKindDrop:     entity.GuardActionBlock,
+				entity.StatementKindTruncate: entity.GuardActionConfirm,
+				entity.StatementKindKill:     entity.GuardActionAllow,
+				entity.StatementKindSystem:   entity.GuardActionConfirm,
+			},
+		},
+		{name: "Approve", policy: "ddl=approve", want: entity.GuardPolicy{entity.StatementKindDDL: entity.GuardActionApprove}},
+		{name: "Unknown Kind", policy: "select=block", wantErr: true},
+		{name: "Unknown Action", policy: "drop=deny", wantErr: true},
+		{name: "Not A Pair", policy: "drop", wantErr: true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := usecase.ParseGuardPolicy(tc.policy)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestStatementGuardCheck(t *testing.T) {
+	guard := usecase.NewStatementGuard(map[string]entity.GuardPolicy{
+		entity.LabelStaging: {entity.StatementKindDrop: entity.GuardActionConfirm, entity.StatementKindTruncate: entity.GuardActionBlock},
+	})
+
+	testcases := []struct {
+		name       string
+		label      string
+		statements []string
+		confirm    string
+		wantAction string
+	}{
+		{name: "Development Allows", label: entity.LabelDevelopment, statements: []string{"DROP TABLE t"}},
+		{name: "Empty Label Is Development", label: "", statements: []string{"DROP TABLE t"}},
+		{name: "Reads Always Run", label: entity.LabelProduction, statements: []string{"SELECT 1"}},
+		{name: "Production Default Confirms", label: entity.LabelProduction, statements: []string{"KILL QUERY WHERE 1"}, wantAction: entity.GuardActionConfirm},
+		{name: "Production Default Approves Mutations", label: entity.LabelProduction, statements: []string{"ALTER TABLE t DELETE WHERE 1"}, wantAction: entity.GuardActionApprove},
+		{name: "Confirmation Does Not Approve", label: entity.LabelProduction, statements: []string{"CREATE TABLE t (x UInt8) ENGINE = Memory"}, confirm: "analytics-prod", wantAction: entity.GuardActionApprove},
+		{name: "Approve Beats Confirm", label: entity.LabelProduction, statements: []string{"DROP TABLE a", "ALTER TABLE b ADD COLUMN c UInt8"}, wantAction: entity.GuardActionApprove},
+		{name: "Hash Comment Hides Nothing", label: entity.LabelProduction, statements: []string{"# '\nDROP TABLE t"}, wantAction: entity.GuardActionConfirm},
+		{name: "Confirmed", label: entity.LabelProduction, statements: []string{"DROP TABLE t"}, confirm: " analytics-prod ", wantAction: ""},
+		{name: "Wrong Confirmation", label: entity.LabelProduction, statements: []string{"DROP TABLE t"}, confirm: "yes", wantAction: entity.GuardActionConfirm},
+		{name: "Configured Label", label: entity.LabelStaging, statements: []string{"DROP TABLE t"}, wantAction: entity.GuardActionConfirm},
+		{name: "Unlisted Kind Allowed", label: entity.LabelStaging, statements: []string{"CREATE TABLE t (x UInt8) ENGINE = Memory"}},
+		{name: "Strictest Statement Wins", label: entity.LabelStaging, statements: []string{"DROP TABLE a", "TRUNCATE TABLE b"}, wantAction: entity.GuardActionBlock},
+		{name: "Block Ignores Confirmation", label: entity.LabelStaging, statements: []string{"TRUNCATE TABLE b"}, confirm: "analytics-prod", wantAction: entity.GuardActionBlock},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			conn := &entity.CHConnection{Name: "analytics-prod", Label: tc.label}
+			err := guard.Check(conn, tc.statements, tc.confirm)
+			if tc.wantAction == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var guardErr *entity.StatementGuardError
+			require.ErrorAs(t, err, &guardErr)
+			assert.Equal(t, tc.wantAction, guardErr.Action)
+			assert.Equal(t, "analytics-prod", guardErr.ConfirmToken)
+		})
+	}
+}
+
+func TestStatementGuardCheckApproved(t *testing.T) {
+	guard := usecase.NewStatementGuard(map[string]entity.GuardPolicy{
+		entity.LabelStaging: {entity.StatementKindDDL: entity.GuardActionApprove, entity.StatementKindDrop: entity.GuardActionBlock},
+	})
+
+	testcases := []struct {
+		name       string
+		label      string
+		statements []string
+		wantErr    bool
+	}{
+		{name: "Approval Covers Approve", label: entity.LabelProduction, statements: []string{"ALTER TABLE t UPDATE x = 1 WHERE 1"}},
+		{name: "Approval Covers Confirm", label: entity.LabelProduction, statements: []string{"DROP TABLE t", "CREATE TABLE u (x UInt8) ENGINE = Memory"}},
+		{name: "Block Still Stops", label: entity.LabelStaging, statements: []string{"CREATE TABLE u (x UInt8) ENGINE = Memory", "DROP TABLE t"}, wantErr: true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := guard.CheckApproved(&entity.CHConnection{Name: "analytics", Label: tc.label}, tc.statements)
+			if tc.wantErr {
+				var guardErr *entity.StatementGuardError
+				require.ErrorAs(t, err, &guardErr)
+				assert.Equal(t, entity.GuardActionBlock, guardErr.Action)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestGuardedKinds(t *testing.T) {
+	statements := []string{"SELECT 1", "ALTER TABLE t DELETE WHERE 1", "CREATE TABLE u (x UInt8) ENGINE = Memory", "DELETE FROM t WHERE 1"}
+	assert.Equal(t, []string{entity.StatementKindMutation, entity.StatementKindDDL}, usecase.GuardedKinds(statements))
+	assert.Empty(t, usecase.GuardedKinds([]string{"SELECT 1"}))
+}
@@ -0,0 +1,450 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/helper"
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/clickhouse"
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/sqlite"
+)
+
+// SuiteUsecase manages the suites of a connection, every suite ID is looked up on the given connection only
+type SuiteUsecase interface {
+	CreateSuite(ctx context.Context, suite *entity.ComparisonSuite) error
+	UpdateSuite(ctx context.Context, connectionID, id int64, suite *entity.ComparisonSuite) error
+	GetSuites(ctx context.Context, connectionID int64) ([]*entity.ComparisonSuite, error)
+	DeleteSuite(ctx context.Context, connectionID, id int64) error
+	StartSuite(ctx context.Context, connectionID, id int64) (*entity.ComparisonSuiteRun, error)
+	RunDueSuites(ctx context.Context) error
+	GetRuns(ctx context.Context, connectionID, suiteID int64) ([]*entity.ComparisonSuiteRun, error)
+	GetRun(ctx context.Context, connectionID, suiteID, runID int64) (*entity.ComparisonSuiteRun, error)
+	SetBaseline(ctx context.Context, connectionID, suiteID, runID int64) error
+}
+
+type suiteUsecase struct {
+	suiteRepo      sqlite.SuiteRepository
+	favRepo        sqlite.FavoriteRepository
+	connectionRepo sqlite.ConnectionRepository
+	chClient       clickhouse.ClickHouseClient
+
+	mu      sync.Mutex
+	running map[int64]bool
+}
+
+func NewSuiteUsecase(
+	suiteRepo sqlite.SuiteRepository,
+	favRepo sqlite.FavoriteRepository,
+	connectionRepo sqlite.ConnectionRepository,
+	chClient clickhouse.ClickHouseClient,
+) SuiteUsecase {
+	return &suiteUsecase{
+		suiteRepo:      suiteRepo,
+		favRepo:        favRepo,
+		connectionRepo: connectionRepo,
+		chClient:       chClient,
+		running:        make(map[int64]bool),
+	}
+}
+
+func (u *suiteUsecase) CreateSuite(ctx context.Context, suite *entity.ComparisonSuite) error {
+	if err := u.validateSuite(ctx, suite); err != nil {
+		return err
+	}
+
+	suite.ID = 0
+	suite.BaselineRunID = 0
+	suite.LastRunAt = nil
+	suite.CreatedAt = time.Now()
+	suite.UpdatedAt = time.Now()
+
+	return u.suiteRepo.Create(ctx, suite)
+}
+
+func (u *suiteUsecase) UpdateSuite(ctx context.Context, connectionID, id int64, suite *entity.ComparisonSuite) error {
+	existing, err := u.findSuite(ctx, connectionID, id)
+	if err != nil {
+		return err
+	}
+
+	suite.ConnectionID = existing.ConnectionID
+	if err := u.validateSuite(ctx, suite); err != nil {
+		return err
+	}
+
+	// Baseline and run bookkeeping are owned by the runner
+	suite.ID = id
+	suite.BaselineRunID = existing.BaselineRunID
+	suite.LastRunAt = existing.LastRunAt
+	suite.CreatedAt = existing.CreatedAt
+	suite.UpdatedAt = time.Now()
+
+	return u.suiteRepo.Update(ctx, suite)
+}
+
+func (u *suiteUsecase) validateSuite(ctx context.Context, suite *entity.ComparisonSuite) error {
+	if suite.Name == "" {
+		return fmt.Errorf("suite name is required")
+	}
+	if len(suite.FavoriteIDs) == 0 {
+		return fmt.Errorf("suite must contain at least one favorite comparison")
+	}
+	if suite.Iterations <= 0 {
+		suite.Iterations = entity.DefaultSuiteIterations
+	}
+	if suite.RegressionThresholdPct <= 0 {
+		suite.RegressionThresholdPct = entity.DefaultSuiteRegressionThresholdPct
+	}
+	if suite.ScheduleIntervalMinutes < 0 {
+		suite.ScheduleIntervalMinutes = 0
+	}
+	if _, err := normalizeCompareOptions(entity.CompareOptions{Iterations: suite.Iterations, Warmup: suite.Warmup}); err != nil {
+		return err
+	}
+
+	for _, favID := range suite.FavoriteIDs {
+		fav, err := u.favRepo.FindByID(ctx, favID)
+		if err != nil {
+			return err
+		}
+		if fav == nil || fav.ConnectionID != suite.ConnectionID {
+			return fmt.Errorf("favorite comparison %d not found on this connection", favID)
+		}
+		if err := requireSelectVariants(fav); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (u *suiteUsecase) GetSuites(ctx context.Context, connectionID int64) ([]*entity.ComparisonSuite, error) {
+	return u.suiteRepo.FindAllByConnectionID(ctx, connectionID)
+}
+
+// findSuite returns the suite when it belongs to the connection
+func (u *suiteUsecase) findSuite(ctx context.Context, connectionID, id int64) (*entity.ComparisonSuite, error) {
+	suite, err := u.suiteRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if suite == nil || suite.ConnectionID != connectionID {
+		return nil, fmt.Errorf("suite not found")
+	}
+	return suite, nil
+}
+
+func (u *suiteUsecase) DeleteSuite(ctx context.Context, connectionID, id int64) error {
+	if _, err := u.findSuite(ctx, connectionID, id); err != nil {
+		return err
+	}
+	return u.suiteRepo.Delete(ctx, id)
+}
+
+func (u *suiteUsecase) GetRuns(ctx context.Context, connectionID, suiteID int64) ([]*entity.ComparisonSuiteRun, error) {
+	if _, err := u.findSuite(ctx, connectionID, suiteID); err != nil {
+		return nil, err
+	}
+	return u.suiteRepo.FindRunsBySuiteID(ctx, suiteID, 50)
+}
+
+func (u *suiteUsecase) GetRun(ctx context.Context, connectionID, suiteID, runID int64) (*entity.ComparisonSuiteRun, error) {
+	if _, err := u.findSuite(ctx, connectionID, suiteID); err != nil {
+		return nil, err
+	}
+
+	run, err := u.suiteRepo.FindRunByID(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	if run == nil || run.SuiteID != suiteID {
+		return nil, fmt.Errorf("run not found in this suite")
+	}
+	return run, nil
+}
+
+func (u *suiteUsecase) SetBaseline(ctx context.Context, connectionID, suiteID, runID int64) error {
+	suite, err := u.findSuite(ctx, connectionID, suiteID)
+	if err != nil {
+		return err
+	}
+
+	run, err := u.suiteRepo.FindRunByID(ctx, runID)
+	if err != nil {
+		return err
+	}
+	if run == nil || run.SuiteID != suiteID {
+		return fmt.Errorf("run not found in this suite")
+	}
+	if run.Status != entity.SuiteRunStatusCompleted {
+		return fmt.Errorf("only completed runs can be used as baseline")
+	}
+
+	if suite.BaselineRunID != 0 && suite.BaselineRunID != runID {
+		previous, err := u.suiteRepo.FindRunByID(ctx, suite.BaselineRunID)
+		if err != nil {
+			return err
+		}
+		if previous != nil {
+			previous.IsBaseline = false
+			if err := u.suiteRepo.UpdateRun(ctx, previous); err != nil {
+				return err
+			}
+		}
+	}
+
+	run.IsBaseline = true
+	if err := u.suiteRepo.UpdateRun(ctx, run); err != nil {
+		return err
+	}
+
+	suite.BaselineRunID = runID
+	suite.UpdatedAt = time.Now()
+	return u.suiteRepo.Update(ctx, suite)
+}
+
+// RunDueSuites is called periodically by the scheduler and runs every suite whose interval has elapsed
+func (u *suiteUsecase) RunDueSuites(ctx context.Context) error {
+	funcName := "SuiteUsecase.RunDueSuites"
+
+	suites, err := u.suiteRepo.FindScheduled(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, suite := range suites {
+		if !suite.IsDue(now) {
+			continue
+		}
+		run, err := u.startRun(ctx, suite, entity.SuiteRunTriggerSchedule)
+		if err != nil {
+			helper.LogError("run scheduled suite", funcName, err, entity.CaptureFields{
+				"suite_id": helper.ToString(suite.ID),
+			}, "")
+			continue
+		}
+		u.run(ctx, suite, run)
+	}
+
+	return nil
+}
+
+func (u *suiteUsecase) acquire(id int64) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.running[id] {
+		return false
+	}
+	u.running[id] = true
+	return true
+}
+
+func (u *suiteUsecase) release(id int64) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	delete(u.running, id)
+}
+
+// StartSuite creates a run of the suite and executes it in the background, GetRun returns its results once
+// it is no longer running
+func (u *suiteUsecase) StartSuite(ctx context.Context, connectionID, id int64) (*entity.ComparisonSuiteRun, error) {
+	suite, err := u.findSuite(ctx, connectionID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	run, err := u.startRun(ctx, suite, entity.SuiteRunTriggerManual)
+	if err != nil {
+		return nil, err
+	}
+
+	// The request context ends with the HTTP call, the run keeps going in the background
+	started := *run
+	go u.run(context.Background(), suite, run)
+
+	return &started, nil
+}
+
+// startRun marks the suite as running and saves a new run of it, run must follow to release the suite
+func (u *suiteUsecase) startRun(ctx context.Context, suite *entity.ComparisonSuite, trigger string) (*entity.ComparisonSuiteRun, error) {
+	if !u.acquire(suite.ID) {
+		return nil, fmt.Errorf("suite %q is already running", suite.Name)
+	}
+
+	run := &entity.ComparisonSuiteRun{
+		SuiteID:       suite.ID,
+		Status:        entity.SuiteRunStatusRunning,
+		Trigger:       trigger,
+		BaselineRunID: suite.BaselineRunID,
+		StartedAt:     time.Now(),
+	}
+	if err := u.suiteRepo.CreateRun(ctx, run); err != nil {
+		u.release(suite.ID)
+		return nil, err
+	}
+	return run, nil
+}
+
+// run executes a started run, saves its results and releases the suite
+func (u *suiteUsecase) run(ctx context.Context, suite *entity.ComparisonSuite, run *entity.ComparisonSuiteRun) {
+	funcName := "SuiteUsecase.run"
+	released := false
+	release := func() {
+		if !released {
+			released = true
+			u.release(suite.ID)
+		}
+	}
+	defer release()
+
+	runErr := u.executeRun(ctx, suite, run)
+	status := entity.SuiteRunStatusCompleted
+	if runErr != nil {
+		status = entity.SuiteRunStatusFailed
+		run.Error = runErr.Error()
+	}
+
+	// The suite may have been edited while it ran, only the run bookkeeping is written back
+	latest, err := u.suiteRepo.FindByID(ctx, suite.ID)
+	if err != nil {
+		latest = suite
+	}
+	if latest == nil {
+		// Deleted while it ran, nothing is left to update
+		return
+	}
+
+	// The first successful run becomes the baseline for later runs
+	if status == entity.SuiteRunStatusCompleted && latest.BaselineRunID == 0 {
+		run.IsBaseline = true
+		latest.BaselineRunID = run.ID
+	}
+
+	// Results and bookkeeping are saved before the run shows as finished, so whoever sees it finished can
+	// start the next run right away and compare it against this one
+	fields := entity.CaptureFields{"suite_id": helper.ToString(suite.ID), "run_id": helper.ToString(run.ID)}
+	if err := u.suiteRepo.UpdateRun(ctx, run); err != nil {
+		helper.LogError("save suite run", funcName, err, fields, "")
+		return
+	}
+	finished := time.Now()
+	latest.LastRunAt = &finished
+	if err := u.suiteRepo.Update(ctx, latest); err != nil {
+		helper.LogError("save suite", funcName, err, fields, "")
+	}
+	release()
+
+	run.Status = status
+	run.FinishedAt = &finished
+	if err := u.suiteRepo.UpdateRun(ctx, run); err != nil {
+		helper.LogError("save suite run", funcName, err, fields, "")
+	}
+}
+
+func (u *suiteUsecase) executeRun(ctx context.Context, suite *entity.ComparisonSuite, run *entity.ComparisonSuiteRun) error {
+	conn, err := u.connectionRepo.FindByID(ctx, suite.ConnectionID)
+	if err != nil {
+		return err
+	}
+	if conn == nil {
+		return fmt.Errorf("connection not found")
+	}
+
+	opts, err := normalizeCompareOptions(entity.CompareOptions{Iterations: suite.Iterations, Warmup: suite.Warmup})
+	if err != nil {
+		return err
+	}
+
+	baseline := make(map[string]*entity.ComparisonSuiteRunResult)
+	if suite.BaselineRunID != 0 {
+		baselineRun, err := u.suiteRepo.FindRunByID(ctx, suite.BaselineRunID)
+		if err != nil {
+			return err
+		}
+		if baselineRun != nil {
+			for _, r := range baselineRun.Results {
+				baseline[suiteResultKey(r.FavoriteID, r.Variant)] = r
+			}
+		}
+	}
+
+	for _, favID := range suite.FavoriteIDs {
+		fav, err := u.favRepo.FindByID(ctx, favID)
+		if err != nil {
+			return err
+		}
+		if fav == nil {
+			run.Results = append(run.Results, &entity.ComparisonSuiteRunResult{
+				FavoriteID: favID,
+				Error:      "favorite comparison no longer exists",
+			})
+			continue
+		}
+
+		for _, v := range fav.GetVariants() {
+			result := &entity.ComparisonSuiteRunResult{
+				FavoriteID:    fav.ID,
+				FavoriteTitle: fav.Title,
+				Variant:       v.Label,
+				Query:         v.Query,
+			}
+
+			// The favorite may have been edited since the suite was saved
+			if err := RequireSelect(v.Query); err != nil {
+				result.Error = err.Error()
+				run.Results = append(run.Results, result)
+				continue
+			}
+
+			bench, err := runBenchmark(ctx, u.chClient, conn, v, opts)
+			if err != nil {
+				result.Error = err.Error()
+			} else {
+				result.ExecutionTimeMs = bench.Duration.Mean
+				result.RowsRead = uint64(bench.RowsRead.Mean + 0.5)
+				result.BytesRead = uint64(bench.BytesRead.Mean + 0.5)
+				result.MemoryPeak = uint64(bench.Memory.Mean + 0.5)
+				applyBaseline(result, baseline[suiteResultKey(fav.ID, v.Label)], suite.RegressionThresholdPct)
+			}
+
+			if result.Regressed {
+				run.RegressionCount++
+			}
+			run.Results = append(run.Results, result)
+		}
+	}
+
+	return nil
+}
+
+// requireSelectVariants checks that every variant of a favorite only reads, see RequireSelect
+func requireSelectVariants(fav *entity.FavoriteComparison) error {
+	for _, v := range fav.GetVariants() {
+		if err := RequireSelect(v.Query); err != nil {
+			return fmt.Errorf("favorite comparison %q, %s: %w", fav.Title, v.Label, err)
+		}
+	}
+	return nil
+}
+
+// applyBaseline fills the baseline columns and flags the result when duration or bytes read
+// grew by more than thresholdPct
+func applyBaseline(result, base *entity.ComparisonSuiteRunResult, thresholdPct float64) {
+	if base == nil || base.Error != "" {
+		return
+	}
+
+	result.BaselineExecutionTimeMs = base.ExecutionTimeMs
+	result.BaselineBytesRead = base.BytesRead
+	result.DurationChangePct = changePct(base.ExecutionTimeMs, result.ExecutionTimeMs)
+	result.BytesReadChangePct = changePct(float64(base.BytesRead), float64(result.BytesRead))
+	result.Regressed = result.DurationChangePct > thresholdPct || result.BytesReadChangePct > thresholdPct
+}
+
+func suiteResultKey(favoriteID int64, variant string) string {
+	return fmt.Sprintf("%d|%s", favoriteID, variant)
+}
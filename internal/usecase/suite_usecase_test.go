@@ -0,0 +1,394 @@
+package usecase_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rahmatrdn/go-ch-manager/entity"
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/clickhouse"
+	"github.com/rahmatrdn/go-ch-manager/internal/repository/sqlite"
+	"github.com/rahmatrdn/go-ch-manager/internal/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gormsqlite "gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// statsClient answers ExecuteQueryWithStats with the queued runs of the query, then with its stats. The other
+// methods are not used by suites.
+type statsClient struct {
+	clickhouse.ClickHouseClient
+	stats map[string]*entity.QueryStats
+	runs  map[string][]*entity.QueryStats
+}
+
+func (c *statsClient) ExecuteQueryWithStats(ctx context.Context, conn *entity.CHConnection, query string) (*entity.QueryStats, error) {
+	if runs := c.runs[query]; len(runs) > 0 {
+		c.runs[query] = runs[1:]
+		return runs[0], nil
+	}
+	return c.stats[query], nil
+}
+
+type suiteFixture struct {
+	usecase   usecase.SuiteUsecase
+	suiteRepo sqlite.SuiteRepository
+	favRepo   sqlite.FavoriteRepository
+	client    *statsClient
+	conn      *entity.CHConnection
+}
+
+func newSuiteFixture(t *testing.T) *suiteFixture {
+	db, err := gorm.Open(gormsqlite.Open(filepath.Join(t.TempDir(), "suite.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&entity.CHConnection{}, &entity.FavoriteComparison{},
+		&entity.ComparisonSuite{}, &entity.ComparisonSuiteRun{}, &entity.ComparisonSuiteRunResult{}))
+
+	f := &suiteFixture{
+		suiteRepo: sqlite.NewSuiteRepository(db),
+		favRepo:   sqlite.NewFavoriteRepository(db),
+		client:    &statsClient{stats: make(map[string]*entity.QueryStats), runs: make(map[string][]*entity.QueryStats)},
+		conn:      &entity.CHConnection{Name: "local", Host: "localhost", Port: 9000},
+	}
+	connectionRepo := sqlite.NewConnectionRepository(db)
+	require.NoError(t, connectionRepo.Create(context.Background(), f.conn))
+	f.usecase = usecase.NewSuiteUsecase(f.suiteRepo, f.favRepo, connectionRepo, f.client)
+	return f
+}
+
+// newSuite saves a favorite comparing the two queries and a suite running it once per iteration
+func (f *suiteFixture) newSuite(t *testing.T, query1, query2 string) (*entity.ComparisonSuite, *entity.FavoriteComparison) {
+	ctx := context.Background()
+	fav := &entity.FavoriteComparison{ConnectionID: f.conn.ID, Title: "Events", Query1: query1, Query2: query2}
+	require.NoError(t, f.favRepo.Create(ctx, fav))
+	suite := &entity.ComparisonSuite{ConnectionID: f.conn.ID, Name: "Nightly", FavoriteIDs: []int64{fav.ID}, Iterations: 1, RegressionThresholdPct: 20}
+	require.NoError(t, f.usecase.CreateSuite(ctx, suite))
+	return suite, fav
+}
+
+// runSuite starts a run of the suite and waits for it to finish
+func (f *suiteFixture) runSuite(t *testing.T, suiteID int64) *entity.ComparisonSuiteRun {
+	ctx := context.Background()
+	started, err := f.usecase.StartSuite(ctx, f.conn.ID, suiteID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.SuiteRunStatusRunning, started.Status)
+
+	var run *entity.ComparisonSuiteRun
+	require.Eventually(t, func() bool {
+		run, err = f.usecase.GetRun(ctx, f.conn.ID, suiteID, started.ID)
+		require.NoError(t, err)
+		return run.Status != entity.SuiteRunStatusRunning
+	}, 5*time.Second, 10*time.Millisecond)
+	return run
+}
+
+func TestRunSuiteRegressions(t *testing.T) {
+	base := &entity.QueryStats{ExecutionTimeMs: 100, BytesRead: 1000}
+
+	testcases := []struct {
+		name          string
+		baseline      []*entity.ComparisonSuiteRunResult
+		stats         *entity.QueryStats
+		wantBaseline  float64
+		wantRegressed bool
+	}{
+		{
+			name:     "Within Threshold",
+			baseline: []*entity.ComparisonSuiteRunResult{{Variant: "Query 1", ExecutionTimeMs: 100, BytesRead: 1000}},
+			stats:    &entity.QueryStats{ExecutionTimeMs: 119, BytesRead: 1000},
+			// 19% slower stays under the 20% threshold
+			wantBaseline: 100,
+		},
+		{
+			name:          "Slower Than Threshold",
+			baseline:      []*entity.ComparisonSuiteRunResult{{Variant: "Query 1", ExecutionTimeMs: 100, BytesRead: 1000}},
+			stats:         &entity.QueryStats{ExecutionTimeMs: 130, BytesRead: 1000},
+			wantBaseline:  100,
+			wantRegressed: true,
+		},
+		{
+			name:          "Reads More Than Threshold",
+			baseline:      []*entity.ComparisonSuiteRunResult{{Variant: "Query 1", ExecutionTimeMs: 100, BytesRead: 1000}},
+			stats:         &entity.QueryStats{ExecutionTimeMs: 100, BytesRead: 1500},
+			wantBaseline:  100,
+			wantRegressed: true,
+		},
+		{
+			name:     "Missing Baseline",
+			baseline: []*entity.ComparisonSuiteRunResult{{Variant: "Other", ExecutionTimeMs: 1, BytesRead: 1}},
+			stats:    &entity.QueryStats{ExecutionTimeMs: 500, BytesRead: 5000},
+		},
+		{
+			name:     "Failed Baseline",
+			baseline: []*entity.ComparisonSuiteRunResult{{Variant: "Query 1", Error: "timeout"}},
+			stats:    &entity.QueryStats{ExecutionTimeMs: 500, BytesRead: 5000},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			f := newSuiteFixture(t)
+			suite, fav := f.newSuite(t, "SELECT 1", "SELECT 2")
+
+			for _, r := range tc.baseline {
+				r.FavoriteID = fav.ID
+			}
+			baseline := &entity.ComparisonSuiteRun{SuiteID: suite.ID, Status: entity.SuiteRunStatusCompleted, Results: tc.baseline}
+			require.NoError(t, f.suiteRepo.CreateRun(ctx, baseline))
+			require.NoError(t, f.usecase.SetBaseline(ctx, f.conn.ID, suite.ID, baseline.ID))
+
+			f.client.stats["SELECT 1"] = tc.stats
+			f.client.stats["SELECT 2"] = base
+
+			run := f.runSuite(t, suite.ID)
+			require.Len(t, run.Results, 2)
+			assert.Equal(t, entity.SuiteRunStatusCompleted, run.Status)
+			assert.Equal(t, baseline.ID, run.BaselineRunID)
+			assert.False(t, run.IsBaseline)
+
+			result := run.Results[0]
+			assert.Empty(t, result.Error)
+			assert.Equal(t, tc.wantBaseline, result.BaselineExecutionTimeMs)
+			assert.Equal(t, tc.wantRegressed, result.Regressed)
+			if tc.wantRegressed {
+				assert.Equal(t, 1, run.RegressionCount)
+			} else {
+				assert.Equal(t, 0, run.RegressionCount)
+			}
+		})
+	}
+}
+
+func TestRunSuiteFirstRunIsBaseline(t *testing.T) {
+	ctx := context.Background()
+	f := newSuiteFixture(t)
+	suite, _ := f.newSuite(t, "SELECT 1", "SELECT 2")
+	f.client.stats["SELECT 1"] = &entity.QueryStats{ExecutionTimeMs: 100}
+	f.client.stats["SELECT 2"] = &entity.QueryStats{ExecutionTimeMs: 100}
+
+	run := f.runSuite(t, suite.ID)
+	assert.True(t, run.IsBaseline)
+
+	saved, err := f.suiteRepo.FindByID(ctx, suite.ID)
+	require.NoError(t, err)
+	assert.Equal(t, run.ID, saved.BaselineRunID)
+	assert.NotNil(t, saved.LastRunAt)
+}
+
+func TestRunSuiteSkipsUnloggedRuns(t *testing.T) {
+	ctx := context.Background()
+	f := newSuiteFixture(t)
+	suite, _ := f.newSuite(t, "SELECT 1", "SELECT 2")
+	suite.Iterations = 3
+	require.NoError(t, f.usecase.UpdateSuite(ctx, f.conn.ID, suite.ID, suite))
+
+	// The query_log row of the second measured run never showed up, its zeros must not pull the means down
+	f.client.runs["SELECT 1"] = []*entity.QueryStats{
+		{ExecutionTimeMs: 900, BytesRead: 9000}, // warmup
+		{ExecutionTimeMs: 100, BytesRead: 1000},
+		{ExecutionTimeMs: 40, Unlogged: true},
+		{ExecutionTimeMs: 200, BytesRead: 3000},
+	}
+	// Without any query_log row the client side durations are all there is
+	f.client.stats["SELECT 2"] = &entity.QueryStats{ExecutionTimeMs: 50, Unlogged: true}
+
+	run := f.runSuite(t, suite.ID)
+	require.Len(t, run.Results, 2)
+
+	assert.Equal(t, 150.0, run.Results[0].ExecutionTimeMs)
+	assert.Equal(t, uint64(2000), run.Results[0].BytesRead)
+	assert.Equal(t, 50.0, run.Results[1].ExecutionTimeMs)
+	assert.Zero(t, run.Results[1].BytesRead)
+}
+
+func TestRunSuiteVariantErrors(t *testing.T) {
+	ctx := context.Background()
+	f := newSuiteFixture(t)
+	suite, fav := f.newSuite(t, "SELECT 1", "SELECT 2")
+	f.client.stats["SELECT 1"] = &entity.QueryStats{ExecutionTimeMs: 100}
+
+	// The favorite was edited into a write after the suite was saved
+	fav.Query2 = "INSERT INTO t SELECT 1"
+	require.NoError(t, f.favRepo.Delete(ctx, fav.ID))
+	require.NoError(t, f.favRepo.Create(ctx, fav))
+
+	run := f.runSuite(t, suite.ID)
+	require.Len(t, run.Results, 2)
+	assert.Empty(t, run.Results[0].Error)
+	assert.Contains(t, run.Results[1].Error, "only SELECT queries")
+
+	// A deleted favorite is reported, the run still completes
+	require.NoError(t, f.favRepo.Delete(ctx, fav.ID))
+	run = f.runSuite(t, suite.ID)
+	require.Len(t, run.Results, 1)
+	assert.Equal(t, "favorite comparison no longer exists", run.Results[0].Error)
+	assert.Equal(t, entity.SuiteRunStatusCompleted, run.Status)
+}
+
+func TestSetBaseline(t *testing.T) {
+	ctx := context.Background()
+	f := newSuiteFixture(t)
+	suite, _ := f.newSuite(t, "SELECT 1", "SELECT 2")
+	other, _ := f.newSuite(t, "SELECT 3", "SELECT 4")
+
+	first := &entity.ComparisonSuiteRun{SuiteID: suite.ID, Status: entity.SuiteRunStatusCompleted}
+	second := &entity.ComparisonSuiteRun{SuiteID: suite.ID, Status: entity.SuiteRunStatusCompleted}
+	failed := &entity.ComparisonSuiteRun{SuiteID: suite.ID, Status: entity.SuiteRunStatusFailed}
+	foreign := &entity.ComparisonSuiteRun{SuiteID: other.ID, Status: entity.SuiteRunStatusCompleted}
+	for _, run := range []*entity.ComparisonSuiteRun{first, second, failed, foreign} {
+		require.NoError(t, f.suiteRepo.CreateRun(ctx, run))
+	}
+	require.NoError(t, f.usecase.SetBaseline(ctx, f.conn.ID, suite.ID, first.ID))
+
+	testcases := []struct {
+		name    string
+		suiteID int64
+		runID   int64
+		wantErr bool
+	}{
+		{name: "Replaces Previous Baseline", suiteID: suite.ID, runID: second.ID},
+		{name: "Failed Run", suiteID: suite.ID, runID: failed.ID, wantErr: true},
+		{name: "Run Of Another Suite", suiteID: suite.ID, runID: foreign.ID, wantErr: true},
+		{name: "Unknown Run", suiteID: suite.ID, runID: 999, wantErr: true},
+		{name: "Unknown Suite", suiteID: 999, runID: second.ID, wantErr: true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := f.usecase.SetBaseline(ctx, f.conn.ID, tc.suiteID, tc.runID)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			saved, err := f.suiteRepo.FindByID(ctx, tc.suiteID)
+			require.NoError(t, err)
+			assert.Equal(t, tc.runID, saved.BaselineRunID)
+
+			previous, err := f.suiteRepo.FindRunByID(ctx, first.ID)
+			require.NoError(t, err)
+			assert.False(t, previous.IsBaseline)
+			current, err := f.suiteRepo.FindRunByID(ctx, tc.runID)
+			require.NoError(t, err)
+			assert.True(t, current.IsBaseline)
+		})
+	}
+}
+
+func TestGetRun(t *testing.T) {
+	ctx := context.Background()
+	f := newSuiteFixture(t)
+	suite, _ := f.newSuite(t, "SELECT 1", "SELECT 2")
+	other, _ := f.newSuite(t, "SELECT 3", "SELECT 4")
+	run := &entity.ComparisonSuiteRun{SuiteID: suite.ID, Status: entity.SuiteRunStatusCompleted}
+	require.NoError(t, f.suiteRepo.CreateRun(ctx, run))
+
+	got, err := f.usecase.GetRun(ctx, f.conn.ID, suite.ID, run.ID)
+	require.NoError(t, err)
+	assert.Equal(t, run.ID, got.ID)
+
+	_, err = f.usecase.GetRun(ctx, f.conn.ID, other.ID, run.ID)
+	assert.Error(t, err)
+}
+
+func TestSuiteOfAnotherConnection(t *testing.T) {
+	ctx := context.Background()
+	f := newSuiteFixture(t)
+	suite, _ := f.newSuite(t, "SELECT 1", "SELECT 2")
+	run := &entity.ComparisonSuiteRun{SuiteID: suite.ID, Status: entity.SuiteRunStatusCompleted}
+	require.NoError(t, f.suiteRepo.CreateRun(ctx, run))
+
+	other := f.conn.ID + 1
+	testcases := []struct {
+		name string
+		call func() error
+	}{
+		{name: "Update", call: func() error { return f.usecase.UpdateSuite(ctx, other, suite.ID, suite) }},
+		{name: "Delete", call: func() error { return f.usecase.DeleteSuite(ctx, other, suite.ID) }},
+		{name: "Start", call: func() error { _, err := f.usecase.StartSuite(ctx, other, suite.ID); return err }},
+		{name: "Runs", call: func() error { _, err := f.usecase.GetRuns(ctx, other, suite.ID); return err }},
+		{name: "Run", call: func() error { _, err := f.usecase.GetRun(ctx, other, suite.ID, run.ID); return err }},
+		{name: "Baseline", call: func() error { return f.usecase.SetBaseline(ctx, other, suite.ID, run.ID) }},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.EqualError(t, tc.call(), "suite not found")
+		})
+	}
+
+	saved, err := f.suiteRepo.FindByID(ctx, suite.ID)
+	require.NoError(t, err)
+	require.NotNil(t, saved)
+	assert.Zero(t, saved.BaselineRunID)
+	runs, err := f.suiteRepo.FindRunsBySuiteID(ctx, suite.ID, 10)
+	require.NoError(t, err)
+	assert.Len(t, runs, 1)
+}
+
+func TestSuiteIsDue(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+
+	testcases := []struct {
+		name     string
+		interval int
+		lastRun  *time.Time
+		want     bool
+	}{
+		{name: "Manual Only", interval: 0, lastRun: nil, want: false},
+		{name: "Never Run", interval: 60, lastRun: nil, want: true},
+		{name: "Interval Not Elapsed", interval: 60, lastRun: at(-59 * time.Minute), want: false},
+		{name: "Interval Just Elapsed", interval: 60, lastRun: at(-60 * time.Minute), want: true},
+		{name: "Interval Long Elapsed", interval: 60, lastRun: at(-5 * time.Hour), want: true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			suite := &entity.ComparisonSuite{ScheduleIntervalMinutes: tc.interval, LastRunAt: tc.lastRun}
+			assert.Equal(t, tc.want, suite.IsDue(now))
+		})
+	}
+}
+
+func TestRunDueSuites(t *testing.T) {
+	ctx := context.Background()
+	f := newSuiteFixture(t)
+	f.client.stats["SELECT 1"] = &entity.QueryStats{ExecutionTimeMs: 100}
+	f.client.stats["SELECT 2"] = &entity.QueryStats{ExecutionTimeMs: 100}
+
+	due, _ := f.newSuite(t, "SELECT 1", "SELECT 2")
+	due.ScheduleIntervalMinutes = 60
+	require.NoError(t, f.suiteRepo.Update(ctx, due))
+
+	recent, _ := f.newSuite(t, "SELECT 1", "SELECT 2")
+	recent.ScheduleIntervalMinutes = 60
+	lastRun := time.Now().Add(-time.Minute)
+	recent.LastRunAt = &lastRun
+	require.NoError(t, f.suiteRepo.Update(ctx, recent))
+
+	manual, _ := f.newSuite(t, "SELECT 1", "SELECT 2")
+
+	require.NoError(t, f.usecase.RunDueSuites(ctx))
+
+	for _, tc := range []struct {
+		name  string
+		suite *entity.ComparisonSuite
+		want  int
+	}{
+		{name: "Due", suite: due, want: 1},
+		{name: "Not Due", suite: recent, want: 0},
+		{name: "Manual Only", suite: manual, want: 0},
+	} {
+		runs, err := f.suiteRepo.FindRunsBySuiteID(ctx, tc.suite.ID, 10)
+		require.NoError(t, err)
+		assert.Len(t, runs, tc.want, tc.name)
+	}
+}